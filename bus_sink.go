@@ -0,0 +1,408 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Bus sink defaults
+const (
+	DefaultBusBatchSize     = 100         // Messages per Produce call
+	DefaultBusFlushInterval = time.Second // Maximum time a message waits for its batch
+	DefaultBusMaxRetries    = 10          // Retries of a batch before it is dropped
+	DefaultBusQueueSize     = 1000        // Messages waiting to be produced
+)
+
+// OverflowPolicy decides what happens to a message when the queue is full
+type OverflowPolicy uint8
+
+// Overflow policies
+const (
+	OverflowBlock      OverflowPolicy = iota // Wait for room in the queue (backpressure on the caller)
+	OverflowDropNewest                       // Drop the message being logged
+	OverflowDropOldest                       // Drop the oldest queued message to make room
+)
+
+// String returns the name of the policy
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop_newest"
+	case OverflowDropOldest:
+		return "drop_oldest"
+	}
+	return ""
+}
+
+// BusMessage is a log line published on a message bus
+type BusMessage struct {
+	Key   []byte    // Partitioning key (nil when the key field is not set)
+	Value []byte    // Encoded log entry
+	Time  time.Time // When the entry was logged
+}
+
+// Producer publishes messages to a topic of a message bus (IE: an adapter for a Kafka,
+// Redpanda or NATS client), the messages must not be retained after Produce returns
+type Producer interface {
+	Produce(ctx context.Context, topic string, messages []BusMessage) error
+}
+
+// BusOption is an optional setting for NewBusSink
+type BusOption func(s *BusSink)
+
+// WithBusBatchSize sets the maximum number of messages per Produce call
+func WithBusBatchSize(size int) BusOption {
+	return func(s *BusSink) {
+		s.batchSize = size
+	}
+}
+
+// WithBusEncoder sets the encoder of the messages (defaults to the JSONEncoder)
+func WithBusEncoder(enc Encoder) BusOption {
+	return func(s *BusSink) {
+		s.encoder = enc
+	}
+}
+
+// WithBusFlushInterval sets the maximum time a message waits for its batch to fill up
+func WithBusFlushInterval(interval time.Duration) BusOption {
+	return func(s *BusSink) {
+		s.flushInterval = interval
+	}
+}
+
+// WithBusKeyField uses the value of a Data() field as the message key (IE: "tenant"), so the
+// entries of a key stay ordered in their partition
+func WithBusKeyField(key string) BusOption {
+	return func(s *BusSink) {
+		s.keyField = key
+	}
+}
+
+// WithBusMaxRetries sets the number of retries of a batch before it is dropped
+func WithBusMaxRetries(retries int) BusOption {
+	return func(s *BusSink) {
+		s.maxRetries = retries
+	}
+}
+
+// WithBusOverflow sets what happens when the queue is full (defaults to OverflowBlock)
+func WithBusOverflow(policy OverflowPolicy) BusOption {
+	return func(s *BusSink) {
+		s.overflow = policy
+	}
+}
+
+// WithBusQueueSize sets the number of messages waiting to be produced
+func WithBusQueueSize(size int) BusOption {
+	return func(s *BusSink) {
+		s.queueSize = size
+	}
+}
+
+// BusSink is a Logger publishing batches of entries to a message bus through a Producer,
+// start sending with go sink.ProcessQueue()
+type BusSink struct {
+	batchSize     int
+	closed        bool
+	done          chan struct{}
+	dropped       atomic.Uint64
+	encoder       Encoder
+	exiting       atomic.Bool // Set by Fatal and Panic, the queued batches are only produced once
+	flushInterval time.Duration
+	keyField      string
+	maxRetries    int
+	messages      chan BusMessage
+	mu            sync.RWMutex // Guards closed
+	overflow      OverflowPolicy
+	producer      Producer
+	queueSize     int
+	retryDelay    time.Duration // First delay between retries, doubled up to MaxRetryDelay
+	sleep         func(time.Duration)
+	started       atomic.Bool // Set by the first ProcessQueue call
+	topic         string
+}
+
+// NewBusSink creates a sink publishing the entries to a topic
+func NewBusSink(producer Producer, topic string, opts ...BusOption) *BusSink {
+	s := &BusSink{
+		batchSize:     DefaultBusBatchSize,
+		done:          make(chan struct{}),
+		encoder:       &JSONEncoder{},
+		flushInterval: DefaultBusFlushInterval,
+		maxRetries:    DefaultBusMaxRetries,
+		producer:      producer,
+		queueSize:     DefaultBusQueueSize,
+		retryDelay:    RetryDelay,
+		sleep:         time.Sleep,
+		topic:         topic,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = DefaultBusBatchSize
+	}
+	if s.flushInterval <= 0 {
+		s.flushInterval = DefaultBusFlushInterval
+	}
+	if s.queueSize <= 0 {
+		s.queueSize = DefaultBusQueueSize
+	}
+	s.messages = make(chan BusMessage, s.queueSize)
+	return s
+}
+
+// Dropped returns the number of messages dropped by the overflow policy or after failing
+// every retry
+func (s *BusSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// ProcessQueue produces the queued messages in batches, until Close is called
+// (the calls after the first one return right away)
+func (s *BusSink) ProcessQueue() {
+	if !s.started.CompareAndSwap(false, true) {
+		return
+	}
+	defer close(s.done)
+	processBatches(s.messages, s.batchSize, s.flushInterval, s.flush)
+}
+
+// Close stops accepting messages and waits for the queued ones to be sent (by ProcessQueue, or
+// right away when it was never started)
+func (s *BusSink) Close() {
+	// The queue is drained before locking, so the writers blocked on a full queue
+	// (OverflowBlock) can finish and release the lock
+	go s.ProcessQueue()
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.messages)
+	s.mu.Unlock()
+
+	<-s.done
+}
+
+// flush produces a batch, retrying with backoff (dropping it if every retry failed)
+func (s *BusSink) flush(batch []BusMessage) {
+	if len(batch) == 0 {
+		return
+	}
+
+	maxRetries := s.maxRetries
+	if s.exiting.Load() {
+		maxRetries = 0
+	}
+
+	delay := s.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := s.producer.Produce(context.Background(), s.topic, batch)
+		if err == nil {
+			return
+		}
+		if attempt >= maxRetries {
+			s.dropped.Add(uint64(len(batch)))
+			log.Println("go-logger: failed to produce", len(batch), "messages to", s.topic+":", err) //nolint:gosec // G706: error originates from the producer
+			return
+		}
+		s.sleep(delay)
+		if delay *= 2; delay > MaxRetryDelay {
+			delay = MaxRetryDelay
+		}
+	}
+}
+
+// message encodes an entry, keyed by the value of the key field
+func (s *BusSink) message(e *Entry) BusMessage {
+	var buf bytes.Buffer
+	s.encoder.Encode(&buf, e)
+	msg := BusMessage{Value: buf.Bytes(), Time: e.Time}
+
+	if len(s.keyField) > 0 {
+		for _, kv := range e.KeyValues() {
+			if kv.Key() != s.keyField {
+				continue
+			}
+			if f, ok := kv.(Field); ok {
+				msg.Key = f.AppendValue(nil)
+			} else {
+				msg.Key = []byte(fmt.Sprint(kv.Value()))
+			}
+			break
+		}
+	}
+	return msg
+}
+
+// enqueue queues a message following the overflow policy (or prints it with the log
+// package once closed)
+func (s *BusSink) enqueue(msg BusMessage) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		log.Print(string(msg.Value))
+		return
+	}
+
+	switch s.overflow {
+	case OverflowDropNewest:
+		select {
+		case s.messages <- msg:
+		default:
+			s.dropped.Add(1)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case s.messages <- msg:
+				return
+			default:
+			}
+			select {
+			case <-s.messages:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	default: // OverflowBlock
+		s.messages <- msg
+	}
+}
+
+// LogEntry implements the EntryLogger interface
+func (s *BusSink) LogEntry(e *Entry) {
+	s.enqueue(s.message(e))
+}
+
+// Panic overloads built-in method
+func (s *BusSink) Panic(v ...interface{}) {
+	s.exit(fmt.Sprint(v...))
+}
+
+// Panicln overloads built-in method
+func (s *BusSink) Panicln(v ...interface{}) {
+	s.exit(fmt.Sprintln(v...))
+}
+
+// Panicf overloads built-in method
+func (s *BusSink) Panicf(format string, v ...interface{}) {
+	s.exit(fmt.Sprintf(format, v...))
+}
+
+// Print overloads built-in method
+func (s *BusSink) Print(v ...interface{}) {
+	s.write(fmt.Sprint(v...))
+}
+
+// Println overloads built-in method
+func (s *BusSink) Println(v ...interface{}) {
+	s.write(fmt.Sprintln(v...))
+}
+
+// Printf overloads built-in method
+func (s *BusSink) Printf(format string, v ...interface{}) {
+	s.write(fmt.Sprintf(format, v...))
+}
+
+// Fatal overloads built-in method
+func (s *BusSink) Fatal(v ...interface{}) {
+	s.exit(fmt.Sprint(v...))
+}
+
+// Fatalln overloads built-in method
+func (s *BusSink) Fatalln(v ...interface{}) {
+	s.exit(fmt.Sprintln(v...))
+}
+
+// Fatalf overloads built-in method
+func (s *BusSink) Fatalf(format string, v ...interface{}) {
+	s.exit(fmt.Sprintf(format, v...))
+}
+
+// write queues a line as an INFO entry
+func (s *BusSink) write(line string) {
+	s.enqueue(s.message(&Entry{Time: time.Now(), Level: INFO, Message: strings.TrimSuffix(line, "\n")}))
+}
+
+// exit sends the queued messages and then the line, before exiting (used by Fatal and Panic)
+//
+// The messages are produced without retrying, and the queue is given up after exitTimeout
+func (s *BusSink) exit(line string) {
+	s.exiting.Store(true)
+	if !closeWithin(s.Close, exitTimeout) {
+		log.Println("go-logger: timed out producing the queued messages to", s.topic) //nolint:gosec // G706: the topic is configured by the application
+	}
+	s.sendOne(line)
+	os.Exit(1)
+}
+
+// sendOne produces one ERROR entry right away, bypassing the queue (used before exiting)
+func (s *BusSink) sendOne(line string) {
+	msg := s.message(&Entry{Time: time.Now(), Level: ERROR, Message: strings.TrimSuffix(line, "\n")})
+	if err := s.producer.Produce(context.Background(), s.topic, []BusMessage{msg}); err != nil {
+		log.Print(string(msg.Value))
+		log.Println("go-logger: failed to produce to", s.topic+":", err) //nolint:gosec // G706: error originates from the producer
+	}
+}
+
+// MemoryProducer is a Producer keeping the messages in memory, for tests and local development
+type MemoryProducer struct {
+	batches map[string][][]BusMessage
+	err     error
+	mu      sync.Mutex
+}
+
+// NewMemoryProducer creates an empty MemoryProducer
+func NewMemoryProducer() *MemoryProducer {
+	return &MemoryProducer{batches: make(map[string][][]BusMessage)}
+}
+
+// Produce implements the Producer interface, keeping a copy of the batch
+func (p *MemoryProducer) Produce(_ context.Context, topic string, messages []BusMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.batches[topic] = append(p.batches[topic], append([]BusMessage(nil), messages...))
+	return nil
+}
+
+// SetError makes Produce fail with err (nil to succeed again)
+func (p *MemoryProducer) SetError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Batches returns the batches produced to a topic
+func (p *MemoryProducer) Batches(topic string) [][]BusMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([][]BusMessage(nil), p.batches[topic]...)
+}
+
+// Messages returns the messages produced to a topic, in order
+func (p *MemoryProducer) Messages(topic string) []BusMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var messages []BusMessage
+	for _, batch := range p.batches[topic] {
+		messages = append(messages, batch...)
+	}
+	return messages
+}
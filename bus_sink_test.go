@@ -0,0 +1,413 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBusSink creates a sink for a MemoryProducer, recording the retry delays
+func newTestBusSink(producer Producer, delays *[]time.Duration, opts ...BusOption) *BusSink {
+	s := NewBusSink(producer, "logs", opts...)
+	s.sleep = func(d time.Duration) { *delays = append(*delays, d) }
+	return s
+}
+
+// busValues decodes the JSON values of messages
+func busValues(t *testing.T, messages []BusMessage) []map[string]interface{} {
+	t.Helper()
+	values := make([]map[string]interface{}, 0, len(messages))
+	for _, msg := range messages {
+		var value map[string]interface{}
+		require.NoError(t, json.Unmarshal(msg.Value, &value), string(msg.Value))
+		values = append(values, value)
+	}
+	return values
+}
+
+// TestNewBusSink will test the NewBusSink() method
+func TestNewBusSink(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		producer := NewMemoryProducer()
+		s := NewBusSink(producer, "logs")
+		assert.Equal(t, producer, s.producer)
+		assert.Equal(t, "logs", s.topic)
+		assert.Equal(t, DefaultBusBatchSize, s.batchSize)
+		assert.Equal(t, DefaultBusFlushInterval, s.flushInterval)
+		assert.Equal(t, DefaultBusMaxRetries, s.maxRetries)
+		assert.Equal(t, OverflowBlock, s.overflow)
+		assert.Equal(t, DefaultBusQueueSize, cap(s.messages))
+		assert.IsType(t, &JSONEncoder{}, s.encoder)
+		assert.Empty(t, s.keyField)
+	})
+
+	t.Run("options", func(t *testing.T) {
+		s := NewBusSink(NewMemoryProducer(), "logs",
+			WithBusBatchSize(5),
+			WithBusEncoder(&LogfmtEncoder{}),
+			WithBusFlushInterval(time.Minute),
+			WithBusKeyField("tenant"),
+			WithBusMaxRetries(2),
+			WithBusOverflow(OverflowDropOldest),
+			WithBusQueueSize(10),
+		)
+		assert.Equal(t, 5, s.batchSize)
+		assert.IsType(t, &LogfmtEncoder{}, s.encoder)
+		assert.Equal(t, time.Minute, s.flushInterval)
+		assert.Equal(t, "tenant", s.keyField)
+		assert.Equal(t, 2, s.maxRetries)
+		assert.Equal(t, OverflowDropOldest, s.overflow)
+		assert.Equal(t, 10, cap(s.messages))
+	})
+
+	t.Run("invalid sizes", func(t *testing.T) {
+		s := NewBusSink(NewMemoryProducer(), "logs", WithBusBatchSize(0), WithBusFlushInterval(-1), WithBusQueueSize(-1))
+		assert.Equal(t, DefaultBusBatchSize, s.batchSize)
+		assert.Equal(t, DefaultBusFlushInterval, s.flushInterval)
+		assert.Equal(t, DefaultBusQueueSize, cap(s.messages))
+	})
+}
+
+// TestBusSink_ProcessQueue will test producing batches
+func TestBusSink_ProcessQueue(t *testing.T) {
+	t.Run("batches by size and flushes on close", func(t *testing.T) {
+		producer := NewMemoryProducer()
+		var delays []time.Duration
+		s := newTestBusSink(producer, &delays, WithBusBatchSize(2), WithBusFlushInterval(time.Hour))
+		go s.ProcessQueue()
+
+		s.Print("one")
+		s.Println("two")
+		s.Printf("three %d", 3)
+		s.Close()
+
+		batches := producer.Batches("logs")
+		require.Len(t, batches, 2)
+		assert.Len(t, batches[0], 2)
+		assert.Len(t, batches[1], 1)
+
+		values := busValues(t, producer.Messages("logs"))
+		assert.Equal(t, "one", values[0]["message"])
+		assert.Equal(t, "two", values[1]["message"])
+		assert.Equal(t, "three 3", values[2]["message"])
+		assert.Equal(t, "info", values[0]["level"])
+		assert.Nil(t, producer.Messages("logs")[0].Key)
+		assert.False(t, producer.Messages("logs")[0].Time.IsZero())
+		assert.Empty(t, delays)
+	})
+
+	t.Run("flushes on the interval", func(t *testing.T) {
+		producer := NewMemoryProducer()
+		var delays []time.Duration
+		s := newTestBusSink(producer, &delays, WithBusFlushInterval(10*time.Millisecond))
+		go s.ProcessQueue()
+		defer s.Close()
+
+		s.Println("waiting")
+		assert.Eventually(t, func() bool { return len(producer.Messages("logs")) == 1 }, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("keyed entries", func(t *testing.T) {
+		producer := NewMemoryProducer()
+		var delays []time.Duration
+		s := newTestBusSink(producer, &delays, WithBusKeyField("tenant"), WithBusEncoder(&LogfmtEncoder{}))
+		go s.ProcessQueue()
+
+		previous := GetImplementation()
+		SetImplementation(s)
+		NoFileData(WARN, "first", String("tenant", "acme"))
+		NoFileData(INFO, "second", MakeParameter("tenant", 42))
+		NoFileData(INFO, "third", String("other", "value"))
+		SetImplementation(previous)
+		s.Close()
+
+		messages := producer.Messages("logs")
+		require.Len(t, messages, 3)
+		assert.Equal(t, []byte("acme"), messages[0].Key)
+		assert.Equal(t, `type="warn" message="first" tenant="acme"`, string(messages[0].Value))
+		assert.Equal(t, []byte("42"), messages[1].Key)
+		assert.Nil(t, messages[2].Key)
+	})
+
+	t.Run("lines after close", func(t *testing.T) {
+		producer := NewMemoryProducer()
+		var delays []time.Duration
+		s := newTestBusSink(producer, &delays)
+		go s.ProcessQueue()
+		s.Close()
+		s.Close()
+
+		s.Println("closed")
+		assert.Empty(t, producer.Messages("logs"))
+	})
+
+	t.Run("close without process queue", func(t *testing.T) {
+		producer := NewMemoryProducer()
+		var delays []time.Duration
+		s := newTestBusSink(producer, &delays)
+		s.Println("queued")
+		s.Close()
+		go s.ProcessQueue() // Returns right away
+
+		values := busValues(t, producer.Messages("logs"))
+		require.Len(t, values, 1)
+		assert.Equal(t, "queued", values[0]["message"])
+	})
+}
+
+// TestBusSink_Retry will test retrying failed batches
+func TestBusSink_Retry(t *testing.T) {
+	t.Run("dropped after the retries", func(t *testing.T) {
+		producer := NewMemoryProducer()
+		producer.SetError(errors.New("broker unavailable"))
+		var delays []time.Duration
+		s := newTestBusSink(producer, &delays, WithBusMaxRetries(2))
+		go s.ProcessQueue()
+
+		s.Println("lost")
+		s.Close()
+
+		assert.Equal(t, []time.Duration{RetryDelay, 2 * RetryDelay}, delays)
+		assert.Empty(t, producer.Messages("logs"))
+		assert.Equal(t, uint64(1), s.Dropped())
+	})
+
+	t.Run("recovers", func(t *testing.T) {
+		producer := NewMemoryProducer()
+		producer.SetError(errors.New("broker unavailable"))
+		var delays []time.Duration
+		s := NewBusSink(producer, "logs")
+		s.sleep = func(d time.Duration) {
+			delays = append(delays, d)
+			producer.SetError(nil)
+		}
+		go s.ProcessQueue()
+
+		s.Println("delivered")
+		s.Close()
+
+		assert.Equal(t, []time.Duration{RetryDelay}, delays)
+		assert.Len(t, producer.Messages("logs"), 1)
+		assert.Zero(t, s.Dropped())
+	})
+}
+
+// TestBusSink_Overflow will test the overflow policies
+func TestBusSink_Overflow(t *testing.T) {
+	t.Run("drop newest", func(t *testing.T) {
+		producer := NewMemoryProducer()
+		s := NewBusSink(producer, "logs", WithBusQueueSize(2), WithBusOverflow(OverflowDropNewest))
+		for _, line := range []string{"one", "two", "three", "four"} {
+			s.Print(line)
+		}
+		assert.Equal(t, uint64(2), s.Dropped())
+
+		go s.ProcessQueue()
+		s.Close()
+		values := busValues(t, producer.Messages("logs"))
+		require.Len(t, values, 2)
+		assert.Equal(t, "one", values[0]["message"])
+		assert.Equal(t, "two", values[1]["message"])
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		producer := NewMemoryProducer()
+		s := NewBusSink(producer, "logs", WithBusQueueSize(2), WithBusOverflow(OverflowDropOldest))
+		for _, line := range []string{"one", "two", "three", "four"} {
+			s.Print(line)
+		}
+		assert.Equal(t, uint64(2), s.Dropped())
+
+		go s.ProcessQueue()
+		s.Close()
+		values := busValues(t, producer.Messages("logs"))
+		require.Len(t, values, 2)
+		assert.Equal(t, "three", values[0]["message"])
+		assert.Equal(t, "four", values[1]["message"])
+	})
+
+	t.Run("block", func(t *testing.T) {
+		producer := NewMemoryProducer()
+		s := NewBusSink(producer, "logs", WithBusQueueSize(1), WithBusFlushInterval(time.Hour))
+		s.Print("one")
+
+		logged := make(chan struct{})
+		go func() {
+			s.Print("two")
+			close(logged)
+		}()
+		select {
+		case <-logged:
+			t.Fatal("the queue is full, Print should block")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		go s.ProcessQueue()
+		<-logged
+		s.Close()
+		assert.Len(t, producer.Messages("logs"), 2)
+		assert.Zero(t, s.Dropped())
+	})
+
+	t.Run("block and close without processing the queue", func(t *testing.T) {
+		producer := NewMemoryProducer()
+		s := NewBusSink(producer, "logs", WithBusQueueSize(1), WithBusFlushInterval(time.Hour))
+		s.Print("one")
+
+		logged := make(chan struct{})
+		go func() {
+			s.Print("two")
+			close(logged)
+		}()
+		time.Sleep(20 * time.Millisecond) // Print("two") is blocked on the full queue
+
+		closed := make(chan struct{})
+		go func() {
+			s.Close()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatal("Close should not wait for the blocked writer forever")
+		}
+		<-logged
+		values := busValues(t, producer.Messages("logs"))
+		require.Len(t, values, 2)
+		assert.Equal(t, "one", values[0]["message"])
+		assert.Equal(t, "two", values[1]["message"])
+	})
+}
+
+// TestOverflowPolicy_String will test the OverflowPolicy.String() method
+func TestOverflowPolicy_String(t *testing.T) {
+	assert.Equal(t, "block", OverflowBlock.String())
+	assert.Equal(t, "drop_newest", OverflowDropNewest.String())
+	assert.Equal(t, "drop_oldest", OverflowDropOldest.String())
+	assert.Empty(t, OverflowPolicy(42).String())
+}
+
+// TestMemoryProducer will test the MemoryProducer
+func TestMemoryProducer(t *testing.T) {
+	producer := NewMemoryProducer()
+	batch := []BusMessage{{Value: []byte("one")}}
+	require.NoError(t, producer.Produce(context.Background(), "a", batch))
+	batch[0] = BusMessage{Value: []byte("changed")}
+	require.NoError(t, producer.Produce(context.Background(), "b", batch))
+
+	assert.Equal(t, []BusMessage{{Value: []byte("one")}}, producer.Messages("a"))
+	assert.Equal(t, [][]BusMessage{{{Value: []byte("changed")}}}, producer.Batches("b"))
+	assert.Empty(t, producer.Messages("c"))
+
+	producer.SetError(errors.New("failed"))
+	require.Error(t, producer.Produce(context.Background(), "a", batch))
+	assert.Len(t, producer.Messages("a"), 1)
+}
+
+// TestBusSink_Panic will test the Panic() method
+func TestBusSink_Panic(t *testing.T) {
+	if os.Getenv("EXIT_FUNCTION") == "bus" {
+		s := NewBusSink(writerProducer{}, "logs")
+		s.Println("queued")
+		s.Panicf("panicf %d", 1)
+		return
+	}
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestBusSink_Panic") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION=bus")
+	output, err := cmd.Output()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		assert.Regexp(t, `"message":"queued"[^\n]*\n[^\n]*"level":"error"[^\n]*"message":"panicf 1"`, string(output))
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestBusSink_Fatal will test the Fatal() method
+func TestBusSink_Fatal(t *testing.T) {
+	if os.Getenv("EXIT_FUNCTION") == "bus" {
+		s := NewBusSink(writerProducer{}, "logs", WithBusFlushInterval(time.Hour))
+		go s.ProcessQueue()
+		s.Println("queued")
+		s.Fatalf("fatal %d", 1)
+		return
+	}
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestBusSink_Fatal") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION=bus")
+	output, err := cmd.Output()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		assert.Regexp(t, `"message":"queued"[^\n]*\n[^\n]*"message":"fatal 1"`, string(output))
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestBusSink_FatalFailingProducer will test that Fatal() doesn't retry when the producer fails
+func TestBusSink_FatalFailingProducer(t *testing.T) {
+	if os.Getenv("EXIT_FUNCTION") == "bus" {
+		s := NewBusSink(failingProducer{}, "logs", WithBusFlushInterval(time.Hour))
+		go s.ProcessQueue()
+		s.Println("queued")
+		s.Fatalf("fatal %d", 1)
+		return
+	}
+
+	start := time.Now()
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestBusSink_FatalFailingProducer") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION=bus")
+	output, err := cmd.Output()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		assert.Less(t, time.Since(start), exitTimeout)
+		assert.Equal(t, "attempt\nattempt\n", string(output))
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// failingProducer is a Producer failing every attempt, and writing "attempt" to stdout
+// (for the subprocess tests)
+type failingProducer struct{}
+
+// Produce implements the Producer interface
+func (failingProducer) Produce(context.Context, string, []BusMessage) error {
+	_, _ = os.Stdout.WriteString("attempt\n")
+	return errors.New("broker unavailable")
+}
+
+// writerProducer is a Producer writing the values to stdout (for the subprocess tests)
+type writerProducer struct{}
+
+// Produce implements the Producer interface
+func (writerProducer) Produce(_ context.Context, _ string, messages []BusMessage) error {
+	for _, msg := range messages {
+		if _, err := os.Stdout.Write(append(msg.Value, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BenchmarkBusSink_Println benchmarks the Println() method (queueing only)
+func BenchmarkBusSink_Println(b *testing.B) {
+	s := NewBusSink(NewMemoryProducer(), "logs")
+	go func() {
+		for range s.messages { //nolint:revive // draining the queue
+		}
+	}()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Println("benchmark line")
+	}
+}
@@ -0,0 +1,287 @@
+/*
+Package main is the go-logger command line tool, a viewer and pretty-printer for logs
+written with go-logger's Data() format (or its JSON encoder)
+
+Usage:
+
+	go-logger view [flags] [file ...]
+
+Logs are read from the given files, or from stdin when no files are given:
+
+	tail -f app.log | go-logger view --level warn
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mrz1836/go-logger"
+)
+
+// Output formats
+const (
+	formatHuman = "human"
+	formatJSON  = "json"
+	formatRaw   = "raw"
+)
+
+// Color modes
+const (
+	colorAlways = "always"
+	colorAuto   = "auto"
+	colorNever  = "never"
+)
+
+var (
+	// errUsage is returned when the command line is invalid
+	errUsage = errors.New("usage: go-logger view [flags] [file ...]")
+
+	// errBadFilter is returned when a --where filter is not key=value
+	errBadFilter = errors.New("filter must be in the form key=value")
+)
+
+// main
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command and returns the process exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "view" {
+		_, _ = fmt.Fprintln(stderr, errUsage)
+		return 2
+	}
+
+	v, files, err := parseViewFlags(args[1:], stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		_, _ = fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	if v.color == colorAuto {
+		v.colors = logger.ColorsEnabled(stdout)
+	}
+	v.console = logger.NewConsoleEncoder(stdout)
+	v.console.Colors = v.colors
+
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	for _, name := range files {
+		if err = v.viewFile(name, stdin, stdout); err != nil {
+			_, _ = fmt.Fprintln(stderr, "go-logger:", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// whereFilter is a repeatable key=value flag
+type whereFilter []logger.Parameter
+
+// String implements flag.Value
+func (w *whereFilter) String() string {
+	pairs := make([]string, 0, len(*w))
+	for _, p := range *w {
+		pairs = append(pairs, p.K+"="+fmt.Sprint(p.V))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set implements flag.Value
+func (w *whereFilter) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || len(key) == 0 {
+		return fmt.Errorf("%w: %q", errBadFilter, value)
+	}
+	*w = append(*w, logger.Parameter{K: key, V: val})
+	return nil
+}
+
+// viewer holds the options for the view command
+type viewer struct {
+	color    string
+	colors   bool
+	console  *logger.ConsoleEncoder
+	file     string
+	format   string
+	minLevel logger.LogLevel
+	method   string
+	where    whereFilter
+}
+
+// parseViewFlags parses the flags of the view command
+func parseViewFlags(args []string, stderr io.Writer) (*viewer, []string, error) {
+	v := &viewer{}
+	var level string
+
+	fs := flag.NewFlagSet("view", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&level, "level", "debug", "minimum level to show (debug, info, warn, error)")
+	fs.StringVar(&v.file, "file", "", "only show lines whose file contains this value")
+	fs.StringVar(&v.method, "method", "", "only show lines whose method contains this value")
+	fs.Var(&v.where, "where", "only show lines with this key=value (repeatable)")
+	fs.StringVar(&v.format, "format", formatHuman, "output format (human, json, raw)")
+	fs.StringVar(&v.color, "color", colorAuto, "colorize output (auto, always, never)")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	var err error
+	if v.minLevel, err = logger.ParseLogLevel(level); err != nil {
+		return nil, nil, err
+	}
+
+	switch v.format {
+	case formatHuman, formatJSON, formatRaw:
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown format %q", errUsage, v.format)
+	}
+
+	switch v.color {
+	case colorAlways:
+		v.colors = true
+	case colorAuto, colorNever:
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown color mode %q", errUsage, v.color)
+	}
+
+	return v, fs.Args(), nil
+}
+
+// filtering reports whether any filters are set (non-Data lines are hidden when filtering)
+func (v *viewer) filtering() bool {
+	return v.minLevel > logger.DEBUG || len(v.file) > 0 || len(v.method) > 0 || len(v.where) > 0
+}
+
+// viewFile reads one file ("-" for stdin) and writes the matching lines
+func (v *viewer) viewFile(name string, stdin io.Reader, out io.Writer) error {
+	r := stdin
+	if name != "-" {
+		f, err := os.Open(name) //nolint:gosec // G304: reading the files the user asked for is the point
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+
+	s := logger.NewScanner(r)
+	for s.Scan() {
+		if err := v.writeLine(out, s.Text(), s.Data()); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// writeLine writes one line in the chosen format, if it passes the filters
+func (v *viewer) writeLine(out io.Writer, text string, d *logger.DataLine) error {
+	if d == nil {
+		if v.filtering() || v.format == formatJSON {
+			return nil
+		}
+		_, err := fmt.Fprintln(out, text)
+		return err
+	}
+	if !v.matches(d) {
+		return nil
+	}
+
+	var line string
+	switch v.format {
+	case formatJSON:
+		line = formatDataJSON(d)
+	case formatRaw:
+		line = v.colorize(logger.LevelColor(d.Level), text)
+	default:
+		line = v.formatHuman(d)
+	}
+	_, err := fmt.Fprintln(out, line)
+	return err
+}
+
+// matches reports whether a line passes the level, file, method and key=value filters
+func (v *viewer) matches(d *logger.DataLine) bool {
+	if d.Level < v.minLevel {
+		return false
+	}
+	if len(v.file) > 0 && !strings.Contains(d.File, v.file) {
+		return false
+	}
+	if len(v.method) > 0 && !strings.Contains(d.Method, v.method) {
+		return false
+	}
+	for _, w := range v.where {
+		if value, ok := d.Param(w.K); !ok || value != w.V {
+			return false
+		}
+	}
+	return true
+}
+
+// formatHuman renders a line using the console encoder, keeping the original prefix
+func (v *viewer) formatHuman(d *logger.DataLine) string {
+	var buf bytes.Buffer
+	if len(d.Prefix) > 0 {
+		buf.WriteString(v.colorize(logger.ColorDim, d.Prefix))
+		buf.WriteByte(' ')
+	}
+	v.console.Encode(&buf, d.Entry())
+	return buf.String()
+}
+
+// colorize wraps s in the given color when colors are enabled
+func (v *viewer) colorize(color, s string) string {
+	if !v.colors {
+		return s
+	}
+	return color + s + logger.ColorReset
+}
+
+// formatDataJSON renders a line as a single JSON object, keeping the parameter order
+func formatDataJSON(d *logger.DataLine) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	writeJSONPair(&b, "level", d.Level.String(), true)
+	if len(d.Prefix) > 0 {
+		writeJSONPair(&b, "prefix", d.Prefix, false)
+	}
+	if len(d.File) > 0 {
+		writeJSONPair(&b, "file", d.File, false)
+		writeJSONPair(&b, "method", d.Method, false)
+		writeJSONPair(&b, "line", d.Line, false)
+	}
+	writeJSONPair(&b, "message", d.Message, false)
+	for _, p := range d.Params {
+		writeJSONPair(&b, p.K, p.V, false)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// writeJSONPair writes one "key":value member
+func writeJSONPair(b *strings.Builder, key string, value interface{}, first bool) {
+	if !first {
+		b.WriteByte(',')
+	}
+	k, _ := json.Marshal(key) //nolint:errchkjson // strings always marshal
+	b.Write(k)
+	b.WriteByte(':')
+	val, err := json.Marshal(value)
+	if err != nil {
+		val, _ = json.Marshal(fmt.Sprint(value)) //nolint:errchkjson // strings always marshal
+	}
+	b.Write(val)
+}
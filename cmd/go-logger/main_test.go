@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mrz1836/go-logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLogs = `2024/01/02 03:04:05 type="debug" file="app/db.go" method="app.query" line="10" message="running query" table="users"
+2024/01/02 03:04:06 type="warn" file="app/api.go" method="app.handle" line="42" message="slow request" path="/v1/users"
+a plain print line
+2024/01/02 03:04:07 type="error" file="app/db.go" method="app.query" line="12" message="query failed" table="orders"
+`
+
+// runView runs the view command against the test logs
+func runView(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(append([]string{"view"}, args...), strings.NewReader(testLogs), &stdout, &stderr)
+	return stdout.String(), code
+}
+
+// TestRun will test the command line handling
+func TestRun(t *testing.T) {
+	t.Run("missing command", func(t *testing.T) {
+		var stderr bytes.Buffer
+		assert.Equal(t, 2, run(nil, strings.NewReader(""), &bytes.Buffer{}, &stderr))
+		assert.Contains(t, stderr.String(), "usage")
+	})
+
+	t.Run("bad flags", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"--level", "loud"},
+			{"--format", "xml"},
+			{"--color", "sometimes"},
+			{"--where", "novalue"},
+		} {
+			_, code := runView(t, args...)
+			assert.Equal(t, 2, code, args)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, code := runView(t, filepath.Join(t.TempDir(), "missing.log"))
+		assert.Equal(t, 1, code)
+	})
+}
+
+// TestViewFilters will test the level, file, method and key=value filters
+func TestViewFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "no filters keeps everything",
+			args:     nil,
+			expected: []string{"running query", "slow request", "a plain print line", "query failed"},
+		},
+		{
+			name:     "minimum level",
+			args:     []string{"--level", "warn"},
+			expected: []string{"slow request", "query failed"},
+		},
+		{
+			name:     "file",
+			args:     []string{"--file", "db.go"},
+			expected: []string{"running query", "query failed"},
+		},
+		{
+			name:     "method",
+			args:     []string{"--method", "handle"},
+			expected: []string{"slow request"},
+		},
+		{
+			name:     "key value",
+			args:     []string{"--where", "table=orders"},
+			expected: []string{"query failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, code := runView(t, append([]string{"--color", "never"}, tt.args...)...)
+			require.Equal(t, 0, code)
+
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			require.Len(t, lines, len(tt.expected))
+			for i, expected := range tt.expected {
+				assert.Contains(t, lines[i], expected)
+			}
+		})
+	}
+}
+
+// TestViewFormats will test the output formats
+func TestViewFormats(t *testing.T) {
+	t.Run("human", func(t *testing.T) {
+		out, code := runView(t, "--color", "never", "--level", "error")
+		require.Equal(t, 0, code)
+		assert.Equal(t, "2024/01/02 03:04:07 ERR app/db.go:12"+strings.Repeat(" ", 16)+
+			" app.query"+strings.Repeat(" ", 15)+" query failed table=orders\n", out)
+	})
+
+	t.Run("human with colors", func(t *testing.T) {
+		out, code := runView(t, "--color", "always", "--level", "error")
+		require.Equal(t, 0, code)
+		assert.Contains(t, out, "ERR"+logger.ColorReset)
+		assert.Contains(t, out, logger.ColorDim+"table="+logger.ColorReset)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, code := runView(t, "--format", "json", "--level", "error")
+		require.Equal(t, 0, code)
+		assert.JSONEq(t, `{"level":"error","prefix":"2024/01/02 03:04:07","file":"app/db.go","method":"app.query","line":12,"message":"query failed","table":"orders"}`, out)
+	})
+
+	t.Run("json skips plain lines", func(t *testing.T) {
+		out, code := runView(t, "--format", "json")
+		require.Equal(t, 0, code)
+		assert.Len(t, strings.Split(strings.TrimSpace(out), "\n"), 3)
+	})
+
+	t.Run("raw", func(t *testing.T) {
+		out, code := runView(t, "--format", "raw", "--color", "never", "--where", "path=/v1/users")
+		require.Equal(t, 0, code)
+		assert.Equal(t, strings.Split(testLogs, "\n")[1]+"\n", out)
+	})
+}
+
+// TestViewJSONInput will test reading lines written by the JSON encoder
+func TestViewJSONInput(t *testing.T) {
+	input := `{"time":"2024-01-02T03:04:05Z","level":"warn","file":"app/db.go","method":"app.query","line":7,"message":"slow","rows":12}` + "\n"
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"view", "--color", "never", "--where", "rows=12"}, strings.NewReader(input), &stdout, &stderr)
+	require.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), "2024-01-02T03:04:05Z WRN app/db.go:7")
+	assert.Contains(t, stdout.String(), "slow rows=12")
+}
+
+// TestViewFiles will test reading from files
+func TestViewFiles(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(name, []byte(testLogs), 0o600))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"view", "--level", "error", "--color", "never", name, name}, strings.NewReader(""), &stdout, &stderr)
+	require.Equal(t, 0, code)
+	assert.Equal(t, 2, strings.Count(stdout.String(), "query failed"))
+}
@@ -6,9 +6,10 @@ import "time"
 
 // Package constants
 const (
-	LogEntriesPort         = "10000"               // 80, 514, 443, 10000
-	LogEntriesTestEndpoint = "52.214.43.195"       // This is an IP for now, since GitHub Actions fails on resolving the domains
-	LogEntriesURL          = "data.logentries.com" // "data.insight.rapid7.com" "eu.data.logs.insight.rapid7.com"
+	LogEntriesPort         = "10000"                                                   // 80, 514, 443, 10000
+	LogEntriesTestEndpoint = "52.214.43.195"                                           // This is an IP for now, since GitHub Actions fails on resolving the domains
+	LogEntriesURL          = "data.logentries.com"                                     // "data.insight.rapid7.com" "eu.data.logs.insight.rapid7.com"
+	LogEntriesWebhookURL   = "https://us.webhook.logs.insight.rapid7.com/v1/noformat/" // Followed by the token (eu, ca, au, ap...)
 	MaxRetryDelay          = 2 * time.Minute
 	RetryDelay             = 100 * time.Millisecond
 )
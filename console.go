@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Console column widths
+const (
+	ConsoleFileWidth   = 28
+	ConsoleMethodWidth = 24
+)
+
+// ANSI escape codes used by the console encoder (and the go-logger view command)
+const (
+	ColorReset  = "\x1b[0m"
+	ColorBold   = "\x1b[1m"
+	ColorDim    = "\x1b[2m"
+	ColorRed    = "\x1b[31m"
+	ColorYellow = "\x1b[33m"
+	ColorCyan   = "\x1b[36m"
+	ColorGray   = "\x1b[90m"
+)
+
+// ConsoleEncoder is a human-friendly encoder for local development, with aligned
+// columns and (optionally) level colors
+type ConsoleEncoder struct {
+	Colors      bool // Use ANSI colors
+	FileWidth   int  // Minimum width of the file:line column
+	MethodWidth int  // Minimum width of the method column
+}
+
+// NewConsoleEncoder creates a console encoder, enabling colors when out is a terminal
+// and NO_COLOR is not set
+func NewConsoleEncoder(out io.Writer) *ConsoleEncoder {
+	return &ConsoleEncoder{
+		Colors:      ColorsEnabled(out),
+		FileWidth:   ConsoleFileWidth,
+		MethodWidth: ConsoleMethodWidth,
+	}
+}
+
+// ColorsEnabled reports whether colored output should be written to w: it must be
+// a terminal, and the NO_COLOR environment variable must not be set (https://no-color.org)
+func ColorsEnabled(w io.Writer) bool {
+	if len(os.Getenv("NO_COLOR")) > 0 {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Encode implements the Encoder interface
+func (c *ConsoleEncoder) Encode(buf *bytes.Buffer, e *Entry) {
+	c.colorize(buf, LevelColor(e.Level), levelTag(e.Level))
+
+	if len(e.File) > 0 {
+		buf.WriteByte(' ')
+		c.column(buf, ColorDim, e.File+":"+strconv.Itoa(e.Line), c.FileWidth)
+		buf.WriteByte(' ')
+		c.column(buf, ColorDim, e.Method, c.MethodWidth)
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(e.Message)
+
+	for _, arg := range e.Fields {
+		buf.WriteByte(' ')
+		c.colorize(buf, ColorDim, arg.Key()+"=")
+		buf.WriteString(consoleValue(string(appendValue(nil, arg))))
+	}
+	for i := range e.Typed {
+		buf.WriteByte(' ')
+		c.colorize(buf, ColorDim, e.Typed[i].key+"=")
+		buf.WriteString(consoleValue(string(e.Typed[i].AppendValue(nil))))
+	}
+}
+
+// column writes s, padded with spaces to width (padding stays outside the colors)
+func (c *ConsoleEncoder) column(buf *bytes.Buffer, color, s string, width int) {
+	c.colorize(buf, color, s)
+	if pad := width - len(s); pad > 0 {
+		buf.WriteString(strings.Repeat(" ", pad))
+	}
+}
+
+// colorize writes s wrapped in the given color, if colors are enabled
+func (c *ConsoleEncoder) colorize(buf *bytes.Buffer, color, s string) {
+	if !c.Colors {
+		buf.WriteString(s)
+		return
+	}
+	buf.WriteString(color)
+	buf.WriteString(s)
+	buf.WriteString(ColorReset)
+}
+
+// consoleValue quotes values that would be ambiguous when unquoted
+func consoleValue(s string) string {
+	if len(s) == 0 || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// levelTag is the short, fixed width name of a level
+func levelTag(level LogLevel) string {
+	switch level {
+	case DEBUG:
+		return "DBG"
+	case INFO:
+		return "INF"
+	case WARN:
+		return "WRN"
+	case ERROR:
+		return "ERR"
+	}
+	return "???"
+}
+
+// LevelColor is the console color of a level
+func LevelColor(level LogLevel) string {
+	switch level {
+	case DEBUG:
+		return ColorGray
+	case INFO:
+		return ColorCyan
+	case WARN:
+		return ColorYellow
+	case ERROR:
+		return ColorBold + ColorRed
+	}
+	return ColorReset
+}
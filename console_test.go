@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestConsoleEncoder_Encode will test the ConsoleEncoder
+func TestConsoleEncoder_Encode(t *testing.T) {
+	t.Run("aligned columns", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := &ConsoleEncoder{FileWidth: 30, MethodWidth: 20}
+		enc.Encode(&buf, testEntry())
+		assert.Equal(t, "WRN go-logger/logger_test.go:188   go-logger.TestData   test this method another=value", buf.String())
+	})
+
+	t.Run("long columns are not truncated", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := &ConsoleEncoder{FileWidth: 5, MethodWidth: 5}
+		enc.Encode(&buf, testEntry())
+		assert.True(t, strings.HasPrefix(buf.String(), "WRN go-logger/logger_test.go:188 go-logger.TestData test"))
+	})
+
+	t.Run("no file", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := &ConsoleEncoder{FileWidth: 30, MethodWidth: 20}
+		enc.Encode(&buf, &Entry{Level: DEBUG, Message: "hello", Fields: []KeyValue{
+			MakeParameter("empty", ""),
+			MakeParameter("spaced", "a b"),
+		}})
+		assert.Equal(t, `DBG hello empty="" spaced="a b"`, buf.String())
+	})
+
+	t.Run("typed fields", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := &ConsoleEncoder{}
+		enc.Encode(&buf, &Entry{Level: INFO, Message: "typed", Typed: []Field{Int("n", 1), String("s", "a b")}})
+		assert.Equal(t, `INF typed n=1 s="a b"`, buf.String())
+	})
+
+	t.Run("colors", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := &ConsoleEncoder{Colors: true}
+		enc.Encode(&buf, testEntry())
+		assert.Contains(t, buf.String(), ColorYellow+"WRN"+ColorReset)
+		assert.Contains(t, buf.String(), ColorDim+"another="+ColorReset+"value")
+	})
+}
+
+// TestColorsEnabled will test the terminal and NO_COLOR detection
+func TestColorsEnabled(t *testing.T) {
+	assert.False(t, ColorsEnabled(&bytes.Buffer{}))
+
+	f, err := os.CreateTemp(t.TempDir(), "console")
+	if assert.NoError(t, err) {
+		defer func() { _ = f.Close() }()
+		assert.False(t, ColorsEnabled(f))
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	assert.False(t, ColorsEnabled(os.Stderr))
+}
+
+// TestNewConsoleEncoder will test the console encoder defaults
+func TestNewConsoleEncoder(t *testing.T) {
+	enc := NewConsoleEncoder(&bytes.Buffer{})
+	assert.False(t, enc.Colors)
+	assert.Equal(t, ConsoleFileWidth, enc.FileWidth)
+	assert.Equal(t, ConsoleMethodWidth, enc.MethodWidth)
+}
+
+// BenchmarkConsoleEncoder_Encode benchmarks the ConsoleEncoder
+func BenchmarkConsoleEncoder_Encode(b *testing.B) {
+	var buf bytes.Buffer
+	enc := &ConsoleEncoder{Colors: true, FileWidth: ConsoleFileWidth, MethodWidth: ConsoleMethodWidth}
+	entry := testEntry()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		enc.Encode(&buf, entry)
+	}
+}
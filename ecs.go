@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ECS constants
+const (
+	DefaultECSNamespace = "fields"                        // Object holding the fields of an entry
+	ECSVersion          = "8.11.0"                        // Version of the Elastic Common Schema written in ecs.version
+	ecsTimeFormat       = "2006-01-02T15:04:05.000Z07:00" // Milliseconds, like the ecs-logging libraries
+)
+
+// ECS fields mapped from the fields of an entry
+const (
+	ecsStatement uint8 = 1 << iota // db.statement
+	ecsDuration                    // event.duration
+	ecsError                       // error.message and error.type
+)
+
+// ECSEncoder writes each entry as an Elastic Common Schema JSON object. The fields of the
+// entry are nested in a namespace (custom fields must not collide with ECS fields), except
+// the ones written by the GORM logger's Trace and Err() fields, which map to ECS fields:
+//
+//	sql         -> db.statement
+//	duration_ms -> event.duration (nanoseconds)
+//	error       -> error.message (and error.type for Err() fields)
+//
+// Only the first field mapped to an ECS field is mapped, the others (IE: a second error)
+// stay in the namespace so the object has no duplicate keys.
+type ECSEncoder struct {
+	Namespace string // Object holding the fields (defaults to DefaultECSNamespace)
+}
+
+// Encode implements the Encoder interface
+func (c *ECSEncoder) Encode(buf *bytes.Buffer, e *Entry) {
+	t := e.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	buf.WriteString(`{"@timestamp":"`)
+	buf.Write(t.UTC().AppendFormat(buf.AvailableBuffer(), ecsTimeFormat))
+	buf.WriteString(`","log.level":"`)
+	buf.WriteString(e.Level.String())
+	buf.WriteString(`","message":`)
+	writeJSONString(buf, e.Message)
+	buf.WriteString(`,"ecs.version":"` + ECSVersion + `"`)
+	if len(e.File) > 0 {
+		buf.WriteString(`,"log.origin.file.name":`)
+		writeJSONString(buf, e.File)
+		buf.WriteString(`,"log.origin.file.line":`)
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(e.Line), 10))
+		buf.WriteString(`,"log.origin.function":`)
+		writeJSONString(buf, e.Method)
+	}
+
+	var fields []KeyValue
+	var mapped uint8
+	for _, kv := range e.KeyValues() {
+		if !writeECSField(buf, kv, &mapped) {
+			fields = append(fields, kv)
+		}
+	}
+	if len(fields) > 0 {
+		namespace := c.Namespace
+		if len(namespace) == 0 {
+			namespace = DefaultECSNamespace
+		}
+		buf.WriteByte(',')
+		writeJSONString(buf, namespace)
+		buf.WriteString(`:{`)
+		for i, kv := range fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, kv.Key())
+			buf.WriteByte(':')
+			writeJSONValue(buf, kv)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('}')
+}
+
+// writeECSField writes a field mapped to ECS fields, returning false if it is not mapped
+// (or if its ECS fields were already written, according to mapped)
+func writeECSField(buf *bytes.Buffer, kv KeyValue, mapped *uint8) bool {
+	var field uint8
+	switch kv.Key() {
+	case "sql":
+		field = ecsStatement
+	case "duration_ms":
+		field = ecsDuration
+	case "error":
+		field = ecsError
+	}
+	if field == 0 || *mapped&field != 0 {
+		return false
+	}
+
+	switch field {
+	case ecsStatement:
+		buf.WriteString(`,"db.statement":`)
+		writeJSONString(buf, fmt.Sprint(kv.Value()))
+	case ecsDuration:
+		ms, ok := kv.Value().(float64)
+		if !ok {
+			return false
+		}
+		buf.WriteString(`,"event.duration":`)
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(ms*float64(time.Millisecond)), 10))
+	case ecsError:
+		if f, ok := kv.(Field); ok && f.fieldType == ErrorType {
+			err := fieldError(f)
+			if err == nil {
+				return false
+			}
+			buf.WriteString(`,"error.type":`)
+			writeJSONString(buf, fmt.Sprintf("%T", err))
+		}
+		buf.WriteString(`,"error.message":`)
+		writeJSONString(buf, fmt.Sprint(kv.Value()))
+	}
+	*mapped |= field
+	return true
+}
@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestECSEncoder_Encode will test the ECSEncoder
+func TestECSEncoder_Encode(t *testing.T) {
+	t.Run("entry with fields", func(t *testing.T) {
+		var buf bytes.Buffer
+		e := testEntry()
+		e.Time = time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.FixedZone("EST", -5*3600))
+		e.Fields = append(e.Fields, Int("rows", 12), Bool("ok", false))
+		(&ECSEncoder{}).Encode(&buf, e)
+
+		assert.JSONEq(t, `{
+			"@timestamp":"2024-01-02T08:04:05.123Z","log.level":"warn","message":"test this method",
+			"ecs.version":"`+ECSVersion+`","log.origin.file.name":"go-logger/logger_test.go",
+			"log.origin.file.line":188,"log.origin.function":"go-logger.TestData",
+			"fields":{"another":"value","rows":12,"ok":false}
+		}`, buf.String())
+		assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte(`{"@timestamp":`)))
+	})
+
+	t.Run("mapped fields", func(t *testing.T) {
+		var buf bytes.Buffer
+		(&ECSEncoder{Namespace: "app"}).Encode(&buf, &Entry{
+			Time:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Level:   ERROR,
+			Message: "error executing query",
+			Fields: []KeyValue{
+				MakeParameter("sql", "SELECT 1"),
+				Float64("duration_ms", 1.5),
+				Err(errors.New("boom")),
+				MakeParameter("caller", "main.go:7"),
+			},
+		})
+
+		assert.JSONEq(t, `{
+			"@timestamp":"2024-01-02T03:04:05.000Z","log.level":"error","message":"error executing query",
+			"ecs.version":"`+ECSVersion+`","db.statement":"SELECT 1","event.duration":1500000,
+			"error.type":"*errors.errorString","error.message":"boom","app":{"caller":"main.go:7"}
+		}`, buf.String())
+	})
+
+	t.Run("unmapped values", func(t *testing.T) {
+		var buf bytes.Buffer
+		(&ECSEncoder{}).Encode(&buf, &Entry{Level: INFO, Message: "no file", Fields: []KeyValue{
+			MakeParameter("duration_ms", "fast"),
+			MakeParameter("error", "failed"),
+			Err(nil),
+		}})
+
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.NotEmpty(t, decoded["@timestamp"])
+		assert.NotContains(t, decoded, "log.origin.file.name")
+		assert.NotContains(t, decoded, "error.type")
+		assert.Equal(t, "failed", decoded["error.message"])
+		assert.Equal(t, map[string]interface{}{"duration_ms": "fast", "error": nil}, decoded["fields"])
+	})
+
+	t.Run("fields mapped twice", func(t *testing.T) {
+		var buf bytes.Buffer
+		(&ECSEncoder{}).Encode(&buf, &Entry{Level: ERROR, Message: "twice", Fields: []KeyValue{
+			Err(errors.New("first")),
+			Float64("duration_ms", 1),
+			MakeParameter("error", "second"),
+			Float64("duration_ms", 2),
+		}})
+
+		assert.Equal(t, 1, strings.Count(buf.String(), `"error.message"`))
+		assert.Equal(t, 1, strings.Count(buf.String(), `"event.duration"`))
+		assert.Contains(t, buf.String(), `"error.message":"first"`)
+		assert.Contains(t, buf.String(), `"event.duration":1000000`)
+		assert.Contains(t, buf.String(), `"fields":{"error":"second","duration_ms":2}`)
+	})
+}
+
+// TestECSEncoder_Gorm will test encoding the entries of the GORM logger
+func TestECSEncoder_Gorm(t *testing.T) {
+	SetEncoder(&ECSEncoder{})
+	defer SetEncoder(&LogfmtEncoder{})
+
+	l := NewGormLogger(true, 3)
+	messages := traceMessages(context.Background(), l, 2*time.Millisecond, "SELECT * FROM users WHERE id = 1", nil)
+	require.Len(t, messages, 1)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(messages[0]), &decoded), messages[0])
+	assert.Equal(t, "info", decoded["log.level"])
+	assert.Equal(t, "executing sql query", decoded["message"])
+	assert.Equal(t, "SELECT * FROM users WHERE id = 1", decoded["db.statement"])
+	assert.GreaterOrEqual(t, decoded["event.duration"], float64(2*time.Millisecond))
+
+	fields, ok := decoded["fields"].(map[string]interface{})
+	require.True(t, ok)
+	assert.InDelta(t, 1, fields["rows"], 0)
+	assert.Contains(t, fields, "query_fingerprint")
+	assert.NotContains(t, fields, "sql")
+	assert.NotContains(t, fields, "duration_ms")
+}
+
+// BenchmarkECSEncoder_Encode benchmarks the ECSEncoder
+func BenchmarkECSEncoder_Encode(b *testing.B) {
+	var buf bytes.Buffer
+	enc := &ECSEncoder{}
+	entry := testEntry()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		enc.Encode(&buf, entry)
+	}
+}
@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Encoder names (used by the LOG_ENCODER environment variable)
+const (
+	EncoderConsole = "console"
+	EncoderECS     = "ecs"
+	EncoderJSON    = "json"
+	EncoderLogfmt  = "logfmt"
+)
+
+// Entry is a single structured log entry, as built by Data() and NoFileData()
+type Entry struct {
+	Time    time.Time  // When the entry was created
+	Level   LogLevel   // Log level
+	File    string     // File tag (empty for NoFileData)
+	Method  string     // Method name (empty for NoFileData)
+	Line    int        // Line number (zero for NoFileData)
+	Message string     // Log message
+	Fields  []KeyValue // Additional key/value pairs
+	Typed   []Field    // Typed fields (DataFields and NoFileDataFields), written after Fields
+}
+
+// KeyValues returns the fields followed by the typed fields of the entry, for encoders and
+// sinks that don't write the typed fields separately (the typed fields are boxed, which
+// allocates)
+func (e *Entry) KeyValues() []KeyValue {
+	if len(e.Typed) == 0 {
+		return e.Fields
+	}
+	kvs := make([]KeyValue, 0, len(e.Fields)+len(e.Typed))
+	kvs = append(kvs, e.Fields...)
+	for _, f := range e.Typed {
+		kvs = append(kvs, f)
+	}
+	return kvs
+}
+
+// Encoder turns a log entry into a single line of text
+type Encoder interface {
+	Encode(buf *bytes.Buffer, e *Entry)
+}
+
+// encoder is the current Encoder used by Data() and NoFileData()
+//
+//nolint:gochecknoglobals // Global variable required for logger package design
+var encoder Encoder = &LogfmtEncoder{}
+
+// SetEncoder allows the encoder used by Data() and NoFileData() to be swapped at runtime
+func SetEncoder(enc Encoder) {
+	encoder = enc
+}
+
+// GetEncoder gets the current encoder
+func GetEncoder() Encoder {
+	return encoder
+}
+
+// newEncoder returns the encoder for a name (see LOG_ENCODER), or nil if the name is unknown,
+// out is the sink of the lines (the console encoder only uses colors on a terminal)
+func newEncoder(name string, out io.Writer) Encoder {
+	switch name {
+	case EncoderLogfmt:
+		return &LogfmtEncoder{}
+	case EncoderConsole:
+		return NewConsoleEncoder(out)
+	case EncoderJSON:
+		return &JSONEncoder{}
+	case EncoderECS:
+		return &ECSEncoder{}
+	}
+	return nil
+}
+
+// LogfmtEncoder is the standard key="value" Log Entries compatible format
+//
+// This is the default encoder, and its output can be read back using ParseDataLine()
+type LogfmtEncoder struct{}
+
+// Encode implements the Encoder interface
+func (l *LogfmtEncoder) Encode(buf *bytes.Buffer, e *Entry) {
+	buf.WriteString(`type="`)
+	buf.WriteString(e.Level.String())
+	if len(e.File) > 0 {
+		buf.WriteString(`" file="`)
+		buf.WriteString(e.File)
+		buf.WriteString(`" method="`)
+		buf.WriteString(e.Method)
+		buf.WriteString(`" line="`)
+		buf.WriteString(strconv.Itoa(e.Line))
+	}
+	buf.WriteString(`" message="`)
+	buf.WriteString(e.Message)
+	buf.WriteString(`"`)
+
+	for _, arg := range e.Fields {
+		buf.WriteByte(' ')
+		if f, ok := arg.(Field); ok {
+			writeLogfmtField(buf, &f)
+			continue
+		}
+		buf.WriteString(arg.Key())
+		buf.WriteString(`="`)
+		buf.Write(appendValue(buf.AvailableBuffer(), arg))
+		buf.WriteByte('"')
+	}
+	for i := range e.Typed {
+		buf.WriteByte(' ')
+		writeLogfmtField(buf, &e.Typed[i])
+	}
+}
+
+// writeLogfmtField writes a typed field without boxing it
+func writeLogfmtField(buf *bytes.Buffer, f *Field) {
+	if f.fieldType == ErrorType {
+		if err, _ := f.obj.(error); err != nil {
+			writeErrorLogfmt(buf, f.key, err)
+			return
+		}
+	}
+	buf.WriteString(f.key)
+	if f.numeric() {
+		// Numbers are unquoted, so log backends can index them as numbers
+		buf.WriteByte('=')
+		buf.Write(f.AppendValue(buf.AvailableBuffer()))
+		return
+	}
+	buf.WriteString(`="`)
+	buf.Write(f.AppendValue(buf.AvailableBuffer()))
+	buf.WriteByte('"')
+}
+
+// appendValue appends the text form of a key/value pair's value, natively for typed fields
+func appendValue(dst []byte, kv KeyValue) []byte {
+	if f, ok := kv.(Field); ok {
+		return f.AppendValue(dst)
+	}
+	return fmt.Append(dst, kv.Value())
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEntry is a sample entry used by the encoder tests
+func testEntry() *Entry {
+	return &Entry{
+		Level:   WARN,
+		File:    "go-logger/logger_test.go",
+		Method:  "go-logger.TestData",
+		Line:    188,
+		Message: "test this method",
+		Fields:  []KeyValue{MakeParameter("another", "value")},
+	}
+}
+
+// TestLogfmtEncoder_Encode will test the LogfmtEncoder
+func TestLogfmtEncoder_Encode(t *testing.T) {
+	t.Run("with file", func(t *testing.T) {
+		var buf bytes.Buffer
+		(&LogfmtEncoder{}).Encode(&buf, testEntry())
+		assert.Equal(t, `type="warn" file="go-logger/logger_test.go" method="go-logger.TestData" line="188" message="test this method" another="value"`, buf.String())
+	})
+
+	t.Run("without file", func(t *testing.T) {
+		var buf bytes.Buffer
+		(&LogfmtEncoder{}).Encode(&buf, &Entry{Level: INFO, Message: "no file", Fields: []KeyValue{MakeParameter("n", 1)}})
+		assert.Equal(t, `type="info" message="no file" n="1"`, buf.String())
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		var buf bytes.Buffer
+		(&LogfmtEncoder{}).Encode(&buf, testEntry())
+		d, err := ParseDataLine(buf.String())
+		require.NoError(t, err)
+
+		entry := d.Entry()
+		assert.Equal(t, testEntry().File, entry.File)
+		assert.Equal(t, "another", entry.Fields[0].Key())
+		assert.Equal(t, "value", entry.Fields[0].Value())
+	})
+}
+
+// TestSetEncoder will test swapping the encoder used by Data()
+func TestSetEncoder(t *testing.T) {
+	defer SetEncoder(&LogfmtEncoder{})
+
+	SetEncoder(&ConsoleEncoder{})
+	assert.IsType(t, &ConsoleEncoder{}, GetEncoder())
+
+	implementation = &logPkg{}
+	captured := captureOutput(func() {
+		NoFileData(ERROR, "console message", MakeParameter("key", "some value"))
+	})
+	assert.Contains(t, captured, `ERR console message key="some value"`)
+}
+
+// TestNewEncoder will test looking up encoders by name
+func TestNewEncoder(t *testing.T) {
+	assert.IsType(t, &LogfmtEncoder{}, newEncoder(EncoderLogfmt, os.Stderr))
+	assert.IsType(t, &ConsoleEncoder{}, newEncoder(EncoderConsole, os.Stderr))
+	assert.IsType(t, &JSONEncoder{}, newEncoder(EncoderJSON, os.Stderr))
+	assert.IsType(t, &ECSEncoder{}, newEncoder(EncoderECS, os.Stderr))
+	assert.Nil(t, newEncoder("", os.Stderr))
+	assert.Nil(t, newEncoder("unknown", os.Stderr))
+
+	t.Run("console to a file", func(t *testing.T) {
+		t.Setenv("NO_COLOR", "")
+		f, err := os.Create(filepath.Join(t.TempDir(), "app.log"))
+		require.NoError(t, err)
+		defer func() { _ = f.Close() }()
+
+		enc, ok := newEncoder(EncoderConsole, f).(*ConsoleEncoder)
+		require.True(t, ok)
+		assert.False(t, enc.Colors)
+
+		enc, ok = newEncoder(EncoderConsole, nil).(*ConsoleEncoder)
+		require.True(t, ok)
+		assert.False(t, enc.Colors)
+	})
+}
+
+// BenchmarkLogfmtEncoder_Encode benchmarks the LogfmtEncoder
+func BenchmarkLogfmtEncoder_Encode(b *testing.B) {
+	var buf bytes.Buffer
+	enc := &LogfmtEncoder{}
+	entry := testEntry()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		enc.Encode(&buf, entry)
+	}
+}
@@ -0,0 +1,254 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxErrorDepth limits how far an error chain is followed (guards against cycles)
+const maxErrorDepth = 32
+
+// errorInfo is the rendered form of an error used by the Err() field
+type errorInfo struct {
+	message string      // err.Error()
+	errType string      // Concrete type (IE: *fs.PathError)
+	chain   []errorInfo // Wrapped errors (errors.Unwrap or errors.Join)
+	stack   []string    // Frames, if the error exposes its stack (see stackFrames)
+}
+
+// newErrorInfo renders an error, following its wrapped errors
+func newErrorInfo(err error, depth int) errorInfo {
+	info := errorInfo{
+		message: err.Error(),
+		errType: fmt.Sprintf("%T", err),
+		stack:   stackFrames(err),
+	}
+	if depth >= maxErrorDepth {
+		return info
+	}
+
+	var wrapped []error
+	switch e := err.(type) { //nolint:errorlint // looking for the Unwrap methods of this error, not its chain
+	case interface{ Unwrap() []error }:
+		wrapped = e.Unwrap()
+	case interface{ Unwrap() error }:
+		wrapped = []error{e.Unwrap()}
+	}
+	for _, w := range wrapped {
+		if w != nil {
+			info.chain = append(info.chain, newErrorInfo(w, depth+1))
+		}
+	}
+	return info
+}
+
+// stackTracer is implemented by errors exposing where they were created as program counters
+type stackTracer interface {
+	StackTrace() []uintptr
+}
+
+// callersTracer is implemented by errors exposing their program counters as Callers()
+// (IE: github.com/go-errors/errors)
+type callersTracer interface {
+	Callers() []uintptr
+}
+
+// frameTracer is implemented by errors exposing their stack as resolved frames
+type frameTracer interface {
+	StackTrace() []runtime.Frame
+}
+
+// stackFrames returns the frames of an error exposing its stack (see stackTracer,
+// callersTracer and frameTracer). Interfaces are matched instead of looking up the method
+// by name, which would keep every method of every type in the binary. Errors of
+// github.com/pkg/errors return a named StackTrace type, which can't be matched without
+// importing the package, so their stack is read from their %+v output instead.
+func stackFrames(err error) []string {
+	switch e := err.(type) { //nolint:errorlint // looking for the stack of this error, not its chain
+	case stackTracer:
+		return callersFrames(e.StackTrace())
+	case callersTracer:
+		return callersFrames(e.Callers())
+	case frameTracer:
+		trace := e.StackTrace()
+		frames := make([]string, 0, len(trace))
+		for _, f := range trace {
+			frames = append(frames, formatFrame(f.Function, f.File, f.Line))
+		}
+		return frames
+	case fmt.Formatter:
+		return formattedFrames(e)
+	}
+	return nil
+}
+
+// formattedFrames reads the stack from the %+v output of an error, in the format of
+// github.com/pkg/errors (a "function" line followed by a "\tfile:line" line per frame).
+// Wrapping errors print the stack of their cause first, so the last stack is kept.
+func formattedFrames(f fmt.Formatter) []string {
+	lines := strings.Split(fmt.Sprintf("%+v", f), "\n")
+
+	var frames, current []string
+	for i := 0; i+1 < len(lines); i++ {
+		function, location := lines[i], lines[i+1]
+		if len(function) == 0 || function[0] == '\t' || !isFrameLocation(location) {
+			if len(current) > 0 {
+				frames, current = current, nil
+			}
+			continue
+		}
+		current = append(current, function+" "+location[1:])
+		i++
+	}
+	if len(current) > 0 {
+		frames = current
+	}
+	return frames
+}
+
+// isFrameLocation reports whether s is a "\tfile:line" line
+func isFrameLocation(s string) bool {
+	colon := strings.LastIndexByte(s, ':')
+	if len(s) < 2 || s[0] != '\t' || colon < 2 {
+		return false
+	}
+	_, err := strconv.Atoi(s[colon+1:])
+	return err == nil
+}
+
+// callersFrames resolves program counters into frames
+func callersFrames(pcs []uintptr) []string {
+	frames := make([]string, 0, len(pcs))
+	iter := runtime.CallersFrames(pcs)
+	for {
+		f, more := iter.Next()
+		if f.PC != 0 {
+			frames = append(frames, formatFrame(f.Function, f.File, f.Line))
+		}
+		if !more {
+			return frames
+		}
+	}
+}
+
+// formatFrame formats a single stack frame
+func formatFrame(function, file string, line int) string {
+	return function + " " + file + ":" + strconv.Itoa(line)
+}
+
+// deepestStack returns the stack closest to where the error originated
+func (e *errorInfo) deepestStack() []string {
+	stack := e.stack
+	for i := range e.chain {
+		if s := e.chain[i].deepestStack(); len(s) > 0 {
+			stack = s
+		}
+	}
+	return stack
+}
+
+// appendChain appends the wrapped errors as a nested list: [type: message [type: message]; ...]
+func (e *errorInfo) appendChain(buf *bytes.Buffer) {
+	buf.WriteByte('[')
+	for i := range e.chain {
+		if i > 0 {
+			buf.WriteString("; ")
+		}
+		c := &e.chain[i]
+		buf.WriteString(c.errType)
+		buf.WriteString(": ")
+		buf.WriteString(singleLine(c.message))
+		if len(c.chain) > 0 {
+			buf.WriteByte(' ')
+			c.appendChain(buf)
+		}
+	}
+	buf.WriteByte(']')
+}
+
+// singleLine keeps multi-line messages (IE: from errors.Join) on one log line
+func singleLine(s string) string {
+	return strings.ReplaceAll(s, "\n", " | ")
+}
+
+// writeErrorLogfmt writes an error field as key="message" key.type="..." and, when
+// present, key.chain="[...]" and key.stack="..."
+func writeErrorLogfmt(buf *bytes.Buffer, key string, err error) {
+	info := newErrorInfo(err, 0)
+
+	buf.WriteString(key)
+	buf.WriteString(`="`)
+	buf.WriteString(singleLine(info.message))
+	buf.WriteString(`" `)
+	buf.WriteString(key)
+	buf.WriteString(`.type="`)
+	buf.WriteString(info.errType)
+	buf.WriteByte('"')
+
+	if len(info.chain) > 0 {
+		buf.WriteByte(' ')
+		buf.WriteString(key)
+		buf.WriteString(`.chain="`)
+		info.appendChain(buf)
+		buf.WriteByte('"')
+	}
+
+	if stack := info.deepestStack(); len(stack) > 0 {
+		buf.WriteByte(' ')
+		buf.WriteString(key)
+		buf.WriteString(`.stack="`)
+		buf.WriteString(strings.Join(stack, "; "))
+		buf.WriteByte('"')
+	}
+}
+
+// writeErrorJSON writes an error as {"message":...,"type":...,"chain":[...],"stack":[...]},
+// with the deepest stack of the chain (like the logfmt key.stack)
+func writeErrorJSON(buf *bytes.Buffer, info *errorInfo) {
+	openErrorJSON(buf, info)
+	if stack := info.deepestStack(); len(stack) > 0 {
+		buf.WriteString(`,"stack":[`)
+		for i, frame := range stack {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, frame)
+		}
+		buf.WriteByte(']')
+	}
+	buf.WriteByte('}')
+}
+
+// openErrorJSON writes an error as {"message":...,"type":...,"chain":[...] leaving the
+// object open (the wrapped errors are closed)
+func openErrorJSON(buf *bytes.Buffer, info *errorInfo) {
+	buf.WriteString(`{"message":`)
+	writeJSONString(buf, info.message)
+	buf.WriteString(`,"type":`)
+	writeJSONString(buf, info.errType)
+
+	if len(info.chain) > 0 {
+		buf.WriteString(`,"chain":[`)
+		for i := range info.chain {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			openErrorJSON(buf, &info.chain[i])
+			buf.WriteByte('}')
+		}
+		buf.WriteByte(']')
+	}
+}
+
+// fieldError returns the (non-nil) error held by an Err() field, or nil if kv is not one
+func fieldError(kv KeyValue) error {
+	f, ok := kv.(Field)
+	if !ok || f.fieldType != ErrorType {
+		return nil
+	}
+	err, _ := f.obj.(error)
+	return err
+}
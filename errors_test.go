@@ -0,0 +1,241 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stackError exposes its stack as program counters
+type stackError struct {
+	err   error
+	stack []uintptr
+}
+
+func newStackError(message string) *stackError {
+	pcs := make([]uintptr, 8)
+	n := runtime.Callers(2, pcs)
+	return &stackError{err: errors.New(message), stack: pcs[:n]}
+}
+
+func (s *stackError) Error() string { return s.err.Error() }
+
+func (s *stackError) Unwrap() error { return s.err }
+
+func (s *stackError) StackTrace() []uintptr { return s.stack }
+
+// frameError exposes its stack as resolved frames
+type frameError struct{}
+
+func (frameError) Error() string { return "frame error" }
+
+func (frameError) StackTrace() []runtime.Frame {
+	return []runtime.Frame{
+		{Function: "main.handler", File: "/app/main.go", Line: 12},
+		{Function: "main.main", File: "/app/main.go", Line: 5},
+	}
+}
+
+// callersError exposes its program counters like github.com/go-errors/errors
+type callersError struct {
+	pcs []uintptr
+}
+
+func (callersError) Error() string { return "callers error" }
+
+func (c callersError) Callers() []uintptr { return c.pcs }
+
+// pkgFrame and pkgStackTrace are shaped like the Frame and StackTrace types of
+// github.com/pkg/errors
+type (
+	pkgFrame      uintptr
+	pkgStackTrace []pkgFrame
+)
+
+// pkgError is shaped like the errors of github.com/pkg/errors: its stack is a named type,
+// and it's printed by %+v
+type pkgError struct {
+	cause error
+	msg   string
+	stack pkgStackTrace
+}
+
+func newPkgError(cause error, message string) *pkgError {
+	pcs := make([]uintptr, 8)
+	n := runtime.Callers(2, pcs)
+	stack := make(pkgStackTrace, n)
+	for i := range stack {
+		stack[i] = pkgFrame(pcs[i])
+	}
+	return &pkgError{cause: cause, msg: message, stack: stack}
+}
+
+// pkgCause returns a pkgError created in another function
+func pkgCause() error { return newPkgError(nil, "cause") }
+
+func (p *pkgError) Error() string { return p.msg }
+
+func (p *pkgError) Unwrap() error { return p.cause }
+
+func (p *pkgError) StackTrace() pkgStackTrace { return p.stack }
+
+func (p *pkgError) Format(s fmt.State, verb rune) {
+	if verb != 'v' || !s.Flag('+') {
+		_, _ = io.WriteString(s, p.msg)
+		return
+	}
+	if p.cause != nil {
+		_, _ = fmt.Fprintf(s, "%+v\n", p.cause)
+	}
+	_, _ = io.WriteString(s, p.msg)
+	for _, f := range p.stack {
+		fn := runtime.FuncForPC(uintptr(f) - 1)
+		file, line := fn.FileLine(uintptr(f) - 1)
+		_, _ = fmt.Fprintf(s, "\n%s\n\t%s:%d", fn.Name(), file, line)
+	}
+}
+
+// TestErrLogfmt will test rendering errors with the logfmt encoder
+func TestErrLogfmt(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "plain error",
+			err:      errors.New("boom"),
+			expected: `error="boom" error.type="*errors.errorString"`,
+		},
+		{
+			name:     "wrapped chain",
+			err:      fmt.Errorf("outer: %w", fmt.Errorf("middle: %w", fs.ErrNotExist)),
+			expected: `error="outer: middle: file does not exist" error.type="*fmt.wrapError" error.chain="[*fmt.wrapError: middle: file does not exist [*errors.errorString: file does not exist]]"`,
+		},
+		{
+			name:     "joined errors",
+			err:      errors.Join(errors.New("first"), fmt.Errorf("second: %w", fs.ErrClosed)),
+			expected: `error="first | second: file already closed" error.type="*errors.joinError" error.chain="[*errors.errorString: first; *fmt.wrapError: second: file already closed [*errors.errorString: file already closed]]"`,
+		},
+		{
+			name:     "resolved frames",
+			err:      frameError{},
+			expected: `error="frame error" error.type="logger.frameError" error.stack="main.handler /app/main.go:12; main.main /app/main.go:5"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			(&LogfmtEncoder{}).Encode(&buf, &Entry{Level: ERROR, Message: "failed", Fields: []KeyValue{Err(tt.err)}})
+			assert.Equal(t, `type="error" message="failed" `+tt.expected, buf.String())
+
+			_, err := ParseDataLine(buf.String())
+			require.NoError(t, err)
+		})
+	}
+}
+
+// TestErrStack will test rendering an error with program counters as its stack
+func TestErrStack(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", newStackError("with stack"))
+
+	var buf bytes.Buffer
+	(&LogfmtEncoder{}).Encode(&buf, &Entry{Level: ERROR, Message: "failed", Fields: []KeyValue{Err(err)}})
+	assert.Contains(t, buf.String(), `error.stack="github.com/mrz1836/go-logger.TestErrStack `)
+	assert.Contains(t, buf.String(), "errors_test.go:")
+
+	buf.Reset()
+	(&JSONEncoder{}).Encode(&buf, &Entry{Level: ERROR, Message: "failed", Fields: []KeyValue{Err(err)}})
+
+	var decoded struct {
+		Error struct {
+			Message string   `json:"message"`
+			Type    string   `json:"type"`
+			Stack   []string `json:"stack"`
+			Chain   []struct {
+				Type  string   `json:"type"`
+				Stack []string `json:"stack"`
+				Chain []struct {
+					Message string `json:"message"`
+				} `json:"chain"`
+			} `json:"chain"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "wrapped: with stack", decoded.Error.Message)
+	assert.Equal(t, "*fmt.wrapError", decoded.Error.Type)
+	require.NotEmpty(t, decoded.Error.Stack)
+	assert.Contains(t, decoded.Error.Stack[0], "github.com/mrz1836/go-logger.TestErrStack ")
+	require.Len(t, decoded.Error.Chain, 1)
+	assert.Equal(t, "*logger.stackError", decoded.Error.Chain[0].Type)
+	assert.Empty(t, decoded.Error.Chain[0].Stack, "the stack is written once, like with logfmt")
+	require.Len(t, decoded.Error.Chain[0].Chain, 1)
+	assert.Equal(t, "with stack", decoded.Error.Chain[0].Chain[0].Message)
+}
+
+// TestStackFrames will test the stackFrames() method
+func TestStackFrames(t *testing.T) {
+	pcs := make([]uintptr, 8)
+	pcs = pcs[:runtime.Callers(1, pcs)]
+
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"program counters", &stackError{err: errors.New("boom"), stack: pcs}, "github.com/mrz1836/go-logger.TestStackFrames "},
+		{"callers", callersError{pcs: pcs}, "github.com/mrz1836/go-logger.TestStackFrames "},
+		{"resolved frames", frameError{}, "main.handler /app/main.go:12"},
+		{"pkg/errors stack", newPkgError(nil, "boom"), "github.com/mrz1836/go-logger.TestStackFrames "},
+		{"pkg/errors wrapped stack", newPkgError(pkgCause(), "wrapped"), "github.com/mrz1836/go-logger.TestStackFrames "},
+		{"formatter without stack", &pkgError{msg: "boom"}, ""},
+		{"no stack", errors.New("boom"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := stackFrames(tt.err)
+			if len(tt.expected) == 0 {
+				assert.Empty(t, frames)
+				return
+			}
+			require.NotEmpty(t, frames)
+			assert.Contains(t, frames[0], tt.expected)
+		})
+	}
+}
+
+// TestErrJSON will test rendering joined and nil errors with the JSON encoder
+func TestErrJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := errors.Join(errors.New("first"), errors.New("second"))
+	(&JSONEncoder{}).Encode(&buf, &Entry{Level: ERROR, Message: "failed", Fields: []KeyValue{Err(err)}})
+
+	assert.JSONEq(t, `{"level":"error","message":"failed","error":{"message":"first\nsecond","type":"*errors.joinError","chain":[
+		{"message":"first","type":"*errors.errorString"},
+		{"message":"second","type":"*errors.errorString"}
+	]}}`, buf.String())
+
+	buf.Reset()
+	(&JSONEncoder{}).Encode(&buf, &Entry{Level: ERROR, Message: "failed", Fields: []KeyValue{Err(nil)}})
+	assert.JSONEq(t, `{"level":"error","message":"failed","error":null}`, buf.String())
+}
+
+// BenchmarkErrLogfmt benchmarks rendering a wrapped error with the logfmt encoder
+func BenchmarkErrLogfmt(b *testing.B) {
+	var buf bytes.Buffer
+	enc := &LogfmtEncoder{}
+	entry := &Entry{Level: ERROR, Message: "failed", Fields: []KeyValue{Err(fmt.Errorf("outer: %w", fs.ErrNotExist))}}
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		enc.Encode(&buf, entry)
+	}
+}
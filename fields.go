@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// FieldType is the type of value held by a Field
+type FieldType uint8
+
+// Field types
+const (
+	AnyType FieldType = iota
+	StringType
+	IntType
+	FloatType
+	BoolType
+	DurationType
+	TimeType
+	ErrorType
+	StringerType
+)
+
+// Field is a typed key/value pair for Data() and NoFileData()
+//
+// Unlike Parameter, primitive values are stored unboxed, so creating a Field does
+// not allocate, and encoders can write the value natively (see AppendValue). Passing
+// a Field to Data() still boxes it in a KeyValue, which allocates like a Parameter does,
+// use DataFields() and NoFileDataFields() to pass the fields without boxing them.
+type Field struct {
+	key       string
+	fieldType FieldType
+	num       int64       // IntType, FloatType (bits), BoolType, DurationType, TimeType (unix nano)
+	str       string      // StringType
+	obj       interface{} // AnyType, ErrorType, StringerType, TimeType (*time.Location or time.Time)
+}
+
+// String creates a string field
+func String(key, value string) Field {
+	return Field{key: key, fieldType: StringType, str: value}
+}
+
+// Int creates an integer field
+func Int(key string, value int) Field {
+	return Field{key: key, fieldType: IntType, num: int64(value)}
+}
+
+// Int64 creates a 64-bit integer field
+func Int64(key string, value int64) Field {
+	return Field{key: key, fieldType: IntType, num: value}
+}
+
+// Float64 creates a floating point field
+func Float64(key string, value float64) Field {
+	return Field{key: key, fieldType: FloatType, num: int64(math.Float64bits(value))} //nolint:gosec // G115: bits are restored by math.Float64frombits
+}
+
+// Bool creates a boolean field
+func Bool(key string, value bool) Field {
+	var num int64
+	if value {
+		num = 1
+	}
+	return Field{key: key, fieldType: BoolType, num: num}
+}
+
+// Duration creates a duration field
+func Duration(key string, value time.Duration) Field {
+	return Field{key: key, fieldType: DurationType, num: int64(value)}
+}
+
+// Time creates a time field
+func Time(key string, value time.Time) Field {
+	// Times outside the range of UnixNano (and the zero time) are kept as-is
+	if value.Year() < 1678 || value.Year() > 2261 {
+		return Field{key: key, fieldType: TimeType, obj: value}
+	}
+	return Field{key: key, fieldType: TimeType, num: value.UnixNano(), obj: value.Location()}
+}
+
+// Err creates an "error" field
+func Err(err error) Field {
+	return Field{key: "error", fieldType: ErrorType, obj: err}
+}
+
+// Stringer creates a field from a fmt.Stringer, which is only called when the field is encoded
+func Stringer(key string, value fmt.Stringer) Field {
+	return Field{key: key, fieldType: StringerType, obj: value}
+}
+
+// Any creates a field from any value, using the typed constructors where possible
+func Any(key string, value interface{}) Field {
+	switch v := value.(type) {
+	case string:
+		return String(key, v)
+	case int:
+		return Int(key, v)
+	case int64:
+		return Int64(key, v)
+	case int32:
+		return Int64(key, int64(v))
+	case float64:
+		return Float64(key, v)
+	case float32:
+		return Float64(key, float64(v))
+	case bool:
+		return Bool(key, v)
+	case time.Duration:
+		return Duration(key, v)
+	case time.Time:
+		return Time(key, v)
+	case error:
+		f := Err(v)
+		f.key = key
+		return f
+	case fmt.Stringer:
+		return Stringer(key, v)
+	}
+	return Field{key: key, fieldType: AnyType, obj: value}
+}
+
+// Key implements the Logger KeyValue interface
+func (f Field) Key() string {
+	return f.key
+}
+
+// Type returns the type of value held by the field
+func (f Field) Type() FieldType {
+	return f.fieldType
+}
+
+// Value implements the Logger KeyValue interface (this boxes primitive values)
+func (f Field) Value() interface{} {
+	switch f.fieldType {
+	case StringType:
+		return f.str
+	case IntType:
+		return f.num
+	case FloatType:
+		return f.float()
+	case BoolType:
+		return f.num == 1
+	case DurationType:
+		return time.Duration(f.num)
+	case TimeType:
+		return f.time()
+	case AnyType, ErrorType, StringerType:
+		return f.obj
+	}
+	return f.obj
+}
+
+// AppendValue appends the text form of the value to dst, without boxing primitive values
+func (f Field) AppendValue(dst []byte) []byte {
+	switch f.fieldType {
+	case StringType:
+		return append(dst, f.str...)
+	case IntType:
+		return strconv.AppendInt(dst, f.num, 10)
+	case FloatType:
+		return strconv.AppendFloat(dst, f.float(), 'f', -1, 64)
+	case BoolType:
+		return strconv.AppendBool(dst, f.num == 1)
+	case DurationType:
+		return append(dst, time.Duration(f.num).String()...)
+	case TimeType:
+		return f.time().AppendFormat(dst, time.RFC3339Nano)
+	case ErrorType, StringerType:
+		return append(dst, methodString(f.obj)...)
+	case AnyType:
+	}
+	return fmt.Append(dst, f.obj)
+}
+
+// methodString returns the Error() or String() text of a value, like fmt does: <nil> for a
+// nil value or a nil pointer whose method panics, and a PANIC= text for other panics
+func methodString(v interface{}) (s string) {
+	method := "String"
+	defer func() {
+		if r := recover(); r != nil {
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+				s = "<nil>"
+				return
+			}
+			s = fmt.Sprintf("%%!v(PANIC=%s method: %v)", method, r)
+		}
+	}()
+
+	switch value := v.(type) {
+	case error:
+		method = "Error"
+		return value.Error()
+	case fmt.Stringer:
+		return value.String()
+	}
+	return "<nil>"
+}
+
+// numeric reports whether the field holds a number (written unquoted by the LogfmtEncoder)
+func (f Field) numeric() bool {
+	return f.fieldType == IntType || f.fieldType == FloatType
+}
+
+// float returns the value of a FloatType field
+func (f Field) float() float64 {
+	return math.Float64frombits(uint64(f.num)) //nolint:gosec // G115: stored by Float64()
+}
+
+// time returns the value of a TimeType field
+func (f Field) time() time.Time {
+	if t, ok := f.obj.(time.Time); ok {
+		return t
+	}
+	t := time.Unix(0, f.num)
+	if loc, ok := f.obj.(*time.Location); ok && loc != nil {
+		t = t.In(loc)
+	}
+	return t
+}
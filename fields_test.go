@@ -0,0 +1,244 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFieldConstructors will test the typed field constructors
+func TestFieldConstructors(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	testErr := errors.New("test error")
+
+	tests := []struct {
+		name          string
+		field         Field
+		expectedKey   string
+		expectedType  FieldType
+		expectedValue interface{}
+		expectedText  string
+	}{
+		{"string", String("k", "v"), "k", StringType, "v", "v"},
+		{"int", Int("k", -42), "k", IntType, int64(-42), "-42"},
+		{"int64", Int64("k", math.MaxInt64), "k", IntType, int64(math.MaxInt64), "9223372036854775807"},
+		{"float64", Float64("k", 1.5), "k", FloatType, 1.5, "1.5"},
+		{"bool true", Bool("k", true), "k", BoolType, true, "true"},
+		{"bool false", Bool("k", false), "k", BoolType, false, "false"},
+		{"duration", Duration("k", 1500*time.Millisecond), "k", DurationType, 1500 * time.Millisecond, "1.5s"},
+		{"time", Time("k", now), "k", TimeType, now, "2024-01-02T03:04:05.000000006Z"},
+		{"zero time", Time("k", time.Time{}), "k", TimeType, time.Time{}, "0001-01-01T00:00:00Z"},
+		{"error", Err(testErr), "error", ErrorType, testErr, "test error"},
+		{"nil error", Err(nil), "error", ErrorType, nil, "<nil>"},
+		{"stringer", Stringer("k", net.IPv4(127, 0, 0, 1)), "k", StringerType, net.IPv4(127, 0, 0, 1), "127.0.0.1"},
+		{"nil stringer pointer", Stringer("k", (*net.IPNet)(nil)), "k", StringerType, (*net.IPNet)(nil), "<nil>"},
+		{"panicking stringer", Stringer("k", panicStringer{}), "k", StringerType, panicStringer{}, "%!v(PANIC=String method: boom)"},
+		{"any", Any("k", []int{1, 2}), "k", AnyType, []int{1, 2}, "[1 2]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedKey, tt.field.Key())
+			assert.Equal(t, tt.expectedType, tt.field.Type())
+			assert.Equal(t, tt.expectedValue, tt.field.Value())
+			assert.Equal(t, tt.expectedText, string(tt.field.AppendValue(nil)))
+		})
+	}
+}
+
+// panicStringer is a fmt.Stringer panicking in String()
+type panicStringer struct{}
+
+// String implements fmt.Stringer
+func (panicStringer) String() string {
+	panic("boom")
+}
+
+// TestAny will test that Any() uses the typed constructors
+func TestAny(t *testing.T) {
+	tests := []struct {
+		value        interface{}
+		expectedType FieldType
+	}{
+		{"s", StringType},
+		{1, IntType},
+		{int32(1), IntType},
+		{int64(1), IntType},
+		{float32(1), FloatType},
+		{1.0, FloatType},
+		{true, BoolType},
+		{time.Second, DurationType},
+		{time.Now(), TimeType},
+		{errors.New("e"), ErrorType},
+		{net.IPv4(127, 0, 0, 1), StringerType},
+		{nil, AnyType},
+	}
+
+	for _, tt := range tests {
+		f := Any("key", tt.value)
+		assert.Equal(t, "key", f.Key())
+		assert.Equal(t, tt.expectedType, f.Type(), "%T", tt.value)
+	}
+}
+
+// TestFieldsInData will test using typed fields with Data()
+func TestFieldsInData(t *testing.T) {
+	implementation = &logPkg{}
+	captured := captureOutput(func() {
+		Data(2, INFO, "typed", String("s", "v"), Int("n", 3), Bool("ok", true), Err(errors.New("boom")))
+	})
+
+	assert.Contains(t, captured, `s="v" n=3 ok="true" error="boom" error.type="*errors.errorString"`)
+
+	captured = captureOutput(func() {
+		DataFields(2, INFO, "typed", String("s", "v"), Int("n", 3))
+		NoFileDataFields(WARN, "no file", Float64("f", 1.5))
+	})
+	assert.Contains(t, captured, `method="go-logger.TestFieldsInData.func2" line=`)
+	assert.Contains(t, captured, `message="typed" s="v" n=3`)
+	assert.Contains(t, captured, `type="warn" message="no file" f=1.5`)
+}
+
+// TestEntry_KeyValues will test the KeyValues() method
+func TestEntry_KeyValues(t *testing.T) {
+	fields := []KeyValue{MakeParameter("p", 1)}
+	assert.Equal(t, fields, (&Entry{Fields: fields}).KeyValues())
+	assert.Equal(t, []KeyValue{MakeParameter("p", 1), Int("n", 2)}, (&Entry{Fields: fields, Typed: []Field{Int("n", 2)}}).KeyValues())
+	assert.Len(t, fields, 1)
+}
+
+// TestFieldAllocations will test the allocations of creating, encoding and logging typed fields
+func TestFieldAllocations(t *testing.T) {
+	t.Run("create and append", func(t *testing.T) {
+		buf := bytes.NewBuffer(make([]byte, 0, 1024))
+		allocs := testing.AllocsPerRun(100, func() {
+			buf.Reset()
+			f := Int("n", 42)
+			buf.Write(f.AppendValue(buf.AvailableBuffer()))
+			f = Float64("f", 1.25)
+			buf.Write(f.AppendValue(buf.AvailableBuffer()))
+			f = String("s", "value")
+			buf.Write(f.AppendValue(buf.AvailableBuffer()))
+		})
+		require.Zero(t, allocs)
+	})
+
+	t.Run("encode", func(t *testing.T) {
+		buf := bytes.NewBuffer(make([]byte, 0, 1024))
+		entry := &Entry{Level: INFO, Message: "m", Fields: []KeyValue{Int("n", 42), Float64("f", 1.25), String("s", "value")}}
+		allocs := testing.AllocsPerRun(100, func() {
+			buf.Reset()
+			(&LogfmtEncoder{}).Encode(buf, entry)
+		})
+		require.Zero(t, allocs)
+	})
+
+	t.Run("data", func(t *testing.T) {
+		theLogger := implementation
+		defer func() {
+			implementation = theLogger
+			log.SetOutput(os.Stderr)
+		}()
+		implementation = &logPkg{}
+		log.SetOutput(io.Discard)
+
+		// Each Field is boxed in a KeyValue, so Data() allocates per field like with Parameters
+		fields := testing.AllocsPerRun(100, func() {
+			NoFileData(INFO, "m", Int("n", 42), Float64("f", 1.25), String("s", "value"))
+		})
+		parameters := testing.AllocsPerRun(100, func() {
+			NoFileData(INFO, "m", MakeParameter("n", 42), MakeParameter("f", 1.25), MakeParameter("s", "value"))
+		})
+		none := testing.AllocsPerRun(100, func() {
+			NoFileData(INFO, "m")
+		})
+		assert.Greater(t, fields, none)
+		assert.LessOrEqual(t, fields, parameters)
+
+		// NoFileDataFields() doesn't box the fields, only the slice of fields is allocated
+		one := testing.AllocsPerRun(100, func() {
+			NoFileDataFields(INFO, "m", Int("n", 42))
+		})
+		many := testing.AllocsPerRun(100, func() {
+			NoFileDataFields(INFO, "m", Int("n", 42), Float64("f", 1.25), String("s", "value"), Bool("b", true))
+		})
+		assert.Equal(t, one, many)
+		assert.Less(t, many, fields)
+	})
+
+	t.Run("encode typed", func(t *testing.T) {
+		buf := bytes.NewBuffer(make([]byte, 0, 1024))
+		entry := &Entry{Level: INFO, Message: "m", Typed: []Field{Int("n", 42), Float64("f", 1.25), String("s", "value")}}
+		for _, enc := range []Encoder{&LogfmtEncoder{}, &JSONEncoder{}} {
+			allocs := testing.AllocsPerRun(100, func() {
+				buf.Reset()
+				enc.Encode(buf, entry)
+			})
+			require.Zero(t, allocs, "%T", enc)
+		}
+	})
+}
+
+// BenchmarkNoFileDataFields benchmarks logging typed fields without boxing them
+func BenchmarkNoFileDataFields(b *testing.B) {
+	theLogger := implementation
+	defer func() {
+		implementation = theLogger
+		log.SetOutput(os.Stderr)
+	}()
+	implementation = &logPkg{}
+	log.SetOutput(io.Discard)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NoFileDataFields(INFO, "bench", Int("n", 42), String("s", "value"), Bool("b", true))
+	}
+}
+
+// BenchmarkNoFileData_Fields benchmarks logging typed fields boxed in KeyValues, for
+// comparison with BenchmarkNoFileDataFields
+func BenchmarkNoFileData_Fields(b *testing.B) {
+	theLogger := implementation
+	defer func() {
+		implementation = theLogger
+		log.SetOutput(os.Stderr)
+	}()
+	implementation = &logPkg{}
+	log.SetOutput(io.Discard)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NoFileData(INFO, "bench", Int("n", 42), String("s", "value"), Bool("b", true))
+	}
+}
+
+// BenchmarkInt benchmarks encoding an Int field
+func BenchmarkInt(b *testing.B) {
+	var buf bytes.Buffer
+	enc := &LogfmtEncoder{}
+	entry := &Entry{Level: INFO, Message: "bench", Fields: []KeyValue{Int("n", 42)}}
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		enc.Encode(&buf, entry)
+	}
+}
+
+// BenchmarkMakeParameterInt benchmarks encoding an int Parameter, for comparison with BenchmarkInt
+func BenchmarkMakeParameterInt(b *testing.B) {
+	var buf bytes.Buffer
+	enc := &LogfmtEncoder{}
+	entry := &Entry{Level: INFO, Message: "bench", Fields: []KeyValue{MakeParameter("n", 42)}}
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		enc.Encode(&buf, entry)
+	}
+}
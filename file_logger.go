@@ -0,0 +1,480 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// File logger defaults
+const (
+	DefaultFileMaxSize = 100 * 1024 * 1024 // Rotate files at 100MB
+
+	backupTimeFormat = "2006-01-02T15-04-05.000" // Timestamp in the name of rotated files
+	compressSuffix   = ".gz"
+)
+
+// ErrFileLoggerClosed is returned when writing to a closed FileLogger
+var ErrFileLoggerClosed = errors.New("file logger is closed")
+
+// FileOption is an optional setting for NewFileLogger
+type FileOption func(l *FileLogger)
+
+// WithMaxSize rotates the file when it would grow past maxSize bytes (zero disables)
+func WithMaxSize(maxSize int64) FileOption {
+	return func(l *FileLogger) {
+		l.maxSize = maxSize
+	}
+}
+
+// WithRotateEvery rotates the file every interval, aligned on the interval (IE: every
+// hour on the hour, or every day at midnight UTC)
+func WithRotateEvery(interval time.Duration) FileOption {
+	return func(l *FileLogger) {
+		l.rotateEvery = interval
+	}
+}
+
+// WithMaxBackups keeps at most maxBackups rotated files (zero keeps all)
+func WithMaxBackups(maxBackups int) FileOption {
+	return func(l *FileLogger) {
+		l.maxBackups = maxBackups
+	}
+}
+
+// WithMaxAge removes rotated files older than maxAge (zero keeps all)
+func WithMaxAge(maxAge time.Duration) FileOption {
+	return func(l *FileLogger) {
+		l.maxAge = maxAge
+	}
+}
+
+// WithCompression gzips rotated files
+func WithCompression() FileOption {
+	return func(l *FileLogger) {
+		l.compress = true
+	}
+}
+
+// WithReopenOnSIGHUP reopens the file when the process receives SIGHUP (IE: after logrotate
+// moved it away)
+func WithReopenOnSIGHUP() FileOption {
+	return func(l *FileLogger) {
+		l.signals = make(chan os.Signal, 1)
+	}
+}
+
+// WithWriterOptions sets the prefix, flags or encoder of the lines (see NewWriterLogger)
+func WithWriterOptions(opts ...WriterOption) FileOption {
+	return func(l *FileLogger) {
+		l.writerOptions = append(l.writerOptions, opts...)
+	}
+}
+
+// FileLogger is a Logger writing to a file, with size and time based rotation
+type FileLogger struct {
+	cleanups      sync.WaitGroup // Running cleanups of rotated files
+	cleanupMu     sync.Mutex     // Serializes the cleanups
+	closed        bool
+	compress      bool
+	file          *os.File
+	logger        *WriterLogger
+	maxAge        time.Duration
+	maxBackups    int
+	maxSize       int64
+	mu            sync.Mutex
+	nextRotation  time.Time
+	now           func() time.Time
+	path          string
+	rotateEvery   time.Duration
+	signals       chan os.Signal
+	size          int64
+	writerOptions []WriterOption
+}
+
+// NewFileLogger creates a Logger writing to path (the directory is created if needed)
+func NewFileLogger(path string, opts ...FileOption) (*FileLogger, error) {
+	l := &FileLogger{
+		maxSize: DefaultFileMaxSize,
+		now:     time.Now,
+		path:    path,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.logger = NewWriterLogger(l, l.writerOptions...)
+
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	// Rotated files left by a previous run may have expired
+	if l.maxAge > 0 || l.maxBackups > 0 || l.compress {
+		l.startCleanup()
+	}
+
+	if l.signals != nil {
+		signal.Notify(l.signals, syscall.SIGHUP)
+		go l.handleSignals()
+	}
+	return l, nil
+}
+
+// Write implements the io.Writer interface, rotating the file when needed
+//
+// When the rotation fails, the line is written to the current file and the rotation is
+// tried again on the next write
+func (l *FileLogger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, ErrFileLoggerClosed
+	}
+
+	if l.shouldRotate(int64(len(p))) {
+		if err := l.rotate(); err != nil {
+			log.Println("go-logger: failed to rotate log file:", err)
+		}
+	}
+
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// Rotate renames the current file with a timestamp and opens a new one
+func (l *FileLogger) Rotate() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrFileLoggerClosed
+	}
+	return l.rotate()
+}
+
+// Reopen reopens the file, for when it was moved by an external tool (the current file is
+// kept if the path can't be opened)
+func (l *FileLogger) Reopen() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrFileLoggerClosed
+	}
+	current := l.file
+	if err := l.open(); err != nil {
+		return err
+	}
+	_ = current.Close()
+	return nil
+}
+
+// Close closes the file (waiting for the cleanup of rotated files)
+func (l *FileLogger) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	if l.signals != nil {
+		signal.Stop(l.signals)
+		close(l.signals)
+	}
+	err := l.file.Close()
+	l.mu.Unlock()
+
+	l.cleanups.Wait()
+	return err
+}
+
+// shouldRotate reports whether writing n bytes needs a new file
+func (l *FileLogger) shouldRotate(n int64) bool {
+	if l.maxSize > 0 && l.size > 0 && l.size+n > l.maxSize {
+		return true
+	}
+	return l.rotateEvery > 0 && !l.now().Before(l.nextRotation)
+}
+
+// open opens (or creates) the file for appending, l.file is only replaced on success
+func (l *FileLogger) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o750); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	l.file = file
+	l.size = info.Size()
+	if l.rotateEvery > 0 {
+		l.nextRotation = l.now().Truncate(l.rotateEvery).Add(l.rotateEvery)
+	}
+	return nil
+}
+
+// rotate moves the current file to a backup and opens a new one
+//
+// The file is renamed while it's open, so it can still be written to if the rename or
+// the new file fails. Windows can't rename an open file: it's closed and renamed again,
+// and reopened if that fails too.
+func (l *FileLogger) rotate() error {
+	current := l.file
+	name := l.unusedBackupName(l.now())
+	if err := os.Rename(l.path, name); err != nil && !os.IsNotExist(err) {
+		_ = current.Close()
+		if err = os.Rename(l.path, name); err != nil && !os.IsNotExist(err) {
+			return errors.Join(err, l.open())
+		}
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+	_ = current.Close()
+
+	l.startCleanup()
+	return nil
+}
+
+// startCleanup cleans up the rotated files in the background (see cleanup)
+func (l *FileLogger) startCleanup() {
+	l.cleanups.Add(1)
+	go func() {
+		defer l.cleanups.Done()
+		l.cleanup()
+	}()
+}
+
+// backupName returns the name of a rotated file (IE: app-2024-01-02T15-04-05.000.log)
+func (l *FileLogger) backupName(t time.Time) string {
+	return l.backupNameSeq(t, 0)
+}
+
+// backupNameSeq returns the name of a rotated file, with a counter after the first file
+// rotated in the same millisecond (IE: app-2024-01-02T15-04-05.000.1.log)
+func (l *FileLogger) backupNameSeq(t time.Time, seq int) string {
+	ext := filepath.Ext(l.path)
+	name := strings.TrimSuffix(l.path, ext) + "-" + t.UTC().Format(backupTimeFormat)
+	if seq > 0 {
+		name += "." + strconv.Itoa(seq)
+	}
+	return name + ext
+}
+
+// unusedBackupName returns the first name of a rotated file that is not taken (compressed
+// or not), so a rotation never replaces a backup
+func (l *FileLogger) unusedBackupName(t time.Time) string {
+	for seq := 0; ; seq++ {
+		name := l.backupNameSeq(t, seq)
+		if !fileExists(name) && !fileExists(name+compressSuffix) {
+			return name
+		}
+	}
+}
+
+// fileExists reports whether a file exists
+func fileExists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+// backup is a rotated file
+type backup struct {
+	path      string
+	rotatedAt time.Time
+	seq       int // Counter of the files rotated in the same millisecond
+}
+
+// backups returns the rotated files, newest first
+func (l *FileLogger) backups() ([]backup, error) {
+	entries, err := os.ReadDir(filepath.Dir(l.path))
+	if err != nil {
+		return nil, err
+	}
+
+	ext := filepath.Ext(l.path)
+	prefix := strings.TrimSuffix(filepath.Base(l.path), ext) + "-"
+
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name[len(prefix):], compressSuffix), ext)
+		seq := 0
+		if len(stamp) > len(backupTimeFormat) && stamp[len(backupTimeFormat)] == '.' {
+			var seqErr error
+			if seq, seqErr = strconv.Atoi(stamp[len(backupTimeFormat)+1:]); seqErr != nil {
+				continue // Not one of ours
+			}
+			stamp = stamp[:len(backupTimeFormat)]
+		}
+		rotatedAt, parseErr := time.Parse(backupTimeFormat, stamp)
+		if parseErr != nil {
+			continue // Not one of ours
+		}
+		backups = append(backups, backup{path: filepath.Join(filepath.Dir(l.path), name), rotatedAt: rotatedAt, seq: seq})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].rotatedAt.Equal(backups[j].rotatedAt) {
+			return backups[i].seq > backups[j].seq
+		}
+		return backups[i].rotatedAt.After(backups[j].rotatedAt)
+	})
+	return backups, nil
+}
+
+// cleanup compresses rotated files and removes the ones over MaxBackups or MaxAge
+func (l *FileLogger) cleanup() {
+	l.cleanupMu.Lock()
+	defer l.cleanupMu.Unlock()
+
+	backups, err := l.backups()
+	if err != nil {
+		log.Println("go-logger: failed to list rotated log files:", err)
+		return
+	}
+
+	for i, b := range backups {
+		expired := l.maxAge > 0 && l.now().Sub(b.rotatedAt) > l.maxAge
+		if (l.maxBackups > 0 && i >= l.maxBackups) || expired {
+			if err = os.Remove(b.path); err != nil {
+				log.Println("go-logger: failed to remove rotated log file:", err)
+			}
+			continue
+		}
+		if l.compress && !strings.HasSuffix(b.path, compressSuffix) {
+			if err = compressFile(b.path); err != nil {
+				log.Println("go-logger: failed to compress rotated log file:", err)
+			}
+		}
+	}
+}
+
+// compressFile gzips a file, replacing it with path.gz
+func compressFile(path string) (err error) {
+	src, err := os.Open(path) //nolint:gosec // G304: path is one of our rotated files
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	tmp := path + compressSuffix + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640) //nolint:gosec // G304: next to our rotated file
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path+compressSuffix); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// handleSignals reopens the file on SIGHUP until the logger is closed
+func (l *FileLogger) handleSignals() {
+	for range l.signals {
+		if err := l.Reopen(); err != nil && !errors.Is(err, ErrFileLoggerClosed) {
+			log.Println("go-logger: failed to reopen log file:", err)
+		}
+	}
+}
+
+// LogEntry implements the EntryLogger interface
+func (l *FileLogger) LogEntry(e *Entry) {
+	l.logger.LogEntry(e)
+}
+
+// Panic overloads built-in method
+func (l *FileLogger) Panic(v ...interface{}) {
+	l.logger.Print(v...)
+	_ = l.Close()
+	os.Exit(1)
+}
+
+// Panicln overloads built-in method
+func (l *FileLogger) Panicln(v ...interface{}) {
+	l.logger.Println(v...)
+	_ = l.Close()
+	os.Exit(1)
+}
+
+// Panicf overloads built-in method
+func (l *FileLogger) Panicf(format string, v ...interface{}) {
+	l.logger.Print(fmt.Sprintf(format, v...))
+	_ = l.Close()
+	os.Exit(1)
+}
+
+// Print overloads built-in method
+func (l *FileLogger) Print(v ...interface{}) {
+	l.logger.Print(v...)
+}
+
+// Println overloads built-in method
+func (l *FileLogger) Println(v ...interface{}) {
+	l.logger.Println(v...)
+}
+
+// Printf overloads built-in method
+func (l *FileLogger) Printf(format string, v ...interface{}) {
+	l.logger.Printf(format, v...)
+}
+
+// Fatal overloads built-in method
+func (l *FileLogger) Fatal(v ...interface{}) {
+	l.logger.Print(v...)
+	_ = l.Close()
+	os.Exit(1)
+}
+
+// Fatalln overloads built-in method
+func (l *FileLogger) Fatalln(v ...interface{}) {
+	l.logger.Println(v...)
+	_ = l.Close()
+	os.Exit(1)
+}
+
+// Fatalf overloads built-in method
+func (l *FileLogger) Fatalf(format string, v ...interface{}) {
+	l.logger.Print(fmt.Sprintf(format, v...))
+	_ = l.Close()
+	os.Exit(1)
+}
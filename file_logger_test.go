@@ -0,0 +1,403 @@
+package logger
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFileLogger creates a FileLogger in a temporary directory, with a fake clock
+func newTestFileLogger(t *testing.T, now *time.Time, opts ...FileOption) *FileLogger {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	l, err := NewFileLogger(path, append([]FileOption{func(l *FileLogger) { l.now = func() time.Time { return *now } }}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	return l
+}
+
+// readFile returns the content of a file (gunzipped if needed)
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path) //nolint:gosec // G304: test file
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, compressSuffix) {
+		zr, zErr := gzip.NewReader(f)
+		require.NoError(t, zErr)
+		r = zr
+	}
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// listBackups returns the names of the rotated files
+func listBackups(t *testing.T, l *FileLogger) []string {
+	t.Helper()
+	backups, err := l.backups()
+	require.NoError(t, err)
+	names := make([]string, 0, len(backups))
+	for _, b := range backups {
+		names = append(names, filepath.Base(b.path))
+	}
+	return names
+}
+
+// TestNewFileLogger will test the NewFileLogger() method
+func TestNewFileLogger(t *testing.T) {
+	t.Run("creates the directory and file", func(t *testing.T) {
+		now := time.Now()
+		l := newTestFileLogger(t, &now)
+		assert.Equal(t, int64(DefaultFileMaxSize), l.maxSize)
+		assert.FileExists(t, l.path)
+	})
+
+	t.Run("appends to an existing file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		require.NoError(t, os.WriteFile(path, []byte("existing\n"), 0o600))
+
+		l, err := NewFileLogger(path)
+		require.NoError(t, err)
+		assert.Equal(t, int64(9), l.size)
+
+		l.Print("new")
+		require.NoError(t, l.Close())
+		assert.True(t, strings.HasPrefix(readFile(t, path), "existing\n"))
+		assert.Contains(t, readFile(t, path), "new\n")
+	})
+
+	t.Run("writer options", func(t *testing.T) {
+		now := time.Now()
+		l := newTestFileLogger(t, &now, WithWriterOptions(WithFlags(0), WithPrefix("app: ")))
+
+		l.Print("test")
+		assert.Equal(t, "app: test\n", readFile(t, l.path))
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+		l, err := NewFileLogger(filepath.Join(path, "app.log"))
+		require.Error(t, err)
+		assert.Nil(t, l)
+	})
+}
+
+// TestFileLogger_Print will test the Print(), Println() and Printf() methods
+func TestFileLogger_Print(t *testing.T) {
+	now := time.Now()
+	l := newTestFileLogger(t, &now)
+
+	l.Print("print")
+	l.Println("println")
+	l.Printf("printf %d", 1)
+
+	lines := strings.Split(strings.TrimSpace(readFile(t, l.path)), "\n")
+	require.Len(t, lines, 3)
+	assert.Regexp(t, `^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} print$`, lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], " println"))
+	assert.True(t, strings.HasSuffix(lines[2], " printf 1"))
+}
+
+// TestFileLogger_Data will test logging with Data() through a FileLogger
+func TestFileLogger_Data(t *testing.T) {
+	now := time.Now()
+	l := newTestFileLogger(t, &now)
+
+	previous := GetImplementation()
+	SetImplementation(l)
+	defer SetImplementation(previous)
+
+	Data(2, INFO, "file logger", MakeParameter("key", "value"))
+	assert.Contains(t, readFile(t, l.path), `type="info" file="`)
+	assert.Contains(t, readFile(t, l.path), `message="file logger" key="value"`)
+}
+
+// TestFileLogger_Panic will test the Panic() method
+func TestFileLogger_Panic(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "go-logger-panic-test.log")
+	if os.Getenv("EXIT_FUNCTION") == "1" {
+		l, err := NewFileLogger(path)
+		require.NoError(t, err)
+		l.Panicln("panicln", 1)
+		return
+	}
+	_ = os.Remove(path)
+	defer func() { _ = os.Remove(path) }()
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestFileLogger_Panic") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION=1")
+	err := cmd.Run()
+	var e *exec.ExitError
+	require.ErrorAs(t, err, &e)
+	assert.False(t, e.Success())
+	assert.Contains(t, readFile(t, path), "panicln 1\n")
+}
+
+// TestFileLogger_Fatal will test the Fatal() method
+func TestFileLogger_Fatal(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "go-logger-fatal-test.log")
+	if os.Getenv("EXIT_FUNCTION") == "1" {
+		l, err := NewFileLogger(path)
+		require.NoError(t, err)
+		l.Fatalf("fatal %d", 1)
+		return
+	}
+	_ = os.Remove(path)
+	defer func() { _ = os.Remove(path) }()
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestFileLogger_Fatal") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION=1")
+	err := cmd.Run()
+	var e *exec.ExitError
+	require.ErrorAs(t, err, &e)
+	assert.False(t, e.Success())
+	assert.Contains(t, readFile(t, path), "fatal 1\n")
+}
+
+// TestFileLogger_RotateSize will test rotating by size
+func TestFileLogger_RotateSize(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	l := newTestFileLogger(t, &now, WithMaxSize(30))
+
+	l.Print("first line") // 31 bytes with the date prefix, larger than the max but the file is empty
+	now = now.Add(time.Second)
+	l.Print("second line")
+	now = now.Add(time.Second)
+	l.Print("third line")
+	require.NoError(t, l.Close())
+
+	assert.Equal(t, []string{"app-2024-01-02T03-04-07.000.log", "app-2024-01-02T03-04-06.000.log"}, listBackups(t, l))
+	assert.Contains(t, readFile(t, l.backupName(now.Add(-time.Second))), "first line")
+	assert.Contains(t, readFile(t, l.backupName(now)), "second line")
+	assert.Contains(t, readFile(t, l.path), "third line")
+}
+
+// TestFileLogger_RotateEvery will test rotating on an interval
+func TestFileLogger_RotateEvery(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	l := newTestFileLogger(t, &now, WithRotateEvery(time.Hour))
+	assert.Equal(t, time.Date(2024, 1, 2, 4, 0, 0, 0, time.UTC), l.nextRotation)
+
+	l.Print("before")
+	now = now.Add(50 * time.Minute)
+	l.Print("still before")
+	now = now.Add(10 * time.Minute)
+	l.Print("after")
+	require.NoError(t, l.Close())
+
+	assert.Equal(t, []string{"app-2024-01-02T04-04-05.000.log"}, listBackups(t, l))
+	assert.Equal(t, time.Date(2024, 1, 2, 5, 0, 0, 0, time.UTC), l.nextRotation)
+	assert.Contains(t, readFile(t, l.backupName(now)), "still before")
+	assert.NotContains(t, readFile(t, l.path), "before")
+}
+
+// TestFileLogger_MaxBackups will test removing the oldest rotated files
+func TestFileLogger_MaxBackups(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	l := newTestFileLogger(t, &now, WithMaxBackups(2))
+
+	for i := 0; i < 4; i++ {
+		l.Print(i)
+		now = now.Add(time.Second)
+		require.NoError(t, l.Rotate())
+	}
+	require.NoError(t, l.Close())
+
+	assert.Equal(t, []string{"app-2024-01-02T03-04-09.000.log", "app-2024-01-02T03-04-08.000.log"}, listBackups(t, l))
+}
+
+// TestFileLogger_RotateSameMillisecond will test rotating more than once in a millisecond
+func TestFileLogger_RotateSameMillisecond(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	l := newTestFileLogger(t, &now, WithMaxBackups(3), WithCompression())
+
+	for i := 0; i < 4; i++ {
+		l.Print("line ", i)
+		require.NoError(t, l.Rotate())
+	}
+	require.NoError(t, l.Close())
+
+	assert.Equal(t, []string{
+		"app-2024-01-02T03-04-05.000.3.log.gz",
+		"app-2024-01-02T03-04-05.000.2.log.gz",
+		"app-2024-01-02T03-04-05.000.1.log.gz",
+	}, listBackups(t, l))
+	for i := 1; i < 4; i++ {
+		assert.Contains(t, readFile(t, l.backupNameSeq(now, i)+compressSuffix), fmt.Sprint("line ", i))
+	}
+}
+
+// TestFileLogger_MaxAge will test removing expired rotated files
+func TestFileLogger_MaxAge(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	l := newTestFileLogger(t, &now, WithMaxAge(48*time.Hour))
+
+	dir := filepath.Dir(l.path)
+	for _, name := range []string{
+		"app-2024-01-01T00-00-00.000.log",
+		"app-2024-01-07T00-00-00.000.log.gz",
+		"app-2024-01-09T00-00-00.000.log",
+		"app-not-a-backup.log",
+		"other-2024-01-01T00-00-00.000.log",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+	}
+
+	require.NoError(t, l.Rotate())
+	require.NoError(t, l.Close())
+
+	assert.Equal(t, []string{"app-2024-01-10T00-00-00.000.log", "app-2024-01-09T00-00-00.000.log"}, listBackups(t, l))
+	assert.FileExists(t, filepath.Join(dir, "app-not-a-backup.log"))
+	assert.FileExists(t, filepath.Join(dir, "other-2024-01-01T00-00-00.000.log"))
+}
+
+// TestFileLogger_MaxAgeAtOpen will test removing the rotated files that expired before the logger was created
+func TestFileLogger_MaxAgeAtOpen(t *testing.T) {
+	dir := t.TempDir()
+	expired := filepath.Join(dir, "app-2024-01-01T00-00-00.000.log")
+	recent := filepath.Join(dir, "app-2024-01-09T00-00-00.000.log")
+	require.NoError(t, os.WriteFile(expired, nil, 0o600))
+	require.NoError(t, os.WriteFile(recent, nil, 0o600))
+
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	l, err := NewFileLogger(filepath.Join(dir, "app.log"), WithMaxAge(48*time.Hour), func(l *FileLogger) {
+		l.now = func() time.Time { return now }
+	})
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	assert.NoFileExists(t, expired)
+	assert.FileExists(t, recent)
+}
+
+// TestFileLogger_RotateFailure will test that the logger keeps writing when a rotation fails
+func TestFileLogger_RotateFailure(t *testing.T) {
+	// The name of the rotated file is too long, so renaming the file fails
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	l, err := NewFileLogger(filepath.Join(t.TempDir(), strings.Repeat("a", 240)+".log"), WithMaxSize(20), func(l *FileLogger) {
+		l.now = func() time.Time { return now }
+	})
+	require.NoError(t, err)
+	defer func() { _ = l.Close() }()
+
+	l.Print("before rotating")
+	require.Error(t, l.Rotate())
+	l.Print("after a failed rotation")
+	l.Print("after a failed size rotation")
+
+	content := readFile(t, l.path)
+	assert.Contains(t, content, "before rotating\n")
+	assert.Contains(t, content, "after a failed rotation\n")
+	assert.Contains(t, content, "after a failed size rotation\n")
+	assert.Empty(t, listBackups(t, l))
+}
+
+// TestFileLogger_Compression will test compressing the rotated files
+func TestFileLogger_Compression(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	l := newTestFileLogger(t, &now, WithCompression())
+
+	l.Print("compressed")
+	require.NoError(t, l.Rotate())
+	l.Print("current")
+	require.NoError(t, l.Close())
+
+	backup := l.backupName(now) + compressSuffix
+	assert.Equal(t, []string{filepath.Base(backup)}, listBackups(t, l))
+	assert.NoFileExists(t, l.backupName(now))
+	assert.NoFileExists(t, backup+".tmp")
+	assert.Contains(t, readFile(t, backup), "compressed\n")
+	assert.NotContains(t, readFile(t, l.path), "compressed")
+}
+
+// TestFileLogger_Reopen will test reopening the file after it was moved
+func TestFileLogger_Reopen(t *testing.T) {
+	now := time.Now()
+	l := newTestFileLogger(t, &now)
+
+	l.Print("before")
+	moved := l.path + ".1"
+	require.NoError(t, os.Rename(l.path, moved))
+	l.Print("moved")
+	require.NoError(t, l.Reopen())
+	l.Print("after")
+
+	assert.Contains(t, readFile(t, moved), "moved\n")
+	assert.NotContains(t, readFile(t, l.path), "moved")
+	assert.Contains(t, readFile(t, l.path), "after\n")
+}
+
+// TestFileLogger_ReopenFailure will test that the current file is kept when reopening fails
+func TestFileLogger_ReopenFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("directories holding open files can't be moved on Windows")
+	}
+	now := time.Now()
+	l := newTestFileLogger(t, &now)
+
+	// The directory of the file is moved, and replaced by a file
+	dir := filepath.Dir(l.path)
+	require.NoError(t, os.Rename(dir, dir+".moved"))
+	require.NoError(t, os.WriteFile(dir, nil, 0o600))
+
+	require.Error(t, l.Reopen())
+	l.Print("after a failed reopen")
+	assert.Contains(t, readFile(t, filepath.Join(dir+".moved", filepath.Base(l.path))), "after a failed reopen\n")
+}
+
+// TestFileLogger_ReopenOnSIGHUP will test reopening the file on SIGHUP
+func TestFileLogger_ReopenOnSIGHUP(t *testing.T) {
+	now := time.Now()
+	l := newTestFileLogger(t, &now, WithReopenOnSIGHUP())
+
+	require.NoError(t, os.Rename(l.path, l.path+".1"))
+	l.signals <- syscall.SIGHUP
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(l.path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestFileLogger_Close will test the Close() method
+func TestFileLogger_Close(t *testing.T) {
+	now := time.Now()
+	l := newTestFileLogger(t, &now, WithReopenOnSIGHUP())
+
+	require.NoError(t, l.Close())
+	require.NoError(t, l.Close())
+
+	_, err := l.Write([]byte("closed\n"))
+	require.ErrorIs(t, err, ErrFileLoggerClosed)
+	require.ErrorIs(t, l.Rotate(), ErrFileLoggerClosed)
+	require.ErrorIs(t, l.Reopen(), ErrFileLoggerClosed)
+}
+
+// BenchmarkFileLogger_Println benchmarks the Println() method
+func BenchmarkFileLogger_Println(b *testing.B) {
+	l, err := NewFileLogger(filepath.Join(b.TempDir(), "app.log"), WithMaxSize(1024*1024))
+	require.NoError(b, err)
+	defer func() { _ = l.Close() }()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Println("benchmark line")
+	}
+}
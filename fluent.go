@@ -0,0 +1,468 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Fluent Forward defaults
+const (
+	DefaultFluentAckTimeout = 5 * time.Second
+	DefaultFluentBatchSize  = 100 // Records per PackedForward message
+	DefaultFluentMaxRetries = 10  // Retries of a message before it is dropped
+	DefaultFluentPort       = "24224"
+
+	fluentExitAttempts = 3               // Attempts of the Fatal and Panic methods
+	fluentExitTimeout  = 2 * time.Second // Timeout of an attempt of the Fatal and Panic methods
+)
+
+// ErrFluentAck is returned when the server acknowledged another chunk
+var ErrFluentAck = errors.New("fluent ack does not match the chunk")
+
+// FluentOption is an optional setting for NewFluentClient
+type FluentOption func(c *FluentClient)
+
+// WithFluentAck requires the server to acknowledge every message (at least once delivery)
+func WithFluentAck(timeout time.Duration) FluentOption {
+	return func(c *FluentClient) {
+		c.ack = true
+		c.ackTimeout = timeout
+	}
+}
+
+// WithFluentBatchSize sets the maximum number of records per message
+func WithFluentBatchSize(size int) FluentOption {
+	return func(c *FluentClient) {
+		c.batchSize = size
+	}
+}
+
+// WithFluentMaxRetries sets the number of retries of a message before it is dropped
+func WithFluentMaxRetries(retries int) FluentOption {
+	return func(c *FluentClient) {
+		c.maxRetries = retries
+	}
+}
+
+// WithFluentTagKey uses the value of a Data() field as the tag of the record (IE: "tag"),
+// the field is removed from the record
+func WithFluentTagKey(key string) FluentOption {
+	return func(c *FluentClient) {
+		c.tagKey = key
+	}
+}
+
+// FluentClient is a Logger sending records to Fluentd or Fluent Bit with the Forward
+// protocol (PackedForward mode), start sending with go client.ProcessQueue()
+type FluentClient struct {
+	ack        bool
+	ackTimeout time.Duration
+	batchSize  int
+	closed     bool
+	conn       *net.TCPConn
+	connMu     sync.Mutex // Guards conn and retryDelay
+	done       chan struct{}
+	endpoint   string
+	maxRetries int
+	messages   msgQueue
+	mu         sync.RWMutex // Guards closed
+	port       string
+	retryDelay time.Duration
+	started    atomic.Bool // Set by the first ProcessQueue call
+	tag        string
+	tagKey     string
+}
+
+// NewFluentClient creates a client for a forward input (IE: localhost, DefaultFluentPort),
+// the client is returned with the connection error so it can reconnect later
+func NewFluentClient(endpoint, port, tag string, opts ...FluentOption) (*FluentClient, error) {
+	c := &FluentClient{
+		batchSize:  DefaultFluentBatchSize,
+		done:       make(chan struct{}),
+		endpoint:   endpoint,
+		maxRetries: DefaultFluentMaxRetries,
+		port:       port,
+		retryDelay: RetryDelay,
+		tag:        tag,
+	}
+	c.messages.messagesToSend = make(chan *bytes.Buffer, 1000)
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.batchSize <= 0 {
+		c.batchSize = DefaultFluentBatchSize
+	}
+	if c.ack && c.ackTimeout <= 0 {
+		c.ackTimeout = DefaultFluentAckTimeout
+	}
+
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	return c, c.connect()
+}
+
+// connect (re)connects to the forward input
+func (c *FluentClient) connect() error {
+	if c.conn != nil {
+		_ = c.conn.Close() // close the connection, don't care about the error
+	}
+	c.conn = nil
+
+	conn, err := dialTCP(c.endpoint, c.port, &c.retryDelay)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+// ProcessQueue sends the queued records, batching the ones already waiting, and reconnects
+// after the retry delay when sending fails (the calls after the first one return right away)
+func (c *FluentClient) ProcessQueue() {
+	if !c.started.CompareAndSwap(false, true) {
+		return
+	}
+	defer close(c.done)
+
+	for msg := range c.messages.messagesToSend {
+		batch := []*bytes.Buffer{msg}
+	fill:
+		for len(batch) < c.batchSize {
+			select {
+			case next, ok := <-c.messages.messagesToSend:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, next)
+			default:
+				break fill
+			}
+		}
+
+		for _, forward := range c.forwardMessages(batch) {
+			c.sendWithRetry(forward)
+		}
+	}
+}
+
+// Close stops accepting records and waits for the queued ones to be sent (by ProcessQueue, or
+// right away when it was never started)
+func (c *FluentClient) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.messages.messagesToSend)
+	c.mu.Unlock()
+
+	c.ProcessQueue() // Sends the queued records when ProcessQueue was never started
+	<-c.done
+
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	if c.conn != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// sendWithRetry sends a message, reconnecting like LogClient.ProcessQueue, and drops it
+// after maxRetries failed retries (the connection is only locked during an attempt)
+func (c *FluentClient) sendWithRetry(forward []byte) {
+	for attempt := 0; ; attempt++ {
+		c.connMu.Lock()
+		err := c.send(forward)
+		delay := c.retryDelay
+		c.connMu.Unlock()
+		if err == nil {
+			return
+		}
+		if attempt >= c.maxRetries {
+			log.Println("go-logger: dropped a message after failing to write to log provider:", err) //nolint:gosec // G706: error originates from stdlib network functions
+			return
+		}
+		log.Println("failed to write to log provider", err) //nolint:gosec // G706: error originates from stdlib network functions
+		time.Sleep(delay)
+
+		c.connMu.Lock()
+		err = c.connect()
+		c.connMu.Unlock()
+		if err != nil {
+			log.Println("failed reconnecting to log provider after failing to write", err) //nolint:gosec // G706: error originates from stdlib network functions
+		}
+	}
+}
+
+// send writes a message once on the connection, waiting for its ack if required
+func (c *FluentClient) send(forward []byte) error {
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return err
+		}
+	}
+	if err := c.write(c.conn, forward); err != nil {
+		_ = c.conn.Close()
+		c.conn = nil
+		return err
+	}
+	return nil
+}
+
+// write writes a message once on a connection, waiting for its ack if required
+func (c *FluentClient) write(conn net.Conn, forward []byte) error {
+	chunk := ""
+	if c.ack {
+		var id [16]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return err
+		}
+		chunk = base64.StdEncoding.EncodeToString(id[:])
+	}
+
+	if _, err := conn.Write(appendFluentOption(forward, chunk)); err != nil {
+		return err
+	}
+	if !c.ack {
+		return nil
+	}
+	return readFluentAck(conn, c.ackTimeout, chunk)
+}
+
+// readFluentAck waits for the {"ack": chunk} response of the server
+func readFluentAck(conn net.Conn, timeout time.Duration, chunk string) error {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	r := bufio.NewReader(conn)
+	n, err := readMsgpackMapHeader(r)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		var key, value string
+		if key, err = readMsgpackString(r); err != nil {
+			return err
+		}
+		if value, err = readMsgpackString(r); err != nil {
+			return err
+		}
+		if key == "ack" {
+			if value != chunk {
+				return fmt.Errorf("%w: %q", ErrFluentAck, value)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: missing", ErrFluentAck)
+}
+
+// forwardMessages groups a batch by tag into PackedForward messages (without their option),
+// keeping the order of the records of a tag
+func (c *FluentClient) forwardMessages(batch []*bytes.Buffer) [][]byte {
+	var tags []string
+	entries := make(map[string][]byte)
+	for _, msg := range batch {
+		r := bytes.NewReader(msg.Bytes())
+		tag, err := readMsgpackString(r)
+		if err != nil {
+			continue // Not possible, the buffers are built by record()
+		}
+		if _, ok := entries[tag]; !ok {
+			tags = append(tags, tag)
+		}
+		entries[tag] = append(entries[tag], msg.Bytes()[msg.Len()-r.Len():]...)
+	}
+
+	messages := make([][]byte, 0, len(tags))
+	for _, tag := range tags {
+		forward := appendMsgpackArrayHeader(nil, 3)
+		forward = appendMsgpackString(forward, tag)
+		forward = appendMsgpackBinary(forward, entries[tag])
+		messages = append(messages, forward)
+	}
+	return messages
+}
+
+// appendFluentOption appends the option map of a PackedForward message
+func appendFluentOption(forward []byte, chunk string) []byte {
+	msg := make([]byte, 0, len(forward)+32)
+	msg = append(msg, forward...)
+	if len(chunk) == 0 {
+		return appendMsgpackMapHeader(msg, 0)
+	}
+	msg = appendMsgpackMapHeader(msg, 1)
+	msg = appendMsgpackString(msg, "chunk")
+	return appendMsgpackString(msg, chunk)
+}
+
+// record builds the queued form of a record: its tag, then its [time, record] entry
+func (c *FluentClient) record(t time.Time, level, message string, e *Entry) *bytes.Buffer {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	message = strings.TrimSuffix(message, "\n")
+
+	tag := c.tag
+	fields := 2
+	var kvs []KeyValue
+	if e != nil {
+		if len(e.File) > 0 {
+			fields += 3
+		}
+		for _, kv := range e.KeyValues() {
+			if len(c.tagKey) > 0 && kv.Key() == c.tagKey {
+				tag = fmt.Sprint(kv.Value())
+				continue
+			}
+			kvs = append(kvs, kv)
+		}
+		fields += len(kvs)
+	}
+
+	b := appendMsgpackString(nil, tag)
+	b = appendMsgpackArrayHeader(b, 2)
+	b = appendMsgpackEventTime(b, t)
+	b = appendMsgpackMapHeader(b, fields)
+	b = appendMsgpackString(b, "level")
+	b = appendMsgpackString(b, level)
+	b = appendMsgpackString(b, "message")
+	b = appendMsgpackString(b, message)
+	if e != nil && len(e.File) > 0 {
+		b = appendMsgpackString(b, "file")
+		b = appendMsgpackString(b, e.File)
+		b = appendMsgpackString(b, "method")
+		b = appendMsgpackString(b, e.Method)
+		b = appendMsgpackString(b, "line")
+		b = appendMsgpackInt(b, int64(e.Line))
+	}
+	for _, kv := range kvs {
+		b = appendMsgpackString(b, fluentFieldName(kv.Key()))
+		b = appendMsgpackValue(b, kv)
+	}
+	return bytes.NewBuffer(b)
+}
+
+// fluentFieldName returns the record key of a field, the keys written by the client get an
+// underscore (IE: level is _level), so the record has no duplicate keys
+func fluentFieldName(key string) string {
+	switch key {
+	case "file", "level", "line", "message", "method":
+		return "_" + key
+	}
+	return key
+}
+
+// LogEntry implements the EntryLogger interface, sending the fields in the record
+func (c *FluentClient) LogEntry(e *Entry) {
+	c.enqueue(c.record(e.Time, e.Level.String(), e.Message, e), e.Message)
+}
+
+// Panic overloads built-in method
+func (c *FluentClient) Panic(v ...interface{}) {
+	c.sendOne(c.record(time.Now(), "panic", fmt.Sprint(v...), nil))
+	os.Exit(1)
+}
+
+// Panicln overloads built-in method
+func (c *FluentClient) Panicln(v ...interface{}) {
+	c.sendOne(c.record(time.Now(), "panic", fmt.Sprintln(v...), nil))
+	os.Exit(1)
+}
+
+// Panicf overloads built-in method
+func (c *FluentClient) Panicf(format string, v ...interface{}) {
+	c.sendOne(c.record(time.Now(), "panic", fmt.Sprintf(format, v...), nil))
+	os.Exit(1)
+}
+
+// Print overloads built-in method
+func (c *FluentClient) Print(v ...interface{}) {
+	c.print(fmt.Sprint(v...))
+}
+
+// Println overloads built-in method
+func (c *FluentClient) Println(v ...interface{}) {
+	c.print(fmt.Sprintln(v...))
+}
+
+// Printf overloads built-in method
+func (c *FluentClient) Printf(format string, v ...interface{}) {
+	c.print(fmt.Sprintf(format, v...))
+}
+
+// Fatal overloads built-in method
+func (c *FluentClient) Fatal(v ...interface{}) {
+	c.sendOne(c.record(time.Now(), "fatal", fmt.Sprint(v...), nil))
+	os.Exit(1)
+}
+
+// Fatalln overloads built-in method
+func (c *FluentClient) Fatalln(v ...interface{}) {
+	c.sendOne(c.record(time.Now(), "fatal", fmt.Sprintln(v...), nil))
+	os.Exit(1)
+}
+
+// Fatalf overloads built-in method
+func (c *FluentClient) Fatalf(format string, v ...interface{}) {
+	c.sendOne(c.record(time.Now(), "fatal", fmt.Sprintf(format, v...), nil))
+	os.Exit(1)
+}
+
+// print queues a line as an INFO record
+func (c *FluentClient) print(line string) {
+	c.enqueue(c.record(time.Now(), INFO.String(), line, nil), line)
+}
+
+// enqueue queues a record (or prints its message with the log package once closed)
+func (c *FluentClient) enqueue(msg *bytes.Buffer, message string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		log.Print(message)
+		return
+	}
+	c.messages.Enqueue(msg)
+}
+
+// sendOne sends one record right away on its own connection, bypassing the queue and the
+// connection of ProcessQueue (used before exiting), with a few attempts and a timeout
+func (c *FluentClient) sendOne(msg *bytes.Buffer) {
+	for _, forward := range c.forwardMessages([]*bytes.Buffer{msg}) {
+		var err error
+		for attempt := 0; attempt < fluentExitAttempts; attempt++ {
+			if err = c.sendOnce(forward); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			log.Println("failed to write to log provider", err) //nolint:gosec // G706: error originates from stdlib network functions
+		}
+	}
+}
+
+// sendOnce dials a new connection and writes a message on it, within fluentExitTimeout
+func (c *FluentClient) sendOnce(forward []byte) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(c.endpoint, c.port), fluentExitTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err = conn.SetDeadline(time.Now().Add(fluentExitTimeout)); err != nil {
+		return err
+	}
+	return c.write(conn, forward)
+}
@@ -0,0 +1,419 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fluentMessage is a PackedForward message received by a testFluentServer
+type fluentMessage struct {
+	tag     string
+	records []map[string]interface{}
+	times   []msgpackEventTime
+	option  map[string]interface{}
+}
+
+// testFluentServer is a forward input that decodes the messages (and acks them)
+type testFluentServer struct {
+	listener net.Listener
+	messages chan fluentMessage
+	badAcks  atomic.Int32 // Number of messages to acknowledge with a wrong chunk
+}
+
+// newTestFluentServer starts a testFluentServer
+func newTestFluentServer(t *testing.T) *testFluentServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &testFluentServer{listener: listener, messages: make(chan fluentMessage, 100)}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, acceptErr := listener.Accept()
+			if acceptErr != nil {
+				return
+			}
+			go s.serve(t, conn)
+		}
+	}()
+	return s
+}
+
+// serve decodes the messages of a connection
+func (s *testFluentServer) serve(t *testing.T, conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	for {
+		v, err := decodeMsgpack(r)
+		if err != nil {
+			return
+		}
+		msg := v.([]interface{})
+		if !assert.Len(t, msg, 3) {
+			return
+		}
+
+		received := fluentMessage{tag: msg[0].(string), option: msg[2].(map[string]interface{})}
+		entries := bufio.NewReader(bytes.NewReader(msg[1].([]byte)))
+		for {
+			entry, entryErr := decodeMsgpack(entries)
+			if errors.Is(entryErr, io.EOF) {
+				break
+			}
+			if !assert.NoError(t, entryErr) {
+				return
+			}
+			pair := entry.([]interface{})
+			received.times = append(received.times, pair[0].(msgpackEventTime))
+			received.records = append(received.records, pair[1].(map[string]interface{}))
+		}
+
+		if chunk, ok := received.option["chunk"].(string); ok {
+			if s.badAcks.Load() > 0 {
+				s.badAcks.Add(-1)
+				chunk = "wrong"
+			}
+			ack := appendMsgpackMapHeader(nil, 1)
+			ack = appendMsgpackString(ack, "ack")
+			ack = appendMsgpackString(ack, chunk)
+			if _, err = conn.Write(ack); err != nil {
+				return
+			}
+		}
+		s.messages <- received
+	}
+}
+
+// port returns the port of the server
+func (s *testFluentServer) port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+// next returns the next message
+func (s *testFluentServer) next(t *testing.T) fluentMessage {
+	t.Helper()
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for a fluent message")
+	}
+	return fluentMessage{}
+}
+
+// TestNewFluentClient will test the NewFluentClient() method
+func TestNewFluentClient(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		server := newTestFluentServer(t)
+		c, err := NewFluentClient("127.0.0.1", server.port(), "app")
+		require.NoError(t, err)
+		assert.NotNil(t, c.conn)
+		assert.Equal(t, "app", c.tag)
+		assert.Equal(t, DefaultFluentBatchSize, c.batchSize)
+		assert.Equal(t, DefaultFluentMaxRetries, c.maxRetries)
+		assert.False(t, c.ack)
+		assert.Equal(t, RetryDelay, c.retryDelay)
+	})
+
+	t.Run("options", func(t *testing.T) {
+		server := newTestFluentServer(t)
+		c, err := NewFluentClient("127.0.0.1", server.port(), "app",
+			WithFluentAck(0), WithFluentBatchSize(-1), WithFluentMaxRetries(3), WithFluentTagKey("tag"))
+		require.NoError(t, err)
+		assert.Equal(t, 3, c.maxRetries)
+		assert.True(t, c.ack)
+		assert.Equal(t, DefaultFluentAckTimeout, c.ackTimeout)
+		assert.Equal(t, DefaultFluentBatchSize, c.batchSize)
+		assert.Equal(t, "tag", c.tagKey)
+	})
+
+	t.Run("connection error", func(t *testing.T) {
+		c, err := NewFluentClient("127.0.0.1", "1", "app")
+		require.Error(t, err)
+		assert.NotNil(t, c)
+		assert.Nil(t, c.conn)
+		assert.Equal(t, 2*RetryDelay, c.retryDelay)
+	})
+}
+
+// TestFluentClient_ProcessQueue will test sending records
+func TestFluentClient_ProcessQueue(t *testing.T) {
+	t.Run("print lines", func(t *testing.T) {
+		server := newTestFluentServer(t)
+		c, err := NewFluentClient("127.0.0.1", server.port(), "app")
+		require.NoError(t, err)
+
+		c.Print("one")
+		c.Println("two")
+		c.Printf("three %d", 3)
+		go c.ProcessQueue()
+		c.Close()
+
+		msg := server.next(t)
+		assert.Equal(t, "app", msg.tag)
+		assert.Empty(t, msg.option)
+		require.Len(t, msg.records, 3)
+		assert.Equal(t, map[string]interface{}{"level": "info", "message": "one"}, msg.records[0])
+		assert.Equal(t, "two", msg.records[1]["message"])
+		assert.Equal(t, "three 3", msg.records[2]["message"])
+		assert.InDelta(t, time.Now().Unix(), msg.times[0].seconds, 5)
+	})
+
+	t.Run("entries and tags", func(t *testing.T) {
+		server := newTestFluentServer(t)
+		c, err := NewFluentClient("127.0.0.1", server.port(), "app", WithFluentTagKey("tag"))
+		require.NoError(t, err)
+
+		previous := GetImplementation()
+		SetImplementation(c)
+		Data(2, WARN, "first", String("tag", "app.audit"), Int("n", 1))
+		NoFileData(ERROR, "second", Float64("f", 1.5))
+		Data(2, INFO, "third", String("tag", "app.audit"))
+		SetImplementation(previous)
+		go c.ProcessQueue()
+		c.Close()
+
+		audit := server.next(t)
+		assert.Equal(t, "app.audit", audit.tag)
+		require.Len(t, audit.records, 2)
+		assert.Equal(t, "warn", audit.records[0]["level"])
+		assert.Equal(t, "first", audit.records[0]["message"])
+		assert.Contains(t, audit.records[0]["file"], "fluent_test.go")
+		assert.Contains(t, audit.records[0]["method"], "TestFluentClient_ProcessQueue")
+		assert.NotZero(t, audit.records[0]["line"])
+		assert.Equal(t, int64(1), audit.records[0]["n"])
+		assert.NotContains(t, audit.records[0], "tag")
+		assert.Equal(t, "third", audit.records[1]["message"])
+
+		app := server.next(t)
+		assert.Equal(t, "app", app.tag)
+		assert.Equal(t, []map[string]interface{}{{"level": "error", "message": "second", "f": 1.5}}, app.records)
+	})
+
+	t.Run("fields named like the record keys", func(t *testing.T) {
+		server := newTestFluentServer(t)
+		c, err := NewFluentClient("127.0.0.1", server.port(), "app")
+		require.NoError(t, err)
+
+		c.LogEntry(&Entry{Level: INFO, Message: "entry", File: "main.go", Method: "main.main", Line: 7, Fields: []KeyValue{
+			String("level", "l"), String("message", "m"), String("file", "f"), String("method", "mm"), Int("line", 1), String("other", "o"),
+		}})
+		go c.ProcessQueue()
+		c.Close()
+
+		assert.Equal(t, map[string]interface{}{
+			"level": "info", "message": "entry", "file": "main.go", "method": "main.main", "line": int64(7),
+			"_level": "l", "_message": "m", "_file": "f", "_method": "mm", "_line": int64(1), "other": "o",
+		}, server.next(t).records[0])
+	})
+
+	t.Run("close without processing the queue", func(t *testing.T) {
+		server := newTestFluentServer(t)
+		c, err := NewFluentClient("127.0.0.1", server.port(), "app")
+		require.NoError(t, err)
+
+		c.Println("queued")
+		c.Close()
+		c.ProcessQueue() // Returns right away once closed
+		assert.Equal(t, "queued", server.next(t).records[0]["message"])
+	})
+
+	t.Run("dropped after the retries", func(t *testing.T) {
+		c, err := NewFluentClient("127.0.0.1", "1", "app", WithFluentMaxRetries(1))
+		require.Error(t, err)
+		c.retryDelay = time.Millisecond
+
+		c.Println("lost")
+		closed := make(chan struct{})
+		go func() {
+			c.Close()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Close should return once the retries failed")
+		}
+	})
+
+	t.Run("batch size", func(t *testing.T) {
+		server := newTestFluentServer(t)
+		c, err := NewFluentClient("127.0.0.1", server.port(), "app", WithFluentBatchSize(2))
+		require.NoError(t, err)
+
+		for i := 0; i < 5; i++ {
+			c.Print(i)
+		}
+		go c.ProcessQueue()
+		c.Close()
+
+		assert.Len(t, server.next(t).records, 2)
+		assert.Len(t, server.next(t).records, 2)
+		assert.Len(t, server.next(t).records, 1)
+	})
+
+	t.Run("acks", func(t *testing.T) {
+		server := newTestFluentServer(t)
+		server.badAcks.Store(1)
+		c, err := NewFluentClient("127.0.0.1", server.port(), "app", WithFluentAck(time.Second))
+		require.NoError(t, err)
+		c.retryDelay = time.Millisecond
+
+		c.Println("acknowledged")
+		go c.ProcessQueue()
+		c.Close()
+
+		// The wrong ack is retried with a new chunk
+		first := server.next(t)
+		second := server.next(t)
+		assert.NotEmpty(t, first.option["chunk"])
+		assert.NotEqual(t, first.option["chunk"], second.option["chunk"])
+		assert.Equal(t, "acknowledged", second.records[0]["message"])
+	})
+
+	t.Run("reconnects", func(t *testing.T) {
+		server := newTestFluentServer(t)
+		c, err := NewFluentClient("127.0.0.1", server.port(), "app", WithFluentAck(time.Second))
+		require.NoError(t, err)
+		c.retryDelay = time.Millisecond
+		_ = c.conn.Close() // The next write fails
+
+		c.Println("after reconnecting")
+		go c.ProcessQueue()
+		c.Close()
+
+		assert.Equal(t, "after reconnecting", server.next(t).records[0]["message"])
+	})
+
+	t.Run("records after close", func(t *testing.T) {
+		server := newTestFluentServer(t)
+		c, err := NewFluentClient("127.0.0.1", server.port(), "app")
+		require.NoError(t, err)
+
+		go c.ProcessQueue()
+		c.Close()
+		c.Close()
+		assert.NotPanics(t, func() {
+			c.Println("closed")
+			c.LogEntry(&Entry{Level: INFO, Message: "closed"})
+		})
+	})
+}
+
+// TestFluentClient_sendOne will test sending a record before exiting
+func TestFluentClient_sendOne(t *testing.T) {
+	t.Run("while the queue is sending", func(t *testing.T) {
+		server := newTestFluentServer(t)
+		c, err := NewFluentClient("127.0.0.1", server.port(), "app", WithFluentAck(time.Second))
+		require.NoError(t, err)
+
+		c.connMu.Lock() // ProcessQueue is stuck in an attempt
+		defer c.connMu.Unlock()
+		c.sendOne(c.record(time.Now(), "fatal", "sent", nil))
+		assert.Equal(t, "sent", server.next(t).records[0]["message"])
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		c, err := NewFluentClient("127.0.0.1", "1", "app")
+		require.Error(t, err)
+
+		start := time.Now()
+		c.sendOne(c.record(time.Now(), "fatal", "lost", nil))
+		assert.Less(t, time.Since(start), fluentExitAttempts*fluentExitTimeout)
+	})
+}
+
+// TestReadFluentAck will test the readFluentAck() method
+func TestReadFluentAck(t *testing.T) {
+	tests := []struct {
+		name     string
+		response []byte
+		err      error
+	}{
+		{"valid", append(appendMsgpackString(appendMsgpackMapHeader(nil, 1), "ack"), appendMsgpackString(nil, "id")...), nil},
+		{"wrong chunk", append(appendMsgpackString(appendMsgpackMapHeader(nil, 1), "ack"), appendMsgpackString(nil, "other")...), ErrFluentAck},
+		{"missing ack", appendMsgpackMapHeader(nil, 0), ErrFluentAck},
+		{"not a map", appendMsgpackArrayHeader(nil, 0), ErrMsgpack},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer func() { _ = server.Close() }()
+			go func() { _, _ = server.Write(tt.response) }()
+
+			err := readFluentAck(client, time.Second, "id")
+			if tt.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+// TestFluentClient_Panic will test the Panic() method
+func TestFluentClient_Panic(t *testing.T) {
+	if port := os.Getenv("EXIT_FUNCTION_PORT"); len(port) > 0 {
+		c, _ := NewFluentClient("127.0.0.1", port, "app", WithFluentAck(time.Second))
+		c.Panicf("panicf %d", 1)
+		return
+	}
+	server := newTestFluentServer(t)
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestFluentClient_Panic") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION_PORT="+server.port())
+	err := cmd.Run()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		assert.Equal(t, map[string]interface{}{"level": "panic", "message": "panicf 1"}, server.next(t).records[0])
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestFluentClient_Fatal will test the Fatal() method
+func TestFluentClient_Fatal(t *testing.T) {
+	if port := os.Getenv("EXIT_FUNCTION_PORT"); len(port) > 0 {
+		c, _ := NewFluentClient("127.0.0.1", port, "app", WithFluentAck(time.Second))
+		c.Fatalf("fatal %d", 1)
+		return
+	}
+	server := newTestFluentServer(t)
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestFluentClient_Fatal") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION_PORT="+server.port())
+	err := cmd.Run()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		assert.Equal(t, map[string]interface{}{"level": "fatal", "message": "fatal 1"}, server.next(t).records[0])
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// BenchmarkFluentClient_record benchmarks the record() method
+func BenchmarkFluentClient_record(b *testing.B) {
+	c := &FluentClient{tag: "app"}
+	e := &Entry{Level: INFO, Message: "benchmark", File: "file.go", Method: "Method", Line: 10,
+		Fields: []KeyValue{String("key", "value"), Int("n", 1)}}
+	now := time.Now()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.record(now, "info", e.Message, e)
+	}
+}
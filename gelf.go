@@ -0,0 +1,448 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GELFCompression is the compression of GELF messages sent over UDP
+type GELFCompression uint8
+
+// GELF compressions (TCP messages are never compressed)
+const (
+	GELFCompressNone GELFCompression = iota
+	GELFCompressGzip
+	GELFCompressZlib
+)
+
+// GELF defaults and protocol constants
+const (
+	DefaultGELFChunkSize = 1420 // Fits in the MTU of most networks
+
+	gelfChunkHeaderSize = 12 // Magic bytes, message id, sequence number and count
+	gelfDialTimeout     = 10 * time.Second
+	gelfMaxChunks       = 128
+	gelfVersion         = "1.1"
+	gelfWriteTimeout    = 5 * time.Second // A stalled input doesn't block the callers longer
+)
+
+// GELF errors
+var (
+	ErrGELFMessageTooLarge = errors.New("gelf message needs more than 128 chunks")
+	ErrGELFNetwork         = errors.New("gelf network must be udp or tcp")
+	ErrGELFUnavailable     = errors.New("gelf input unavailable, waiting to reconnect")
+)
+
+// GELFOption is an optional setting for NewGELFClient
+type GELFOption func(c *GELFClient)
+
+// WithGELFChunkSize sets the maximum size of a UDP datagram (larger messages are chunked)
+func WithGELFChunkSize(size int) GELFOption {
+	return func(c *GELFClient) {
+		c.chunkSize = size
+	}
+}
+
+// WithGELFCompression compresses the messages sent over UDP
+func WithGELFCompression(compression GELFCompression) GELFOption {
+	return func(c *GELFClient) {
+		c.compression = compression
+	}
+}
+
+// WithGELFHost sets the host of the messages (defaults to the hostname)
+func WithGELFHost(host string) GELFOption {
+	return func(c *GELFClient) {
+		c.host = host
+	}
+}
+
+// GELFClient is a Logger sending GELF messages to Graylog over UDP or TCP, while it is
+// reconnecting the messages are printed with the log package instead of waiting
+type GELFClient struct {
+	address      string
+	chunkSize    int
+	compression  GELFCompression
+	conn         net.Conn
+	dialing      bool // A caller is connecting (the others don't wait for it)
+	host         string
+	mu           sync.Mutex // Serializes the writes and guards conn, dialing and nextDial
+	network      string
+	nextDial     time.Time     // No reconnect before then, after a failed dial
+	retryDelay   time.Duration // Delay after the next failed dial, doubled up to MaxRetryDelay
+	writeTimeout time.Duration // Deadline of each write
+}
+
+// NewGELFClient creates a client for a Graylog GELF input (network is "udp" or "tcp"),
+// the client is returned with the connection error so it can reconnect later
+func NewGELFClient(network, address string, opts ...GELFOption) (*GELFClient, error) {
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("%w: %q", ErrGELFNetwork, network)
+	}
+
+	c := &GELFClient{
+		address:      address,
+		chunkSize:    DefaultGELFChunkSize,
+		network:      network,
+		retryDelay:   RetryDelay,
+		writeTimeout: gelfWriteTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if len(c.host) == 0 {
+		if c.host, _ = os.Hostname(); len(c.host) == 0 {
+			c.host = "localhost"
+		}
+	}
+	if c.chunkSize <= gelfChunkHeaderSize {
+		c.chunkSize = DefaultGELFChunkSize
+	}
+
+	_, err := c.connection()
+	return c, err
+}
+
+// Close closes the connection
+func (c *GELFClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// connection returns the connection to the GELF input, dialing it if needed: the callers
+// fail fast with ErrGELFUnavailable while another one is dialing or until the backoff of a
+// failed dial elapsed
+func (c *GELFClient) connection() (net.Conn, error) {
+	c.mu.Lock()
+	if c.conn != nil {
+		conn := c.conn
+		c.mu.Unlock()
+		return conn, nil
+	}
+	if c.dialing || time.Now().Before(c.nextDial) {
+		c.mu.Unlock()
+		return nil, ErrGELFUnavailable
+	}
+	c.dialing = true
+	c.mu.Unlock()
+
+	conn, err := net.DialTimeout(c.network, c.address, gelfDialTimeout)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dialing = false
+	if err != nil {
+		c.nextDial = time.Now().Add(c.retryDelay)
+		if c.retryDelay *= 2; c.retryDelay > MaxRetryDelay {
+			c.retryDelay = MaxRetryDelay
+		}
+		return nil, err
+	}
+	c.conn = conn
+	c.retryDelay = RetryDelay
+	return conn, nil
+}
+
+// disconnect closes a connection that failed (unless it was already replaced)
+func (c *GELFClient) disconnect(conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == conn {
+		_ = c.conn.Close() // close the connection, don't care about the error
+		c.conn = nil
+	}
+}
+
+// LogEntry implements the EntryLogger interface, sending the fields as additional fields
+func (c *GELFClient) LogEntry(e *Entry) {
+	c.write(c.encode(e.Time, syslogSeverity(e.Level), e.Message, e))
+}
+
+// Panic overloads built-in method
+func (c *GELFClient) Panic(v ...interface{}) {
+	c.write(c.encode(time.Now(), syslogCritical, fmt.Sprint(v...), nil))
+	os.Exit(1)
+}
+
+// Panicln overloads built-in method
+func (c *GELFClient) Panicln(v ...interface{}) {
+	c.write(c.encode(time.Now(), syslogCritical, fmt.Sprintln(v...), nil))
+	os.Exit(1)
+}
+
+// Panicf overloads built-in method
+func (c *GELFClient) Panicf(format string, v ...interface{}) {
+	c.write(c.encode(time.Now(), syslogCritical, fmt.Sprintf(format, v...), nil))
+	os.Exit(1)
+}
+
+// Print overloads built-in method
+func (c *GELFClient) Print(v ...interface{}) {
+	c.write(c.encode(time.Now(), syslogInfo, fmt.Sprint(v...), nil))
+}
+
+// Println overloads built-in method
+func (c *GELFClient) Println(v ...interface{}) {
+	c.write(c.encode(time.Now(), syslogInfo, fmt.Sprintln(v...), nil))
+}
+
+// Printf overloads built-in method
+func (c *GELFClient) Printf(format string, v ...interface{}) {
+	c.write(c.encode(time.Now(), syslogInfo, fmt.Sprintf(format, v...), nil))
+}
+
+// Fatal overloads built-in method
+func (c *GELFClient) Fatal(v ...interface{}) {
+	c.write(c.encode(time.Now(), syslogCritical, fmt.Sprint(v...), nil))
+	os.Exit(1)
+}
+
+// Fatalln overloads built-in method
+func (c *GELFClient) Fatalln(v ...interface{}) {
+	c.write(c.encode(time.Now(), syslogCritical, fmt.Sprintln(v...), nil))
+	os.Exit(1)
+}
+
+// Fatalf overloads built-in method
+func (c *GELFClient) Fatalf(format string, v ...interface{}) {
+	c.write(c.encode(time.Now(), syslogCritical, fmt.Sprintf(format, v...), nil))
+	os.Exit(1)
+}
+
+// write sends a message, printing it with the log package if that failed
+func (c *GELFClient) write(msg []byte) {
+	if err := c.send(msg); err != nil {
+		log.Println(string(msg))
+		log.Println("go-logger: failed to send to graylog:", err) //nolint:gosec // G706: error originates from stdlib network functions
+	}
+}
+
+// send writes a message, reconnecting once if needed (a timed out write is not retried, the
+// next message reconnects)
+func (c *GELFClient) send(msg []byte) error {
+	var packets [][]byte
+	if c.network == "tcp" {
+		packets = [][]byte{append(msg, 0)} // Null byte framing
+	} else {
+		payload, err := compressGELF(msg, c.compression)
+		if err != nil {
+			return err
+		}
+		if packets, err = chunkGELF(payload, c.chunkSize); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var conn net.Conn
+		if conn, err = c.connection(); err != nil {
+			return err
+		}
+		if err = c.writePackets(conn, packets); err == nil {
+			return nil
+		}
+		c.disconnect(conn)
+
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return err
+		}
+	}
+	return err
+}
+
+// writePackets writes the packets (datagrams or TCP frames) to a connection, each write
+// within writeTimeout
+func (c *GELFClient) writePackets(conn net.Conn, packets [][]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, packet := range packets {
+		if err := conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+			return err
+		}
+		if _, err := conn.Write(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode builds a GELF message, with the fields of the entry (if any) as additional fields
+func (c *GELFClient) encode(t time.Time, level int, message string, e *Entry) []byte {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	message = strings.TrimRight(message, "\r\n")
+	short, _, multiline := strings.Cut(message, "\n")
+	if len(strings.TrimSpace(short)) == 0 {
+		short = "-" // short_message is required
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"version":"` + gelfVersion + `","host":`)
+	writeJSONString(&buf, c.host)
+	buf.WriteString(`,"short_message":`)
+	writeJSONString(&buf, short)
+	if multiline {
+		buf.WriteString(`,"full_message":`)
+		writeJSONString(&buf, message)
+	}
+	buf.WriteString(`,"timestamp":`)
+	buf.Write(strconv.AppendFloat(buf.AvailableBuffer(), float64(t.UnixMilli())/1000, 'f', 3, 64))
+	buf.WriteString(`,"level":`)
+	buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(level), 10))
+
+	if e != nil {
+		if len(e.File) > 0 {
+			buf.WriteString(`,"_file":`)
+			writeJSONString(&buf, e.File)
+			buf.WriteString(`,"_method":`)
+			writeJSONString(&buf, e.Method)
+			buf.WriteString(`,"_line":`)
+			buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(e.Line), 10))
+		}
+		for _, kv := range e.KeyValues() {
+			buf.WriteByte(',')
+			writeJSONString(&buf, gelfFieldName(kv.Key()))
+			buf.WriteByte(':')
+			writeGELFValue(&buf, kv)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+// gelfFieldName returns the additional field name of a key (only letters, digits,
+// underscores, dashes and dots are allowed, and the names used by the client and Graylog
+// get another underscore)
+func gelfFieldName(key string) string {
+	name := []byte("_" + key)
+	for i := 1; i < len(name); i++ {
+		c := name[i]
+		if c != '_' && c != '-' && c != '.' && (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			name[i] = '_'
+		}
+	}
+	switch string(name) {
+	case "_file", "_id", "_line", "_method":
+		return "_" + string(name)
+	}
+	return string(name)
+}
+
+// writeGELFValue writes the value of an additional field (GELF only allows strings and numbers)
+func writeGELFValue(buf *bytes.Buffer, kv KeyValue) {
+	if f, ok := kv.(Field); ok {
+		switch f.fieldType {
+		case IntType:
+			buf.Write(f.AppendValue(buf.AvailableBuffer()))
+			return
+		case FloatType:
+			if v := f.float(); !math.IsNaN(v) && !math.IsInf(v, 0) {
+				buf.Write(f.AppendValue(buf.AvailableBuffer()))
+				return
+			}
+		case DurationType:
+			// Durations are written as integer nanoseconds, like the JSONEncoder
+			buf.Write(strconv.AppendInt(buf.AvailableBuffer(), f.num, 10))
+			return
+		case AnyType, BoolType, ErrorType, StringType, StringerType, TimeType:
+		}
+		writeJSONString(buf, string(f.AppendValue(nil)))
+		return
+	}
+
+	switch v := kv.Value().(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		fmt.Fprint(buf, v)
+	case float32:
+		writeGELFFloat(buf, float64(v))
+	case float64:
+		writeGELFFloat(buf, v)
+	default:
+		writeJSONString(buf, fmt.Sprint(v))
+	}
+}
+
+// writeGELFFloat writes a float as a number (or a string if JSON can't represent it)
+func writeGELFFloat(buf *bytes.Buffer, v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		writeJSONString(buf, strconv.FormatFloat(v, 'f', -1, 64))
+		return
+	}
+	buf.Write(strconv.AppendFloat(buf.AvailableBuffer(), v, 'f', -1, 64))
+}
+
+// compressGELF compresses a message for UDP
+func compressGELF(msg []byte, compression GELFCompression) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch compression {
+	case GELFCompressGzip:
+		w = gzip.NewWriter(&buf)
+	case GELFCompressZlib:
+		w = zlib.NewWriter(&buf)
+	default: // GELFCompressNone
+		return msg, nil
+	}
+	if _, err := w.Write(msg); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// chunkGELF splits a UDP payload into GELF chunks (magic bytes 0x1e 0x0f, a random
+// message id, the sequence number and count) when it doesn't fit in a datagram
+func chunkGELF(payload []byte, chunkSize int) ([][]byte, error) {
+	if len(payload) <= chunkSize {
+		return [][]byte{payload}, nil
+	}
+
+	dataSize := chunkSize - gelfChunkHeaderSize
+	count := (len(payload) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("%w: %d bytes", ErrGELFMessageTooLarge, len(payload))
+	}
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * dataSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+		chunk := make([]byte, 0, gelfChunkHeaderSize+end-i*dataSize)
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id[:]...)
+		chunk = append(chunk, byte(i), byte(count)) //nolint:gosec // G115: at most gelfMaxChunks
+		chunks = append(chunks, append(chunk, payload[i*dataSize:end]...))
+	}
+	return chunks, nil
+}
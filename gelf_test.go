@@ -0,0 +1,474 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestGELFUDP listens for GELF datagrams
+func newTestGELFUDP(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// readGELFDatagram reads a datagram
+func readGELFDatagram(t *testing.T, conn net.PacketConn) []byte {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 65536)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	return buf[:n]
+}
+
+// decodeGELF decodes a GELF message
+func decodeGELF(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	var msg map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &msg))
+	return msg
+}
+
+// newTestGELFClient creates a client with a fixed host
+func newTestGELFClient(t *testing.T, network, address string, opts ...GELFOption) *GELFClient {
+	t.Helper()
+	c, err := NewGELFClient(network, address, append([]GELFOption{WithGELFHost("test-host")}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+// TestNewGELFClient will test the NewGELFClient() method
+func TestNewGELFClient(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		server := newTestGELFUDP(t)
+		c, err := NewGELFClient("udp", server.LocalAddr().String())
+		require.NoError(t, err)
+		defer func() { _ = c.Close() }()
+
+		hostname, _ := os.Hostname()
+		assert.Equal(t, hostname, c.host)
+		assert.Equal(t, DefaultGELFChunkSize, c.chunkSize)
+		assert.Equal(t, GELFCompressNone, c.compression)
+		assert.NotNil(t, c.conn)
+	})
+
+	t.Run("options", func(t *testing.T) {
+		server := newTestGELFUDP(t)
+		c := newTestGELFClient(t, "udp", server.LocalAddr().String(),
+			WithGELFChunkSize(8000), WithGELFCompression(GELFCompressZlib))
+		assert.Equal(t, "test-host", c.host)
+		assert.Equal(t, 8000, c.chunkSize)
+		assert.Equal(t, GELFCompressZlib, c.compression)
+	})
+
+	t.Run("chunk size too small", func(t *testing.T) {
+		server := newTestGELFUDP(t)
+		c := newTestGELFClient(t, "udp", server.LocalAddr().String(), WithGELFChunkSize(12))
+		assert.Equal(t, DefaultGELFChunkSize, c.chunkSize)
+	})
+
+	t.Run("invalid network", func(t *testing.T) {
+		c, err := NewGELFClient("unix", "/tmp/graylog.sock")
+		require.ErrorIs(t, err, ErrGELFNetwork)
+		assert.Nil(t, c)
+	})
+
+	t.Run("connection error", func(t *testing.T) {
+		c, err := NewGELFClient("tcp", "127.0.0.1:1")
+		require.Error(t, err)
+		assert.NotNil(t, c)
+		assert.Nil(t, c.conn)
+	})
+}
+
+// TestGELFClient_UDP will test sending messages over UDP
+func TestGELFClient_UDP(t *testing.T) {
+	t.Run("print", func(t *testing.T) {
+		server := newTestGELFUDP(t)
+		c := newTestGELFClient(t, "udp", server.LocalAddr().String())
+
+		c.Println("hello", "graylog")
+		msg := decodeGELF(t, readGELFDatagram(t, server))
+		assert.Equal(t, "1.1", msg["version"])
+		assert.Equal(t, "test-host", msg["host"])
+		assert.Equal(t, "hello graylog", msg["short_message"])
+		assert.NotContains(t, msg, "full_message")
+		assert.InDelta(t, float64(time.Now().Unix()), msg["timestamp"], 5)
+		assert.InDelta(t, syslogInfo, msg["level"], 0)
+	})
+
+	t.Run("entry fields", func(t *testing.T) {
+		server := newTestGELFUDP(t)
+		c := newTestGELFClient(t, "udp", server.LocalAddr().String())
+
+		previous := GetImplementation()
+		SetImplementation(c)
+		Data(2, WARN, "slow request",
+			String("path", "/users"),
+			Int("status", 200),
+			Duration("elapsed", time.Millisecond),
+			Bool("cached", true),
+			MakeParameter("request id", 42),
+			MakeParameter("id", "abc"),
+			String("file", "upload.csv"),
+		)
+		SetImplementation(previous)
+
+		msg := decodeGELF(t, readGELFDatagram(t, server))
+		assert.Equal(t, "slow request", msg["short_message"])
+		assert.InDelta(t, syslogWarning, msg["level"], 0)
+		assert.Contains(t, msg["_file"], "gelf_test.go")
+		assert.Contains(t, msg["_method"], "TestGELFClient_UDP")
+		assert.Greater(t, msg["_line"], float64(0))
+		assert.Equal(t, "/users", msg["_path"])
+		assert.InDelta(t, 200, msg["_status"], 0)
+		assert.InDelta(t, float64(time.Millisecond), msg["_elapsed"], 0)
+		assert.Equal(t, "true", msg["_cached"])
+		assert.InDelta(t, 42, msg["_request_id"], 0)
+		assert.Equal(t, "abc", msg["__id"])
+		assert.Equal(t, "upload.csv", msg["__file"])
+	})
+
+	t.Run("multiline message", func(t *testing.T) {
+		server := newTestGELFUDP(t)
+		c := newTestGELFClient(t, "udp", server.LocalAddr().String())
+
+		c.Print("first line\nsecond line\n")
+		msg := decodeGELF(t, readGELFDatagram(t, server))
+		assert.Equal(t, "first line", msg["short_message"])
+		assert.Equal(t, "first line\nsecond line", msg["full_message"])
+	})
+
+	t.Run("empty message", func(t *testing.T) {
+		server := newTestGELFUDP(t)
+		c := newTestGELFClient(t, "udp", server.LocalAddr().String())
+
+		c.Println()
+		msg := decodeGELF(t, readGELFDatagram(t, server))
+		assert.Equal(t, "-", msg["short_message"])
+		assert.NotContains(t, msg, "full_message")
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		server := newTestGELFUDP(t)
+		c := newTestGELFClient(t, "udp", server.LocalAddr().String(), WithGELFCompression(GELFCompressGzip))
+
+		c.Printf("compressed %d", 1)
+		zr, err := gzip.NewReader(bytes.NewReader(readGELFDatagram(t, server)))
+		require.NoError(t, err)
+		data, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		assert.Equal(t, "compressed 1", decodeGELF(t, data)["short_message"])
+	})
+
+	t.Run("zlib", func(t *testing.T) {
+		server := newTestGELFUDP(t)
+		c := newTestGELFClient(t, "udp", server.LocalAddr().String(), WithGELFCompression(GELFCompressZlib))
+
+		c.Printf("compressed %d", 2)
+		zr, err := zlib.NewReader(bytes.NewReader(readGELFDatagram(t, server)))
+		require.NoError(t, err)
+		data, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		assert.Equal(t, "compressed 2", decodeGELF(t, data)["short_message"])
+	})
+
+	t.Run("chunked", func(t *testing.T) {
+		server := newTestGELFUDP(t)
+		c := newTestGELFClient(t, "udp", server.LocalAddr().String(), WithGELFChunkSize(100))
+
+		long := strings.Repeat("x", 1000)
+		c.Print(long)
+
+		var payload []byte
+		var id []byte
+		for i := 0; ; i++ {
+			chunk := readGELFDatagram(t, server)
+			require.LessOrEqual(t, len(chunk), 100)
+			assert.Equal(t, []byte{0x1e, 0x0f}, chunk[:2])
+			if id == nil {
+				id = chunk[2:10]
+			}
+			assert.Equal(t, id, chunk[2:10])
+			assert.Equal(t, byte(i), chunk[10])
+			payload = append(payload, chunk[12:]...)
+			if int(chunk[11]) == i+1 {
+				break
+			}
+		}
+		assert.Equal(t, long, decodeGELF(t, payload)["short_message"])
+	})
+}
+
+// TestGELFClient_TCP will test sending messages over TCP
+func TestGELFClient_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	frames := make(chan []byte, 10)
+	go func() {
+		for {
+			conn, acceptErr := listener.Accept()
+			if acceptErr != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				r := bufio.NewReader(conn)
+				for {
+					frame, readErr := r.ReadBytes(0)
+					if readErr != nil {
+						return
+					}
+					frames <- frame
+				}
+			}()
+		}
+	}()
+
+	c := newTestGELFClient(t, "tcp", listener.Addr().String(), WithGELFCompression(GELFCompressGzip))
+	c.Println("first")
+	c.Println("second")
+
+	for _, expected := range []string{"first", "second"} {
+		select {
+		case frame := <-frames:
+			require.Equal(t, byte(0), frame[len(frame)-1])
+			assert.Equal(t, expected, decodeGELF(t, frame[:len(frame)-1])["short_message"])
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for the frame")
+		}
+	}
+
+	// Reconnects after the connection was closed
+	require.NoError(t, c.Close())
+	c.Println("reconnected")
+	select {
+	case frame := <-frames:
+		assert.Equal(t, "reconnected", decodeGELF(t, frame[:len(frame)-1])["short_message"])
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the frame")
+	}
+}
+
+// TestGELFClient_WriteTimeout will test giving up writing to a stalled GELF input
+func TestGELFClient_WriteTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	// Accepts the connections, but never reads them
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, acceptErr := listener.Accept()
+			if acceptErr != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+	defer func() {
+		_ = listener.Close()
+		for {
+			select {
+			case conn := <-accepted:
+				_ = conn.Close()
+			default:
+				return
+			}
+		}
+	}()
+
+	c := newTestGELFClient(t, "tcp", listener.Addr().String())
+	c.writeTimeout = 50 * time.Millisecond
+
+	// Fills the socket buffers until a write times out
+	msg := bytes.Repeat([]byte("x"), 1024*1024)
+	start := time.Now()
+	for i := 0; i < 256; i++ {
+		if err = c.send(msg); err != nil {
+			break
+		}
+	}
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+	assert.Less(t, time.Since(start), 10*time.Second)
+
+	// The stalled connection was dropped, the next message reconnects
+	c.mu.Lock()
+	assert.Nil(t, c.conn)
+	c.mu.Unlock()
+}
+
+// TestGELFClient_SendError will test sending without a GELF input
+func TestGELFClient_SendError(t *testing.T) {
+	c, err := NewGELFClient("tcp", "127.0.0.1:1")
+	require.Error(t, err)
+
+	require.ErrorIs(t, c.send([]byte("{}")), ErrGELFUnavailable) // Waits for the backoff
+	assert.NotPanics(t, func() { c.Println("lost") })
+
+	// Dials again once the backoff elapsed
+	c.mu.Lock()
+	assert.Equal(t, 2*RetryDelay, c.retryDelay)
+	c.nextDial = time.Time{}
+	c.mu.Unlock()
+	err = c.send([]byte("{}"))
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrGELFUnavailable)
+
+	// Doesn't wait for another caller dialing
+	c.mu.Lock()
+	c.dialing, c.nextDial = true, time.Time{}
+	c.mu.Unlock()
+	require.ErrorIs(t, c.send([]byte("{}")), ErrGELFUnavailable)
+}
+
+// TestGELFClient_Panic will test the Panic() method
+func TestGELFClient_Panic(t *testing.T) {
+	if address := os.Getenv("EXIT_FUNCTION_ADDRESS"); len(address) > 0 {
+		c, _ := NewGELFClient("udp", address)
+		c.Panicf("panicf %d", 1)
+		return
+	}
+	server := newTestGELFUDP(t)
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestGELFClient_Panic") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION_ADDRESS="+server.LocalAddr().String())
+	err := cmd.Run()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		msg := decodeGELF(t, readGELFDatagram(t, server))
+		assert.Equal(t, "panicf 1", msg["short_message"])
+		assert.InDelta(t, syslogCritical, msg["level"], 0)
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestGELFClient_Fatal will test the Fatal() method
+func TestGELFClient_Fatal(t *testing.T) {
+	if address := os.Getenv("EXIT_FUNCTION_ADDRESS"); len(address) > 0 {
+		c, _ := NewGELFClient("udp", address)
+		c.Fatalln("fatal", 1)
+		return
+	}
+	server := newTestGELFUDP(t)
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestGELFClient_Fatal") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION_ADDRESS="+server.LocalAddr().String())
+	err := cmd.Run()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		msg := decodeGELF(t, readGELFDatagram(t, server))
+		assert.Equal(t, "fatal 1", msg["short_message"])
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestGELFFieldName will test the gelfFieldName() method
+func TestGELFFieldName(t *testing.T) {
+	tests := map[string]string{
+		"user_id":    "_user_id",
+		"http.path":  "_http.path",
+		"trace-id":   "_trace-id",
+		"with space": "_with_space",
+		"émoji✓":     "___moji___",
+		"id":         "__id",
+		"file":       "__file",
+		"line":       "__line",
+		"method":     "__method",
+		"":           "_",
+	}
+	for key, expected := range tests {
+		assert.Equal(t, expected, gelfFieldName(key), key)
+	}
+}
+
+// TestWriteGELFValue will test the writeGELFValue() method
+func TestWriteGELFValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		kv       KeyValue
+		expected string
+	}{
+		{"int field", Int("k", -3), `-3`},
+		{"float field", Float64("k", 1.5), `1.5`},
+		{"nan field", Float64("k", math.NaN()), `"NaN"`},
+		{"string field", String("k", `a "b"`), `"a \"b\""`},
+		{"error field", Err(errors.New("boom")), `"boom"`},
+		{"int parameter", MakeParameter("k", uint8(7)), `7`},
+		{"float parameter", MakeParameter("k", float32(0.25)), `0.25`},
+		{"inf parameter", MakeParameter("k", math.Inf(1)), `"+Inf"`},
+		{"nil parameter", MakeParameter("k", nil), `"<nil>"`},
+		{"struct parameter", MakeParameter("k", struct{ A int }{1}), `"{1}"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeGELFValue(&buf, tt.kv)
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
+
+// TestChunkGELF will test the chunkGELF() method
+func TestChunkGELF(t *testing.T) {
+	t.Run("fits in one datagram", func(t *testing.T) {
+		chunks, err := chunkGELF([]byte("small"), 100)
+		require.NoError(t, err)
+		assert.Equal(t, [][]byte{[]byte("small")}, chunks)
+	})
+
+	t.Run("chunk count", func(t *testing.T) {
+		chunks, err := chunkGELF(bytes.Repeat([]byte("x"), 177), 100)
+		require.NoError(t, err)
+		require.Len(t, chunks, 3)
+		assert.Len(t, chunks[0], 100)
+		assert.Len(t, chunks[2], 12+177-2*88)
+		assert.Equal(t, byte(3), chunks[2][11])
+	})
+
+	t.Run("too many chunks", func(t *testing.T) {
+		chunks, err := chunkGELF(bytes.Repeat([]byte("x"), 129*88), 100)
+		require.ErrorIs(t, err, ErrGELFMessageTooLarge)
+		assert.Nil(t, chunks)
+	})
+}
+
+// BenchmarkGELFClient_encode benchmarks the encode() method
+func BenchmarkGELFClient_encode(b *testing.B) {
+	c := &GELFClient{host: "bench"}
+	e := &Entry{Level: INFO, Message: "benchmark", File: "file.go", Method: "Method", Line: 10,
+		Fields: []KeyValue{String("key", "value"), Int("n", 1)}}
+	now := time.Now()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.encode(now, syslogInfo, e.Message, e)
+	}
+}
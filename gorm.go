@@ -2,11 +2,14 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"runtime"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // GormLoggerInterface is a logger interface to help work with GORM
@@ -38,25 +41,40 @@ const (
 	Info
 )
 
-// SlowQueryThreshold is the time cut-off for considering a query as "slow"
+// SlowQueryThreshold is the default time cut-off for considering a query as "slow"
 const SlowQueryThreshold = 5 * time.Second
 
 // NewGormLogger will return a basic logger interface
-func NewGormLogger(debugging bool, stackLevel int) GormLoggerInterface {
+func NewGormLogger(debugging bool, stackLevel int, opts ...GormOption) GormLoggerInterface {
 	logLevel := Warn
 	if debugging {
 		logLevel = Info
 	}
-	return &basicGormLogger{
-		logLevel:   logLevel,
-		stackLevel: stackLevel,
+	l := &basicGormLogger{
+		ignoreErrorFunc: isRecordNotFound,
+		logLevel:        logLevel,
+		slowThreshold:   SlowQueryThreshold,
+		stackLevel:      stackLevel,
 	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
 }
 
 // basicGormLogger is a basic implementation of the logger interface if no custom logger is provided
 type basicGormLogger struct {
-	logLevel   GormLogLevel // Log level (info, error, etc)
-	stackLevel int          // How many files/functions to traverse upwards to record the file/line
+	ignoreErrorFunc   func(err error) bool // Reports errors that are expected (defaults to "record not found")
+	ignoredErrorLevel LogLevel             // Level of ignored errors, if logIgnoredErrors is set
+	ignoredErrors     []error              // Errors that are expected (matched with errors.Is)
+	logIgnoredErrors  bool                 // Log ignored errors at ignoredErrorLevel instead of dropping them
+	logLevel          GormLogLevel         // Log level (info, error, etc)
+	nPlusOneThreshold int                  // Same query runs allowed per QueryScope before warning (zero disables)
+	redactor          SQLRedactor          // Removes sensitive values from the SQL before it is logged (optional)
+	rowsThreshold     int64                // Queries affecting more rows than this are logged as a warning (zero disables)
+	slowThreshold     time.Duration        // Queries taking longer than this are logged as slow (zero disables)
+	stats             *QueryStats          // Rolling statistics per query (optional)
+	stackLevel        int                  // How many files/functions to traverse upwards to record the file/line
 }
 
 // SetMode will set the log mode
@@ -103,72 +121,299 @@ func (l *basicGormLogger) Error(_ context.Context, message string, params ...int
 }
 
 // Trace is for GORM/SQL tracing from datastore
-func (l *basicGormLogger) Trace(_ context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+func (l *basicGormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	failed := err != nil && !l.ignoredError(err)
+
+	// The callback is only run once (and statistics are kept even when silent)
+	var q *tracedQuery
+	query := func() *tracedQuery {
+		if q == nil {
+			q = l.query(fc)
+		}
+		return q
+	}
+	if l.stats != nil {
+		l.stats.record(query().fingerprint, q.normalized, elapsed, q.rows, failed)
+	}
 	if l.logLevel <= Silent {
 		return
 	}
-	elapsed := time.Since(begin)
+
+	// Warn once per scope when the same query runs more than the threshold (N+1 queries)
+	if scope := QueryScopeFromContext(ctx); scope != nil && l.nPlusOneThreshold > 0 && l.logLevel >= Warn {
+		if count := scope.add(query().fingerprint); count == l.nPlusOneThreshold+1 {
+			Data(
+				l.stackLevel, WARN,
+				"possible N+1 query",
+				MakeParameter("caller", fileWithLineNum()),
+				Int("count", count),
+				Int("threshold", l.nPlusOneThreshold),
+				MakeParameter("query_fingerprint", q.fingerprint),
+				MakeParameter("query_normalized", q.normalized),
+			)
+		}
+	}
+
+	slowThreshold := l.effectiveSlowThreshold(ctx)
+	slow := slowThreshold > 0 && elapsed > slowThreshold
+	manyRows := l.rowsThreshold > 0 && l.logLevel >= Warn && query().rows > l.rowsThreshold
+
+	var (
+		level   LogLevel
+		message string
+		fields  = []KeyValue{MakeParameter("caller", fileWithLineNum())}
+	)
 	switch {
-	case err != nil && l.logLevel >= Error && (!strings.Contains(err.Error(), "record not found")):
-		sql, rows := fc()
-		Data(
-			l.stackLevel, ERROR,
-			"error executing query",
-			MakeParameter("file", fileWithLineNum()),
-			MakeParameter("error", err.Error()),
-			MakeParameter("duration", fmt.Sprintf("%.3fms", float64(elapsed.Nanoseconds())/1e6)),
-			MakeParameter("rows", rows),
-			MakeParameter("sql", sql),
-		)
-	case elapsed > SlowQueryThreshold && l.logLevel >= Warn:
-		sql, rows := fc()
-		Data(
-			l.stackLevel, WARN,
-			"warning executing query",
-			MakeParameter("file", fileWithLineNum()),
-			MakeParameter("slow_log", fmt.Sprintf("SLOW SQL >= %v", SlowQueryThreshold)),
-			MakeParameter("duration", fmt.Sprintf("%.3fms", float64(elapsed.Nanoseconds())/1e6)),
-			MakeParameter("rows", rows),
-			MakeParameter("sql", sql),
-		)
+	case failed && l.logLevel >= Error:
+		level, message = ERROR, "error executing query"
+		fields = append(fields, MakeParameter("error", err.Error()))
+	case (slow || manyRows) && l.logLevel >= Warn:
+		level, message = WARN, "warning executing query"
+		if slow {
+			fields = append(fields, MakeParameter("slow_log", fmt.Sprintf("SLOW SQL >= %v", slowThreshold)))
+		}
+		if manyRows {
+			fields = append(fields, Int64("rows_threshold", l.rowsThreshold))
+		}
+	case err != nil && !failed && l.logIgnoredErrors && l.logLevel >= gormLogLevel(l.ignoredErrorLevel):
+		level, message = l.ignoredErrorLevel, "ignored error executing query"
+		fields = append(fields, MakeParameter("error", err.Error()))
 	case l.logLevel == Info:
-		sql, rows := fc()
-		Data(
-			l.stackLevel, INFO,
-			"executing sql query",
-			MakeParameter("file", fileWithLineNum()),
-			MakeParameter("duration", fmt.Sprintf("%.3fms", float64(elapsed.Nanoseconds())/1e6)),
-			MakeParameter("rows", rows),
-			MakeParameter("sql", sql),
-		)
+		level, message = INFO, "executing sql query"
+	default:
+		return
 	}
+
+	q = query()
+	Data(l.stackLevel, level, message, append(fields,
+		Float64("duration_ms", float64(elapsed)/float64(time.Millisecond)),
+		Int64("rows", q.rows),
+		MakeParameter("sql", l.redact(q.sql)),
+		MakeParameter("query_fingerprint", q.fingerprint),
+		MakeParameter("query_normalized", q.normalized),
+	)...)
+}
+
+// ignoredError reports whether an error is expected and should not be logged as a failure
+func (l *basicGormLogger) ignoredError(err error) bool {
+	for _, ignored := range l.ignoredErrors {
+		if errors.Is(err, ignored) {
+			return true
+		}
+	}
+	return l.ignoreErrorFunc != nil && l.ignoreErrorFunc(err)
+}
+
+// isRecordNotFound is the default ignoreErrorFunc, matching GORM's ErrRecordNotFound
+// without depending on GORM
+func isRecordNotFound(err error) bool {
+	return strings.Contains(err.Error(), "record not found")
+}
+
+// gormLogLevel returns the GORM log level needed to display a log level
+func gormLogLevel(level LogLevel) GormLogLevel {
+	switch level {
+	case ERROR:
+		return Error
+	case WARN:
+		return Warn
+	case DEBUG, INFO:
+		return Info
+	}
+	return Info
+}
+
+// tracedQuery is a query reported to Trace
+type tracedQuery struct {
+	fingerprint string // Identifies the shape of the query (see FingerprintSQL)
+	normalized  string // Query without its values
+	rows        int64  // Rows affected
+	sql         string // Query as executed
+}
+
+// query runs the GORM callback and fingerprints the SQL
+func (l *basicGormLogger) query(fc func() (sql string, rowsAffected int64)) *tracedQuery {
+	sql, rows := fc()
+	q := &tracedQuery{rows: rows, sql: sql}
+	q.fingerprint, q.normalized = FingerprintSQL(sql)
+	return q
+}
+
+// redact removes sensitive values from the SQL if a redactor is set
+func (l *basicGormLogger) redact(sql string) string {
+	if l.redactor != nil {
+		return l.redactor.Redact(sql)
+	}
+	return sql
 }
 
 // displayLog will display a log using logger
+//
+// Like GORM, the message is formatted with fmt.Sprintf when it contains verbs, but only if
+// there are enough params before the first KeyValue or slog.Attr for them, and only if
+// formatting doesn't produce a %! error (IE: "50% done" is logged unchanged). The params left over are key/value pairs: KeyValue or slog.Attr
+// values, or alternating keys and values ("user_id", 123). Anything else is logged as param_N.
+//
+// Alternating pairs are ambiguous: a positional string without spaces, "=" or quotes is
+// read as the key of the next param (IE: "users", 3 is logged as users="3"). Use KeyValue
+// or slog.Attr values when the strings are values.
 func displayLog(level LogLevel, stackLevel int, message string, params ...interface{}) {
+	if verbs := countFormatVerbs(message); verbs <= formatArgs(params) && (verbs > 0 || strings.Contains(message, "%%")) {
+		if formatted := fmt.Sprintf(message, params[:verbs]...); !strings.Contains(formatted, "%!") || strings.Contains(message, "%!") {
+			message, params = formatted, params[verbs:]
+		}
+	}
+	Data(stackLevel, level, message, paramKeyValues(params)...)
+}
+
+// formatArgs returns the number of leading params that can be format arguments (KeyValue
+// and slog.Attr values are always fields)
+func formatArgs(params []interface{}) int {
+	for i, param := range params {
+		switch param.(type) {
+		case KeyValue, slog.Attr:
+			return i
+		}
+	}
+	return len(params)
+}
+
+// paramKeyValues turns variadic params into key/value pairs
+func paramKeyValues(params []interface{}) []KeyValue {
 	var keyValues []KeyValue
-	if len(params) > 0 {
-		for index, val := range params {
-			keyValues = append(keyValues, MakeParameter(fmt.Sprintf("param_%d", index), val))
+	for index := 0; index < len(params); index++ {
+		switch val := params[index].(type) {
+		case KeyValue:
+			keyValues = append(keyValues, val)
+			continue
+		case slog.Attr:
+			keyValues = appendAttr(keyValues, "", val)
+			continue
+		case string:
+			if index+1 < len(params) && isParamKey(val) {
+				keyValues = append(keyValues, MakeParameter(val, params[index+1]))
+				index++
+				continue
+			}
+		}
+		keyValues = append(keyValues, MakeParameter(fmt.Sprintf("param_%d", index), params[index]))
+	}
+	return keyValues
+}
+
+// appendAttr appends a slog.Attr, flattening groups into dotted keys (IE: request.id)
+func appendAttr(keyValues []KeyValue, prefix string, attr slog.Attr) []KeyValue {
+	key := prefix + attr.Key
+	value := attr.Value.Resolve()
+	if value.Kind() != slog.KindGroup {
+		return append(keyValues, MakeParameter(key, value.Any()))
+	}
+	if len(attr.Key) > 0 {
+		key += "."
+	}
+	for _, a := range value.Group() {
+		keyValues = appendAttr(keyValues, key, a)
+	}
+	return keyValues
+}
+
+// isParamKey reports whether a string param looks like a key (IE: user_id, not a sentence)
+func isParamKey(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, c := range s {
+		if unicode.IsSpace(c) || c == '=' || c == '"' {
+			return false
+		}
+	}
+	return true
+}
+
+// countFormatVerbs returns the number of arguments used by the verbs of a format
+// (IE: "%s took %*d" uses 3, "100%%" uses none)
+//
+// A percent sign after a number and before a space is a percentage, not a verb with the
+// space flag (IE: "50% done" uses none), and only letters are verbs ("100%)" uses none).
+func countFormatVerbs(format string) int {
+	count := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i > 0 && isSQLDigit(format[i-1]) && i+1 < len(format) && format[i+1] == ' ' {
+			continue
+		}
+		i++
+
+		// Flags, width and precision (* takes an argument)
+		stars := 0
+		for ; i < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[i]) >= 0; i++ {
+			if format[i] == '*' {
+				stars++
+			}
+		}
+		if i < len(format) && isFormatVerb(format[i]) {
+			count += stars + 1
 		}
 	}
-	Data(stackLevel, level, message, keyValues...)
+	return count
+}
+
+// isFormatVerb reports whether c is a letter, which are the only fmt verbs
+func isFormatVerb(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// callerPackages are skipped by fileWithLineNum: database/sql and our wrappers add frames
+// between the caller and Trace
+//
+//nolint:gochecknoglobals // Read-only list of package prefixes
+var callerPackages = []string{
+	"database/sql.", // The standard library calling the driver
+	"github.com/mrz1836/go-logger/gormlogger.", // Our gorm.io/gorm/logger.Interface adapter
+	"github.com/mrz1836/go-logger/sqllog.",     // Our database/sql driver wrapper
 }
 
 // fileWithLineNum return the file name and line number of the current file
 // This is originally from GORM: https://github.com/go-gorm/gorm/blob/7837fb6fa001ef78bc76e66b48445dee7b2db37b/utils/utils.go#L23
 // Copied method in order to not make GORM a dependency of the project for this tiny utility method
+//
+// Unlike GORM, the whole stack is walked (not only 15 frames), skipping callerPackages
 func fileWithLineNum() string {
 	// the first & second caller usually from gorm internal, so set index start from 3
-	for i := 2; i < 15; i++ {
-		_, file, line, ok := runtime.Caller(i)
-		if ok && (!strings.HasSuffix(file, "_test.go") && // Skip test files
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	for n == len(pcs) {
+		pcs = make([]uintptr, 2*len(pcs))
+		n = runtime.Callers(3, pcs)
+	}
+
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		file := frame.File
+		if frame.PC != 0 && !isCallerPackage(frame.Function) &&
+			!strings.HasSuffix(file, "_test.go") && // Skip test files
 			!strings.Contains(file, "gorm.go") && // This is our local "gorm.go" file
 			// !strings.Contains(file, "migrator.go") && (This file actually is used when using Migrations
 			!strings.Contains(file, "callbacks.go") && // This file is a helper for GORM
-			!strings.Contains(file, "finisher_api.go")) { // This file is a helper for GORM
-			return file + ":" + strconv.FormatInt(int64(line), 10)
+			!strings.Contains(file, "finisher_api.go") { // This file is a helper for GORM
+			return file + ":" + strconv.FormatInt(int64(frame.Line), 10)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// isCallerPackage reports whether a function belongs to one of the callerPackages
+func isCallerPackage(function string) bool {
+	for _, prefix := range callerPackages {
+		if strings.HasPrefix(function, prefix) {
+			return true
 		}
 	}
-	return ""
+	return false
 }
@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"context"
+	"time"
+)
+
+// GormOption is an optional setting for NewGormLogger
+type GormOption func(l *basicGormLogger)
+
+// slowThresholdKey is the context key for a per-query slow threshold
+type slowThresholdKey struct{}
+
+// WithSlowThreshold sets the duration after which a query is logged as slow
+// (defaults to SlowQueryThreshold, zero disables slow query logging)
+func WithSlowThreshold(threshold time.Duration) GormOption {
+	return func(l *basicGormLogger) {
+		l.slowThreshold = threshold
+	}
+}
+
+// WithRowsThreshold logs a warning (with rows_threshold) for queries affecting more
+// than threshold rows (zero disables)
+func WithRowsThreshold(threshold int64) GormOption {
+	return func(l *basicGormLogger) {
+		l.rowsThreshold = threshold
+	}
+}
+
+// WithSQLRedactor removes sensitive values from the SQL before it is logged
+// (IE: NewLiteralRedactor(RedactPlaceholder))
+func WithSQLRedactor(redactor SQLRedactor) GormOption {
+	return func(l *basicGormLogger) {
+		l.redactor = redactor
+	}
+}
+
+// WithQueryStats keeps rolling statistics per query in stats (even when the logger is silent)
+func WithQueryStats(stats *QueryStats) GormOption {
+	return func(l *basicGormLogger) {
+		l.stats = stats
+	}
+}
+
+// WithNPlusOneThreshold warns (once) when the same query runs more than threshold times
+// within a QueryScope (see NewQueryScope), which usually means an N+1 query
+func WithNPlusOneThreshold(threshold int) GormOption {
+	return func(l *basicGormLogger) {
+		l.nPlusOneThreshold = threshold
+	}
+}
+
+// WithIgnoredErrors sets errors (matched with errors.Is) that are expected, such as
+// gorm.ErrRecordNotFound or context.Canceled, and are not logged as query errors
+func WithIgnoredErrors(errs ...error) GormOption {
+	return func(l *basicGormLogger) {
+		l.ignoredErrors = append(l.ignoredErrors, errs...)
+	}
+}
+
+// WithIgnoreErrorFunc sets a function reporting errors that are expected, replacing the
+// default that ignores "record not found" errors (nil only uses WithIgnoredErrors)
+func WithIgnoreErrorFunc(fn func(err error) bool) GormOption {
+	return func(l *basicGormLogger) {
+		l.ignoreErrorFunc = fn
+	}
+}
+
+// WithIgnoredErrorLevel logs ignored errors at the given level instead of dropping them
+func WithIgnoredErrorLevel(level LogLevel) GormOption {
+	return func(l *basicGormLogger) {
+		l.ignoredErrorLevel = level
+		l.logIgnoredErrors = true
+	}
+}
+
+// ContextWithSlowThreshold overrides the slow query threshold for the queries run
+// with the returned context (IE: reports that are expected to be slower)
+func ContextWithSlowThreshold(ctx context.Context, threshold time.Duration) context.Context {
+	return context.WithValue(ctx, slowThresholdKey{}, threshold)
+}
+
+// effectiveSlowThreshold returns the slow threshold for a query, using the context override if set
+func (l *basicGormLogger) effectiveSlowThreshold(ctx context.Context) time.Duration {
+	if ctx != nil {
+		if threshold, ok := ctx.Value(slowThresholdKey{}).(time.Duration); ok {
+			return threshold
+		}
+	}
+	return l.slowThreshold
+}
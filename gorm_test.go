@@ -1,7 +1,14 @@
 package logger
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -51,4 +58,273 @@ func TestBasicLogger_SetStackLevel(t *testing.T) {
 func TestFileWithLineNum(t *testing.T) {
 	str := fileWithLineNum()
 	assert.Contains(t, str, "src/testing/testing.go:")
+
+	t.Run("deep stack", func(t *testing.T) {
+		// The caller (the sort package) is more than 15 frames away, like with database/sql and sqllog
+		var caller string
+		var recurse func(n int)
+		recurse = func(n int) {
+			if n == 0 {
+				caller = fileWithLineNum()
+				return
+			}
+			recurse(n - 1)
+		}
+		sort.Slice([]int{2, 1}, func(i, j int) bool {
+			recurse(20)
+			return i < j
+		})
+		assert.Contains(t, caller, "/sort/")
+	})
+}
+
+func TestIsCallerPackage(t *testing.T) {
+	assert.True(t, isCallerPackage("database/sql.(*DB).queryDC"))
+	assert.True(t, isCallerPackage("github.com/mrz1836/go-logger/sqllog.(*conn).QueryContext"))
+	assert.True(t, isCallerPackage("github.com/mrz1836/go-logger/gormlogger.(*Logger).Trace"))
+	assert.False(t, isCallerPackage("database/sql/driver.IsValue"))
+	assert.False(t, isCallerPackage("main.main"))
+}
+
+// traceMessages runs Trace on a logger and returns what was logged
+func traceMessages(ctx context.Context, l GormLoggerInterface, elapsed time.Duration, sql string, err error) []string {
+	oldImpl := GetImplementation()
+	defer SetImplementation(oldImpl)
+
+	testLogger := &testLoggerImpl{}
+	SetImplementation(testLogger)
+
+	l.Trace(ctx, time.Now().Add(-elapsed), func() (string, int64) { return sql, 1 }, err)
+	return testLogger.messages
+}
+
+func TestBasicLogger_SlowThreshold(t *testing.T) {
+	t.Run("default threshold", func(t *testing.T) {
+		l := NewGormLogger(false, 3)
+		assert.Empty(t, traceMessages(context.Background(), l, time.Second, "SELECT 1", nil))
+		assert.Len(t, traceMessages(context.Background(), l, SlowQueryThreshold+time.Second, "SELECT 1", nil), 1)
+	})
+
+	t.Run("custom threshold", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithSlowThreshold(100*time.Millisecond))
+		messages := traceMessages(context.Background(), l, 200*time.Millisecond, "SELECT 1", nil)
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], `slow_log="SLOW SQL >= 100ms"`)
+	})
+
+	t.Run("disabled threshold", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithSlowThreshold(0))
+		assert.Empty(t, traceMessages(context.Background(), l, time.Hour, "SELECT 1", nil))
+	})
+
+	t.Run("context override", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithSlowThreshold(100*time.Millisecond))
+		ctx := ContextWithSlowThreshold(context.Background(), time.Minute)
+		assert.Empty(t, traceMessages(ctx, l, 200*time.Millisecond, "SELECT report", nil))
+
+		messages := traceMessages(ctx, l, 2*time.Minute, "SELECT report", nil)
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], `slow_log="SLOW SQL >= 1m0s"`)
+	})
+}
+
+func TestBasicLogger_SQLRedactor(t *testing.T) {
+	const sql = `SELECT * FROM users WHERE email = 'jane@example.com' AND age > 30`
+	const redacted = `SELECT * FROM users WHERE email = ? AND age > ?`
+
+	l := NewGormLogger(true, 3, WithSQLRedactor(NewLiteralRedactor(RedactPlaceholder)), WithSlowThreshold(time.Second))
+
+	t.Run("info", func(t *testing.T) {
+		messages := traceMessages(context.Background(), l, time.Millisecond, sql, nil)
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], `sql="`+redacted+`"`)
+		assert.NotContains(t, messages[0], "jane@example.com")
+	})
+
+	t.Run("slow", func(t *testing.T) {
+		messages := traceMessages(context.Background(), l, time.Minute, sql, nil)
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], `sql="`+redacted+`"`)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		messages := traceMessages(context.Background(), l, time.Millisecond, sql, errors.New("connection reset"))
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], `sql="`+redacted+`"`)
+	})
+}
+
+func TestBasicLogger_QueryFingerprint(t *testing.T) {
+	l := NewGormLogger(true, 3)
+
+	first := traceMessages(context.Background(), l, time.Millisecond, `SELECT * FROM users WHERE id = 1`, nil)
+	second := traceMessages(context.Background(), l, time.Millisecond, `SELECT * FROM users WHERE id = 2`, nil)
+	require.Len(t, first, 1)
+	require.Len(t, second, 1)
+
+	fingerprint, _ := FingerprintSQL(`SELECT * FROM users WHERE id = 1`)
+	assert.Contains(t, first[0], `query_fingerprint="`+fingerprint+`"`)
+	assert.Contains(t, second[0], `query_fingerprint="`+fingerprint+`"`)
+	assert.Contains(t, first[0], `query_normalized="select * from users where id = ?"`)
+}
+
+func TestBasicLogger_IgnoredErrors(t *testing.T) {
+	errNotMine := errors.New("not mine")
+	const sql = "SELECT * FROM users WHERE id = 1"
+
+	t.Run("record not found by default", func(t *testing.T) {
+		l := NewGormLogger(false, 3)
+		assert.Empty(t, traceMessages(context.Background(), l, time.Millisecond, sql, errors.New("record not found")))
+		assert.Len(t, traceMessages(context.Background(), l, time.Millisecond, sql, errNotMine), 1)
+	})
+
+	t.Run("errors.Is", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithIgnoredErrors(context.Canceled, errNotMine))
+		assert.Empty(t, traceMessages(context.Background(), l, time.Millisecond, sql, context.Canceled))
+		assert.Empty(t, traceMessages(context.Background(), l, time.Millisecond, sql, fmt.Errorf("query: %w", errNotMine)))
+		assert.Empty(t, traceMessages(context.Background(), l, time.Millisecond, sql, errors.New("record not found")))
+		assert.Len(t, traceMessages(context.Background(), l, time.Millisecond, sql, context.DeadlineExceeded), 1)
+	})
+
+	t.Run("predicate", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithIgnoreErrorFunc(func(err error) bool {
+			return strings.HasPrefix(err.Error(), "duplicate")
+		}))
+		assert.Empty(t, traceMessages(context.Background(), l, time.Millisecond, sql, errors.New("duplicate key")))
+		assert.Len(t, traceMessages(context.Background(), l, time.Millisecond, sql, errors.New("record not found")), 1)
+	})
+
+	t.Run("no predicate", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithIgnoreErrorFunc(nil))
+		assert.Len(t, traceMessages(context.Background(), l, time.Millisecond, sql, errors.New("record not found")), 1)
+	})
+
+	t.Run("logged at a lower level", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithIgnoredErrors(context.Canceled), WithIgnoredErrorLevel(WARN))
+		messages := traceMessages(context.Background(), l, time.Millisecond, sql, context.Canceled)
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], `type="warn"`)
+		assert.Contains(t, messages[0], `message="ignored error executing query"`)
+		assert.Contains(t, messages[0], `error="context canceled"`)
+	})
+
+	t.Run("lower level hidden by the mode", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithIgnoredErrorLevel(DEBUG))
+		assert.Empty(t, traceMessages(context.Background(), l, time.Millisecond, sql, errors.New("record not found")))
+
+		messages := traceMessages(context.Background(), l.SetMode(Info), time.Millisecond, sql, errors.New("record not found"))
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], `type="debug"`)
+	})
+}
+
+func TestDisplayLog(t *testing.T) {
+	display := func(message string, params ...interface{}) string {
+		oldImpl := GetImplementation()
+		defer SetImplementation(oldImpl)
+
+		testLogger := &testLoggerImpl{}
+		SetImplementation(testLogger)
+		displayLog(INFO, 3, message, params...)
+		require.Len(t, testLogger.messages, 1)
+		return testLogger.messages[0]
+	}
+
+	tests := []struct {
+		name     string
+		message  string
+		params   []interface{}
+		expected string
+	}{
+		{"no params", "migrating", nil, `message="migrating"`},
+		{"unnamed params", "migrating", []interface{}{123, true}, `message="migrating" param_0="123" param_1="true"`},
+		{"key value pairs", "migrating", []interface{}{"table", "users", "version", 3}, `message="migrating" table="users" version="3"`},
+		{"KeyValue", "migrating", []interface{}{MakeParameter("table", "users"), Int("version", 3)}, `message="migrating" table="users" version=3`},
+		{"slog.Attr", "migrating", []interface{}{slog.String("table", "users"), slog.Group("db", slog.Int("port", 5432))}, `message="migrating" table="users" db.port="5432"`},
+		{"sentences are not keys", "migrating", []interface{}{"not a key", "users"}, `message="migrating" param_0="not a key" param_1="users"`},
+		{"trailing string", "migrating", []interface{}{"table", "users", "orphan"}, `message="migrating" table="users" param_2="orphan"`},
+		{"format verbs", "migrating %s to %d", []interface{}{"users", 3, "took", "2ms"}, `message="migrating users to 3" took="2ms"`},
+		{"escaped percent", "100%% done", []interface{}{"table", "users"}, `message="100% done" table="users"`},
+		{"literal percent", "50% done", nil, `message="50% done"`},
+		{"literal percent before a word", "50% done", []interface{}{"users", 3}, `message="50% done" users="3"`},
+		{"literal percent before a verb letter", "copied 100% of rows", []interface{}{5}, `message="copied 100% of rows" param_0="5"`},
+		{"percent before punctuation", "100%) done %s", []interface{}{"now"}, `message="100%) done %s" param_0="now"`},
+		{"format error", "50%done %s", []interface{}{"now"}, `message="50%done %s" param_0="now"`},
+		{"literal percent with params", "50% done", []interface{}{MakeParameter("table", "users"), Int("version", 3)}, `message="50% done" table="users" version=3`},
+		{"positional word is read as a key", "migrating", []interface{}{"users", 3}, `message="migrating" users="3"`},
+		{"positional values as KeyValue", "migrating", []interface{}{MakeParameter("param_0", "users"), 3}, `message="migrating" param_0="users" param_1="3"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Contains(t, display(test.message, test.params...), test.expected)
+		})
+	}
+}
+
+func TestCountFormatVerbs(t *testing.T) {
+	assert.Equal(t, 0, countFormatVerbs("no verbs"))
+	assert.Equal(t, 0, countFormatVerbs("100%% done"))
+	assert.Equal(t, 0, countFormatVerbs("trailing %"))
+	assert.Equal(t, 2, countFormatVerbs("%s took %v"))
+	assert.Equal(t, 2, countFormatVerbs("%-10s|%08.3f"))
+	assert.Equal(t, 3, countFormatVerbs("%*d and %v"))
+	assert.Equal(t, 0, countFormatVerbs("50% done"))
+	assert.Equal(t, 0, countFormatVerbs("copied 100% of rows"))
+	assert.Equal(t, 0, countFormatVerbs("100%) done"))
+	assert.Equal(t, 1, countFormatVerbs("% d rows"))
+}
+
+func TestBasicLogger_TraceFields(t *testing.T) {
+	t.Run("numeric fields", func(t *testing.T) {
+		l := NewGormLogger(true, 3)
+		messages := traceMessages(context.Background(), l, 12*time.Millisecond, "SELECT 1", nil)
+		require.Len(t, messages, 1)
+		assert.Regexp(t, `caller="[^"]+:\d+" duration_ms=12\.\d+ rows=1 sql="SELECT 1"`, messages[0])
+		assert.NotContains(t, messages[0], ` duration=`)
+
+		d, err := ParseDataLine(messages[0])
+		require.NoError(t, err)
+		rows, ok := d.Param("rows")
+		require.True(t, ok)
+		assert.Equal(t, "1", rows)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		defer SetEncoder(&LogfmtEncoder{})
+		SetEncoder(&JSONEncoder{})
+
+		l := NewGormLogger(true, 3)
+		messages := traceMessages(context.Background(), l, 12*time.Millisecond, "SELECT 1", nil)
+		require.Len(t, messages, 1)
+		assert.Regexp(t, `"duration_ms":12\.\d+,"rows":1,"sql":"SELECT 1"`, messages[0])
+	})
+
+	t.Run("rows threshold", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithRowsThreshold(1))
+		assert.Empty(t, traceMessages(context.Background(), l, time.Millisecond, "SELECT 1", nil))
+
+		oldImpl := GetImplementation()
+		defer SetImplementation(oldImpl)
+		testLogger := &testLoggerImpl{}
+		SetImplementation(testLogger)
+
+		l.Trace(context.Background(), time.Now(), func() (string, int64) { return "SELECT * FROM users", 5000 }, nil)
+		require.Len(t, testLogger.messages, 1)
+		assert.Contains(t, testLogger.messages[0], `type="warn"`)
+		assert.Contains(t, testLogger.messages[0], `rows_threshold=1 duration_ms=`)
+		assert.Contains(t, testLogger.messages[0], `rows=5000`)
+	})
+
+	t.Run("slow and rows threshold", func(t *testing.T) {
+		oldImpl := GetImplementation()
+		defer SetImplementation(oldImpl)
+		testLogger := &testLoggerImpl{}
+		SetImplementation(testLogger)
+
+		l := NewGormLogger(false, 3, WithRowsThreshold(1), WithSlowThreshold(time.Millisecond))
+		l.Trace(context.Background(), time.Now().Add(-time.Second), func() (string, int64) { return "SELECT * FROM users", 5000 }, nil)
+		require.Len(t, testLogger.messages, 1)
+		assert.Contains(t, testLogger.messages[0], `slow_log="SLOW SQL >= 1ms" rows_threshold=1`)
+	})
 }
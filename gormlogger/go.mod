@@ -0,0 +1,18 @@
+module github.com/mrz1836/go-logger/gormlogger
+
+go 1.21
+
+require (
+	github.com/mrz1836/go-logger v0.2.5
+	github.com/stretchr/testify v1.12.0
+	gorm.io/gorm v1.31.1
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	golang.org/x/text v0.20.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/mrz1836/go-logger => ../
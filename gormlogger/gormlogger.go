@@ -0,0 +1,89 @@
+/*
+Package gormlogger adapts the go-logger GORM logger to gorm.io/gorm/logger.Interface
+
+It lives in its own module so the core go-logger package does not depend on GORM:
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: gormlogger.New(false, 3, logger.WithSlowThreshold(time.Second)),
+	})
+*/
+package gormlogger
+
+import (
+	"context"
+	"time"
+
+	"github.com/mrz1836/go-logger"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// Logger implements gorm.io/gorm/logger.Interface (and gorm.ParamsFilter)
+type Logger struct {
+	base                 logger.GormLoggerInterface
+	ParameterizedQueries bool // Log the SQL without its params (see ParamsFilter)
+}
+
+// Interfaces implemented by the Logger
+var (
+	_ gormLogger.Interface = (*Logger)(nil)
+	_ gorm.ParamsFilter    = (*Logger)(nil)
+)
+
+// New creates a GORM logger (see logger.NewGormLogger), ignoring gorm.ErrRecordNotFound
+func New(debugging bool, stackLevel int, opts ...logger.GormOption) *Logger {
+	opts = append([]logger.GormOption{logger.WithIgnoredErrors(gorm.ErrRecordNotFound)}, opts...)
+
+	// One more frame: GORM calls this adapter, which calls the base logger
+	return &Logger{base: logger.NewGormLogger(debugging, stackLevel+1, opts...)}
+}
+
+// LogMode will set the log level
+func (l *Logger) LogMode(level gormLogger.LogLevel) gormLogger.Interface {
+	newLogger := *l
+	newLogger.base = l.base.SetMode(fromGormLevel(level))
+	return &newLogger
+}
+
+// Info print information
+func (l *Logger) Info(ctx context.Context, message string, params ...interface{}) {
+	l.base.Info(ctx, message, params...)
+}
+
+// Warn print warn messages
+func (l *Logger) Warn(ctx context.Context, message string, params ...interface{}) {
+	l.base.Warn(ctx, message, params...)
+}
+
+// Error print error messages
+func (l *Logger) Error(ctx context.Context, message string, params ...interface{}) {
+	l.base.Error(ctx, message, params...)
+}
+
+// Trace is for GORM/SQL tracing from datastore
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	l.base.Trace(ctx, begin, fc, err)
+}
+
+// ParamsFilter removes the params from the logged SQL when ParameterizedQueries is set
+func (l *Logger) ParamsFilter(_ context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if l.ParameterizedQueries {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// fromGormLevel converts a GORM log level
+func fromGormLevel(level gormLogger.LogLevel) logger.GormLogLevel {
+	switch level {
+	case gormLogger.Silent:
+		return logger.Silent
+	case gormLogger.Error:
+		return logger.Error
+	case gormLogger.Warn:
+		return logger.Warn
+	case gormLogger.Info:
+		return logger.Info
+	}
+	return logger.Warn
+}
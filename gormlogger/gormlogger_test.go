@@ -0,0 +1,178 @@
+package gormlogger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mrz1836/go-logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	gormLogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// captureLogger records the lines written by go-logger
+type captureLogger struct {
+	lines []string
+}
+
+func (c *captureLogger) Fatal(v ...interface{})                 { c.Print(v...) }
+func (c *captureLogger) Fatalf(format string, v ...interface{}) { c.Printf(format, v...) }
+func (c *captureLogger) Fatalln(v ...interface{})               { c.Print(v...) }
+func (c *captureLogger) Panic(v ...interface{})                 { c.Print(v...) }
+func (c *captureLogger) Panicf(format string, v ...interface{}) { c.Printf(format, v...) }
+func (c *captureLogger) Panicln(v ...interface{})               { c.Print(v...) }
+func (c *captureLogger) Print(v ...interface{})                 { c.lines = append(c.lines, fmt.Sprint(v...)) }
+func (c *captureLogger) Printf(format string, v ...interface{}) {
+	c.lines = append(c.lines, fmt.Sprintf(format, v...))
+}
+func (c *captureLogger) Println(v ...interface{}) { c.Print(v...) }
+
+// capture swaps the go-logger implementation for the duration of a test
+func capture(t *testing.T) *captureLogger {
+	old := logger.GetImplementation()
+	t.Cleanup(func() { logger.SetImplementation(old) })
+
+	c := &captureLogger{}
+	logger.SetImplementation(c)
+	return c
+}
+
+// dryRunDialector is a minimal dialector used with DryRun, so no database is needed
+type dryRunDialector struct{}
+
+func (dryRunDialector) Name() string { return "dryrun" }
+
+func (dryRunDialector) Initialize(db *gorm.DB) error {
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	return nil
+}
+
+func (dryRunDialector) Migrator(*gorm.DB) gorm.Migrator { return nil }
+
+func (dryRunDialector) DataTypeOf(*schema.Field) string { return "" }
+
+func (dryRunDialector) DefaultValueOf(*schema.Field) clause.Expression { return nil }
+
+func (dryRunDialector) BindVarTo(writer clause.Writer, _ *gorm.Statement, _ interface{}) {
+	_ = writer.WriteByte('?')
+}
+
+func (dryRunDialector) QuoteTo(writer clause.Writer, str string) {
+	_ = writer.WriteByte('`')
+	_, _ = writer.WriteString(str)
+	_ = writer.WriteByte('`')
+}
+
+func (dryRunDialector) Explain(sql string, vars ...interface{}) string {
+	return gormLogger.ExplainSQL(sql, nil, `'`, vars...)
+}
+
+// user is a test model
+type user struct {
+	ID    uint
+	Email string
+}
+
+// TestNew will test creating the logger
+func TestNew(t *testing.T) {
+	l := New(true, 3)
+	require.NotNil(t, l)
+	assert.Equal(t, logger.Info, l.base.GetMode())
+	assert.Equal(t, 4, l.base.GetStackLevel())
+}
+
+// TestLogger_LogMode will test changing the log level
+func TestLogger_LogMode(t *testing.T) {
+	l := New(false, 3)
+
+	tests := []struct {
+		level    gormLogger.LogLevel
+		expected logger.GormLogLevel
+	}{
+		{gormLogger.Silent, logger.Silent},
+		{gormLogger.Error, logger.Error},
+		{gormLogger.Warn, logger.Warn},
+		{gormLogger.Info, logger.Info},
+	}
+	for _, test := range tests {
+		changed, ok := l.LogMode(test.level).(*Logger)
+		require.True(t, ok)
+		assert.Equal(t, test.expected, changed.base.GetMode())
+	}
+
+	// The original logger is unchanged
+	assert.Equal(t, logger.Warn, l.base.GetMode())
+}
+
+// TestLogger_Messages will test the Info, Warn and Error methods
+func TestLogger_Messages(t *testing.T) {
+	c := capture(t)
+	l := New(true, 3)
+	l.Info(context.Background(), "migrating %s", "users")
+	l.Warn(context.Background(), "slow migration", "table", "users")
+	l.Error(context.Background(), "failed")
+
+	require.Len(t, c.lines, 3)
+	assert.Contains(t, c.lines[0], `type="info"`)
+	assert.Contains(t, c.lines[0], `message="migrating users"`)
+	assert.Contains(t, c.lines[1], `message="slow migration" table="users"`)
+	assert.Contains(t, c.lines[2], `type="error"`)
+}
+
+// TestLogger_Trace will test tracing queries run by GORM
+func TestLogger_Trace(t *testing.T) {
+	c := capture(t)
+
+	db, err := gorm.Open(dryRunDialector{}, &gorm.Config{DryRun: true, Logger: New(true, 3)})
+	require.NoError(t, err)
+
+	var users []user
+	require.NoError(t, db.Where("email = ?", "jane@example.com").Find(&users).Error)
+
+	require.Len(t, c.lines, 1)
+	assert.Contains(t, c.lines[0], `message="executing sql query"`)
+	assert.Contains(t, c.lines[0], "SELECT * FROM `users` WHERE email = 'jane@example.com'")
+}
+
+// TestLogger_ParamsFilter will test logging parameterized queries
+func TestLogger_ParamsFilter(t *testing.T) {
+	l := New(true, 3)
+	sql, params := l.ParamsFilter(context.Background(), "SELECT ?", 1)
+	assert.Equal(t, "SELECT ?", sql)
+	assert.Equal(t, []interface{}{1}, params)
+
+	l.ParameterizedQueries = true
+	sql, params = l.ParamsFilter(context.Background(), "SELECT ?", 1)
+	assert.Equal(t, "SELECT ?", sql)
+	assert.Nil(t, params)
+
+	c := capture(t)
+	db, err := gorm.Open(dryRunDialector{}, &gorm.Config{DryRun: true, Logger: l})
+	require.NoError(t, err)
+
+	var users []user
+	require.NoError(t, db.Where("email = ?", "jane@example.com").Find(&users).Error)
+	require.Len(t, c.lines, 1)
+	assert.Contains(t, c.lines[0], "SELECT * FROM `users` WHERE email = ?")
+}
+
+// TestLogger_RecordNotFound will test that gorm.ErrRecordNotFound is not an error
+func TestLogger_RecordNotFound(t *testing.T) {
+	c := capture(t)
+	l := New(false, 3)
+
+	sql := func() (string, int64) { return "SELECT 1", 0 }
+	l.Trace(context.Background(), time.Now(), sql, fmt.Errorf("find: %w", gorm.ErrRecordNotFound))
+	assert.Empty(t, c.lines)
+
+	l.Trace(context.Background(), time.Now(), sql, errors.New("connection reset"))
+	require.Len(t, c.lines, 1)
+	assert.Contains(t, c.lines[0], `error="connection reset"`)
+}
@@ -0,0 +1,455 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HTTP log client defaults
+const (
+	DefaultHTTPBatchSize     = 100              // Lines per request
+	DefaultHTTPFlushInterval = time.Second      // Maximum time a line waits for its batch
+	DefaultHTTPMaxRetries    = 10               // Retries of a batch before it is dropped
+	DefaultHTTPTimeout       = 10 * time.Second // Timeout of a request
+
+	contentTypeNDJSON = "application/x-ndjson"
+	contentTypeText   = "text/plain"
+
+	exitTimeout = 5 * time.Second // Time the Fatal and Panic methods wait for the queue to be sent
+)
+
+// ErrHTTPStatus is returned when an HTTP endpoint rejects a request
+var ErrHTTPStatus = errors.New("unexpected http status")
+
+// HTTPOption is an optional setting for NewHTTPLogClient
+type HTTPOption func(c *HTTPLogClient)
+
+// WithBatchSize sets the maximum number of lines per request
+func WithBatchSize(size int) HTTPOption {
+	return func(c *HTTPLogClient) {
+		c.batchSize = size
+	}
+}
+
+// WithFlushInterval sets the maximum time a line waits for its batch to fill up
+func WithFlushInterval(interval time.Duration) HTTPOption {
+	return func(c *HTTPLogClient) {
+		c.flushInterval = interval
+	}
+}
+
+// WithGzip compresses the requests (Content-Encoding: gzip)
+func WithGzip() HTTPOption {
+	return func(c *HTTPLogClient) {
+		c.sender.compress = true
+	}
+}
+
+// WithHeader adds a header to the requests (IE: Authorization)
+func WithHeader(key, value string) HTTPOption {
+	return func(c *HTTPLogClient) {
+		c.sender.headers.Add(key, value)
+	}
+}
+
+// WithHTTPClient sets the client used for the requests
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(c *HTTPLogClient) {
+		c.sender.client = client
+	}
+}
+
+// WithHTTPEncoder sets the encoder for Data() and NoFileData() (nil uses the global encoder)
+func WithHTTPEncoder(enc Encoder, contentType string) HTTPOption {
+	return func(c *HTTPLogClient) {
+		c.encoder = enc
+		c.sender.contentType = contentType
+	}
+}
+
+// WithMaxRetries sets the number of retries of a batch before it is dropped
+func WithMaxRetries(retries int) HTTPOption {
+	return func(c *HTTPLogClient) {
+		c.maxRetries = retries
+	}
+}
+
+// HTTPLogClient is a Logger posting batches of lines to an HTTP ingestion endpoint, for
+// networks where the Log Entries TCP port is blocked
+type HTTPLogClient struct {
+	batchSize     int
+	closed        bool
+	done          chan struct{}
+	encoder       Encoder
+	exiting       atomic.Bool // Set by Fatal and Panic, the queued batches are only sent once
+	flushInterval time.Duration
+	maxRetries    int
+	messages      msgQueue
+	mu            sync.RWMutex // Guards closed
+	sender        httpSender
+	started       atomic.Bool // Set by the first ProcessQueue call
+}
+
+// NewHTTPLogClient creates a client posting newline delimited JSON to url,
+// start sending with go client.ProcessQueue()
+func NewHTTPLogClient(url string, opts ...HTTPOption) *HTTPLogClient {
+	c := &HTTPLogClient{
+		batchSize:     DefaultHTTPBatchSize,
+		done:          make(chan struct{}),
+		encoder:       &JSONEncoder{},
+		flushInterval: DefaultHTTPFlushInterval,
+		maxRetries:    DefaultHTTPMaxRetries,
+		sender:        newHTTPSender(url, contentTypeNDJSON),
+	}
+	c.messages.messagesToSend = make(chan *bytes.Buffer, 1000)
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.batchSize <= 0 {
+		c.batchSize = DefaultHTTPBatchSize
+	}
+	if c.flushInterval <= 0 {
+		c.flushInterval = DefaultHTTPFlushInterval
+	}
+	return c
+}
+
+// NewLogEntriesHTTPClient creates a client posting plain lines to the Log Entries webhook
+// (see LogEntriesWebhookURL), start sending with go client.ProcessQueue()
+func NewLogEntriesHTTPClient(token string, opts ...HTTPOption) *HTTPLogClient {
+	return NewHTTPLogClient(
+		LogEntriesWebhookURL+token,
+		append([]HTTPOption{WithHTTPEncoder(nil, contentTypeText)}, opts...)...,
+	)
+}
+
+// ProcessQueue sends the queued lines in batches, until Close is called
+// (the calls after the first one return right away)
+func (c *HTTPLogClient) ProcessQueue() {
+	if !c.started.CompareAndSwap(false, true) {
+		return
+	}
+	defer close(c.done)
+	processBatches(c.messages.messagesToSend, c.batchSize, c.flushInterval, c.flush)
+}
+
+// processBatches reads the queue until it is closed, flushing a batch when it is full or
+// when the interval elapsed (flush is called with an empty batch when nothing was queued)
+func processBatches[T any](queue <-chan T, batchSize int, interval time.Duration, flush func([]T)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	batch := make([]T, 0, batchSize)
+	for {
+		select {
+		case msg, ok := <-queue:
+			if !ok {
+				flush(batch)
+				return
+			}
+			if batch = append(batch, msg); len(batch) >= batchSize {
+				flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// closeWithin calls closeFn, waiting at most timeout for it to return (used by the Fatal and
+// Panic methods, so an unreachable endpoint doesn't keep the process from exiting)
+func closeWithin(closeFn func(), timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		closeFn()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// Close stops accepting lines and waits for the queued ones to be sent (by ProcessQueue, or
+// right away when it was never started)
+func (c *HTTPLogClient) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.messages.messagesToSend)
+	c.mu.Unlock()
+
+	c.ProcessQueue() // Sends the queued lines when ProcessQueue was never started
+	<-c.done
+}
+
+// flush sends a batch (dropping it if every retry failed)
+func (c *HTTPLogClient) flush(batch []*bytes.Buffer) {
+	if len(batch) == 0 {
+		return
+	}
+	var body bytes.Buffer
+	for _, msg := range batch {
+		body.Write(msg.Bytes())
+	}
+	maxRetries := c.maxRetries
+	if c.exiting.Load() {
+		maxRetries = 0
+	}
+	if err := c.sender.sendWithRetry(body.Bytes(), maxRetries); err != nil {
+		log.Println("go-logger: failed to send", len(batch), "lines to http endpoint:", err) //nolint:gosec // G706: error originates from net/http
+	}
+}
+
+// LogEntry implements the EntryLogger interface
+func (c *HTTPLogClient) LogEntry(e *Entry) {
+	enc := c.encoder
+	if enc == nil {
+		enc = encoder
+	}
+	var buff bytes.Buffer
+	enc.Encode(&buff, e)
+	c.enqueue(&buff)
+}
+
+// Panic overloads built-in method
+func (c *HTTPLogClient) Panic(v ...interface{}) {
+	c.exit(fmt.Sprint(v...))
+}
+
+// Panicln overloads built-in method
+func (c *HTTPLogClient) Panicln(v ...interface{}) {
+	c.exit(fmt.Sprintln(v...))
+}
+
+// Panicf overloads built-in method
+func (c *HTTPLogClient) Panicf(format string, v ...interface{}) {
+	c.exit(fmt.Sprintf(format, v...))
+}
+
+// Print overloads built-in method
+func (c *HTTPLogClient) Print(v ...interface{}) {
+	c.write(fmt.Sprint(v...))
+}
+
+// Println overloads built-in method
+func (c *HTTPLogClient) Println(v ...interface{}) {
+	c.write(fmt.Sprintln(v...))
+}
+
+// Printf overloads built-in method
+func (c *HTTPLogClient) Printf(format string, v ...interface{}) {
+	c.write(fmt.Sprintf(format, v...))
+}
+
+// Fatal overloads built-in method
+func (c *HTTPLogClient) Fatal(v ...interface{}) {
+	c.exit(fmt.Sprint(v...))
+}
+
+// Fatalln overloads built-in method
+func (c *HTTPLogClient) Fatalln(v ...interface{}) {
+	c.exit(fmt.Sprintln(v...))
+}
+
+// Fatalf overloads built-in method
+func (c *HTTPLogClient) Fatalf(format string, v ...interface{}) {
+	c.exit(fmt.Sprintf(format, v...))
+}
+
+// write will write the data to the queue
+func (c *HTTPLogClient) write(data string) {
+	var buff bytes.Buffer
+	buff.WriteString(data)
+	c.enqueue(&buff)
+}
+
+// enqueue terminates the line and queues it (or prints it with the log package once closed)
+func (c *HTTPLogClient) enqueue(buff *bytes.Buffer) {
+	if !bytes.HasSuffix(buff.Bytes(), []byte("\n")) {
+		buff.WriteByte('\n')
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		log.Print(buff.String())
+		return
+	}
+	c.messages.Enqueue(buff)
+}
+
+// exit sends the queued lines and then the line, before exiting (used by Fatal and Panic)
+//
+// The lines are sent without retrying, and the queue is given up after exitTimeout
+func (c *HTTPLogClient) exit(line string) {
+	c.exiting.Store(true)
+	if !closeWithin(c.Close, exitTimeout) {
+		log.Println("go-logger: timed out sending the queued lines to http endpoint")
+	}
+	c.sendOne(line)
+	os.Exit(1)
+}
+
+// sendOne sends one line right away, bypassing the queue (used before exiting)
+func (c *HTTPLogClient) sendOne(data string) {
+	if !strings.HasSuffix(data, "\n") {
+		data += "\n"
+	}
+	if err := c.sender.sendWithRetry([]byte(data), 0); err != nil {
+		log.Print(data)
+		log.Println("go-logger: failed to send to http endpoint:", err) //nolint:gosec // G706: error originates from net/http
+	}
+}
+
+// httpStatusError is an ErrHTTPStatus for a response code
+type httpStatusError struct {
+	code       int
+	retryAfter time.Duration // From the Retry-After header (zero if missing)
+}
+
+// Error implements the error interface
+func (e *httpStatusError) Error() string {
+	return ErrHTTPStatus.Error() + ": " + strconv.Itoa(e.code)
+}
+
+// Unwrap returns ErrHTTPStatus
+func (e *httpStatusError) Unwrap() error {
+	return ErrHTTPStatus
+}
+
+// httpSender posts payloads to an HTTP endpoint, retrying with backoff
+type httpSender struct {
+	client      *http.Client
+	compress    bool
+	contentType string
+	headers     http.Header
+	retryDelay  time.Duration // First delay between retries, doubled up to MaxRetryDelay
+	sleep       func(time.Duration)
+	url         string
+}
+
+// newHTTPSender creates a sender with the default client and delays
+func newHTTPSender(url, contentType string) httpSender {
+	return httpSender{
+		client:      &http.Client{Timeout: DefaultHTTPTimeout},
+		contentType: contentType,
+		headers:     make(http.Header),
+		retryDelay:  RetryDelay,
+		sleep:       time.Sleep,
+		url:         url,
+	}
+}
+
+// sendWithRetry posts the body, retrying network errors, 429 and 5xx responses
+func (s *httpSender) sendWithRetry(body []byte, maxRetries int) error {
+	if s.compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(body)
+		_ = zw.Close()
+		body = buf.Bytes()
+	}
+
+	delay := s.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := s.send(body)
+		if err == nil || attempt >= maxRetries {
+			return err
+		}
+
+		wait := delay
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) {
+			if statusErr.code != http.StatusTooManyRequests && statusErr.code < http.StatusInternalServerError {
+				return err // The request will never be accepted
+			}
+			if statusErr.retryAfter > 0 {
+				wait = statusErr.retryAfter
+			}
+		}
+		s.sleep(wait)
+
+		if delay *= 2; delay > MaxRetryDelay {
+			delay = MaxRetryDelay
+		}
+	}
+}
+
+// send posts an (already compressed) body once
+func (s *httpSender) send(body []byte) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range s.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", s.contentType)
+	if s.compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := s.client.Do(req) //nolint:gosec // G107: the url is configured by the application
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096)) // Allows reusing the connection
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return nil
+	}
+	return &httpStatusError{
+		code:       resp.StatusCode,
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter returns the delay of a Retry-After header (seconds or an HTTP date),
+// capped to MaxRetryDelay
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return 0
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, dateErr := http.ParseTime(value); dateErr == nil {
+		delay = date.Sub(now)
+	}
+
+	switch {
+	case delay < 0:
+		return 0
+	case delay > MaxRetryDelay:
+		return MaxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,384 @@
+package logger
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testHTTPRequest is a request received by a testHTTPServer
+type testHTTPRequest struct {
+	body   string
+	header http.Header
+}
+
+// testHTTPServer records the requests and answers with the given status codes (200 once exhausted)
+type testHTTPServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []testHTTPRequest
+	statuses []int
+}
+
+// newTestHTTPServer creates and starts a testHTTPServer
+func newTestHTTPServer(t *testing.T, statuses ...int) *testHTTPServer {
+	t.Helper()
+	s := &testHTTPServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reader io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if !assert.NoError(t, err) {
+				return
+			}
+			reader = zr
+		}
+		body, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, testHTTPRequest{body: string(body), header: r.Header})
+		if len(s.statuses) > 0 {
+			if s.statuses[0] == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "3")
+			}
+			w.WriteHeader(s.statuses[0])
+			s.statuses = s.statuses[1:]
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// bodies returns the bodies of the received requests
+func (s *testHTTPServer) bodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bodies := make([]string, 0, len(s.requests))
+	for _, r := range s.requests {
+		bodies = append(bodies, r.body)
+	}
+	return bodies
+}
+
+// newTestHTTPLogClient creates a client for a test server, recording the retry delays
+func newTestHTTPLogClient(url string, delays *[]time.Duration, opts ...HTTPOption) *HTTPLogClient {
+	c := NewHTTPLogClient(url, opts...)
+	c.sender.sleep = func(d time.Duration) { *delays = append(*delays, d) }
+	return c
+}
+
+// TestNewHTTPLogClient will test the NewHTTPLogClient() method
+func TestNewHTTPLogClient(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		c := NewHTTPLogClient("http://localhost/logs")
+		assert.Equal(t, DefaultHTTPBatchSize, c.batchSize)
+		assert.Equal(t, DefaultHTTPFlushInterval, c.flushInterval)
+		assert.Equal(t, DefaultHTTPMaxRetries, c.maxRetries)
+		assert.IsType(t, &JSONEncoder{}, c.encoder)
+		assert.Equal(t, contentTypeNDJSON, c.sender.contentType)
+		assert.Equal(t, "http://localhost/logs", c.sender.url)
+		assert.Equal(t, RetryDelay, c.sender.retryDelay)
+		assert.Equal(t, 1000, cap(c.messages.messagesToSend))
+	})
+
+	t.Run("options", func(t *testing.T) {
+		client := &http.Client{}
+		c := NewHTTPLogClient(
+			"http://localhost/logs",
+			WithBatchSize(5),
+			WithFlushInterval(time.Minute),
+			WithGzip(),
+			WithHeader("Authorization", "Bearer token"),
+			WithHTTPClient(client),
+			WithHTTPEncoder(&LogfmtEncoder{}, "text/plain"),
+			WithMaxRetries(2),
+		)
+		assert.Equal(t, 5, c.batchSize)
+		assert.Equal(t, time.Minute, c.flushInterval)
+		assert.True(t, c.sender.compress)
+		assert.Equal(t, "Bearer token", c.sender.headers.Get("Authorization"))
+		assert.Equal(t, client, c.sender.client)
+		assert.IsType(t, &LogfmtEncoder{}, c.encoder)
+		assert.Equal(t, "text/plain", c.sender.contentType)
+		assert.Equal(t, 2, c.maxRetries)
+	})
+
+	t.Run("invalid batch and interval", func(t *testing.T) {
+		c := NewHTTPLogClient("http://localhost/logs", WithBatchSize(0), WithFlushInterval(-time.Second))
+		assert.Equal(t, DefaultHTTPBatchSize, c.batchSize)
+		assert.Equal(t, DefaultHTTPFlushInterval, c.flushInterval)
+	})
+}
+
+// TestNewLogEntriesHTTPClient will test the NewLogEntriesHTTPClient() method
+func TestNewLogEntriesHTTPClient(t *testing.T) {
+	c := NewLogEntriesHTTPClient(testToken, WithBatchSize(1))
+	assert.Equal(t, LogEntriesWebhookURL+testToken, c.sender.url)
+	assert.Equal(t, contentTypeText, c.sender.contentType)
+	assert.Nil(t, c.encoder)
+	assert.Equal(t, 1, c.batchSize)
+}
+
+// TestHTTPLogClient_ProcessQueue will test sending batches
+func TestHTTPLogClient_ProcessQueue(t *testing.T) {
+	t.Run("batches by size and flushes on close", func(t *testing.T) {
+		server := newTestHTTPServer(t)
+		var delays []time.Duration
+		c := newTestHTTPLogClient(server.URL, &delays, WithBatchSize(2), WithFlushInterval(time.Hour))
+		go c.ProcessQueue()
+
+		c.Print("one")
+		c.Println("two")
+		c.Printf("three %d", 3)
+		c.Close()
+
+		assert.Equal(t, []string{"one\ntwo\n", "three 3\n"}, server.bodies())
+		assert.Equal(t, contentTypeNDJSON, server.requests[0].header.Get("Content-Type"))
+		assert.Empty(t, delays)
+	})
+
+	t.Run("flushes on the interval", func(t *testing.T) {
+		server := newTestHTTPServer(t)
+		var delays []time.Duration
+		c := newTestHTTPLogClient(server.URL, &delays, WithFlushInterval(10*time.Millisecond))
+		go c.ProcessQueue()
+		defer c.Close()
+
+		c.Println("waiting")
+		assert.Eventually(t, func() bool { return len(server.bodies()) == 1 }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, []string{"waiting\n"}, server.bodies())
+	})
+
+	t.Run("gzip and headers", func(t *testing.T) {
+		server := newTestHTTPServer(t)
+		var delays []time.Duration
+		c := newTestHTTPLogClient(server.URL, &delays, WithGzip(), WithHeader("X-Api-Key", "secret"))
+		go c.ProcessQueue()
+
+		c.Println("compressed")
+		c.Close()
+
+		require.Len(t, server.requests, 1)
+		assert.Equal(t, "compressed\n", server.requests[0].body)
+		assert.Equal(t, "gzip", server.requests[0].header.Get("Content-Encoding"))
+		assert.Equal(t, "secret", server.requests[0].header.Get("X-Api-Key"))
+	})
+
+	t.Run("entries as ndjson", func(t *testing.T) {
+		server := newTestHTTPServer(t)
+		var delays []time.Duration
+		c := newTestHTTPLogClient(server.URL, &delays)
+		go c.ProcessQueue()
+
+		previous := GetImplementation()
+		SetImplementation(c)
+		NoFileData(WARN, "entry", Int("n", 1))
+		SetImplementation(previous)
+		c.Close()
+
+		require.Len(t, server.bodies(), 1)
+		assert.True(t, strings.HasSuffix(server.bodies()[0], `"level":"warn","message":"entry","n":1}`+"\n"))
+	})
+
+	t.Run("lines after close", func(t *testing.T) {
+		server := newTestHTTPServer(t)
+		var delays []time.Duration
+		c := newTestHTTPLogClient(server.URL, &delays)
+		go c.ProcessQueue()
+		c.Close()
+		c.Close()
+
+		c.Println("closed")
+		assert.Empty(t, server.bodies())
+	})
+
+	t.Run("close without process queue", func(t *testing.T) {
+		server := newTestHTTPServer(t)
+		var delays []time.Duration
+		c := newTestHTTPLogClient(server.URL, &delays)
+		c.Println("queued")
+		c.Close()
+		go c.ProcessQueue() // Returns right away
+
+		assert.Equal(t, []string{"queued\n"}, server.bodies())
+	})
+}
+
+// TestHTTPLogClient_Retry will test retrying failed batches
+func TestHTTPLogClient_Retry(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		delays   []time.Duration
+		requests int
+	}{
+		{"server errors with backoff", []int{500, 503}, []time.Duration{RetryDelay, 2 * RetryDelay}, 3},
+		{"too many requests with retry after", []int{429}, []time.Duration{3 * time.Second}, 2},
+		{"client errors are not retried", []int{400}, nil, 1},
+		{"retries are limited", []int{500, 500, 500, 500}, []time.Duration{RetryDelay, 2 * RetryDelay}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestHTTPServer(t, tt.statuses...)
+			var delays []time.Duration
+			c := newTestHTTPLogClient(server.URL, &delays, WithMaxRetries(2))
+			go c.ProcessQueue()
+
+			c.Println("retry")
+			c.Close()
+
+			assert.Equal(t, tt.delays, delays)
+			assert.Len(t, server.bodies(), tt.requests)
+		})
+	}
+
+	t.Run("network errors", func(t *testing.T) {
+		server := newTestHTTPServer(t)
+		server.Close()
+
+		var delays []time.Duration
+		c := newTestHTTPLogClient(server.URL, &delays, WithMaxRetries(1))
+		go c.ProcessQueue()
+
+		c.Println("unreachable")
+		c.Close()
+		assert.Equal(t, []time.Duration{RetryDelay}, delays)
+	})
+}
+
+// TestHTTPStatusError will test the httpStatusError type
+func TestHTTPStatusError(t *testing.T) {
+	err := error(&httpStatusError{code: http.StatusBadGateway})
+	assert.Equal(t, "unexpected http status: 502", err.Error())
+	assert.ErrorIs(t, err, ErrHTTPStatus)
+}
+
+// TestParseRetryAfter will test the parseRetryAfter() method
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{"empty", "", 0},
+		{"seconds", "120", 2 * time.Minute},
+		{"spaces", " 5 ", 5 * time.Second},
+		{"negative", "-1", 0},
+		{"capped", "3600", MaxRetryDelay},
+		{"date", "Tue, 02 Jan 2024 03:04:35 GMT", 30 * time.Second},
+		{"past date", "Tue, 02 Jan 2024 03:00:00 GMT", 0},
+		{"invalid", "soon", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseRetryAfter(tt.value, now))
+		})
+	}
+}
+
+// TestHTTPLogClient_Panic will test the Panic() method
+func TestHTTPLogClient_Panic(t *testing.T) {
+	if url := os.Getenv("EXIT_FUNCTION_URL"); len(url) > 0 {
+		c := NewHTTPLogClient(url)
+		c.Println("queued")
+		c.Panicf("panicf %d", 1)
+		return
+	}
+	server := newTestHTTPServer(t)
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestHTTPLogClient_Panic") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION_URL="+server.URL)
+	err := cmd.Run()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		assert.Equal(t, []string{"queued\n", "panicf 1\n"}, server.bodies())
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestHTTPLogClient_Fatal will test the Fatal() method
+func TestHTTPLogClient_Fatal(t *testing.T) {
+	if url := os.Getenv("EXIT_FUNCTION_URL"); len(url) > 0 {
+		c := NewHTTPLogClient(url, WithFlushInterval(time.Hour))
+		go c.ProcessQueue()
+		c.Println("queued")
+		c.Fatalf("fatal %d", 1)
+		return
+	}
+	server := newTestHTTPServer(t)
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestHTTPLogClient_Fatal") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION_URL="+server.URL)
+	err := cmd.Run()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		assert.Equal(t, []string{"queued\n", "fatal 1\n"}, server.bodies())
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestHTTPLogClient_FatalFailingEndpoint will test that Fatal() doesn't retry when the endpoint fails
+func TestHTTPLogClient_FatalFailingEndpoint(t *testing.T) {
+	if url := os.Getenv("EXIT_FUNCTION_URL"); len(url) > 0 {
+		c := NewHTTPLogClient(url, WithFlushInterval(time.Hour))
+		go c.ProcessQueue()
+		c.Println("queued")
+		c.Fatalf("fatal %d", 1)
+		return
+	}
+	server := newTestHTTPServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable,
+		http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	start := time.Now()
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestHTTPLogClient_FatalFailingEndpoint") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION_URL="+server.URL)
+	err := cmd.Run()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		assert.Less(t, time.Since(start), exitTimeout)
+		assert.Equal(t, []string{"queued\n", "fatal 1\n"}, server.bodies())
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestCloseWithin will test the closeWithin() method
+func TestCloseWithin(t *testing.T) {
+	assert.True(t, closeWithin(func() {}, time.Second))
+
+	release := make(chan struct{})
+	defer close(release)
+	assert.False(t, closeWithin(func() { <-release }, 10*time.Millisecond))
+}
+
+// BenchmarkHTTPLogClient_Println benchmarks the Println() method (queueing only)
+func BenchmarkHTTPLogClient_Println(b *testing.B) {
+	c := NewHTTPLogClient("http://localhost/logs")
+	go func() {
+		for range c.messages.messagesToSend { //nolint:revive // draining the queue
+		}
+	}()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Println("benchmark line")
+	}
+}
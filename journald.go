@@ -0,0 +1,192 @@
+package logger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Journald defaults
+const (
+	JournaldSocket = "/run/systemd/journal/socket" // Native protocol socket of systemd-journald
+
+	journaldMaxFieldName = 64
+)
+
+// ErrJournaldUnsupported is returned by NewJournaldClient on platforms without systemd
+var ErrJournaldUnsupported = errors.New("journald is only supported on linux")
+
+// JournaldOption is an optional setting for NewJournaldClient
+type JournaldOption func(c *JournaldClient)
+
+// WithJournaldIdentifier sets the SYSLOG_IDENTIFIER of the entries (defaults to the process name)
+func WithJournaldIdentifier(identifier string) JournaldOption {
+	return func(c *JournaldClient) {
+		c.identifier = identifier
+	}
+}
+
+// WithJournaldSocket sets the path of the journald socket (defaults to JournaldSocket)
+func WithJournaldSocket(path string) JournaldOption {
+	return func(c *JournaldClient) {
+		c.socket = path
+	}
+}
+
+// JournaldClient is a Logger writing structured entries to the systemd journal with the
+// native protocol (entries too large for a datagram are passed in a memfd)
+type JournaldClient struct {
+	addr       *net.UnixAddr
+	conn       *net.UnixConn
+	identifier string
+	socket     string
+}
+
+// Close closes the connection
+func (c *JournaldClient) Close() error {
+	return c.conn.Close()
+}
+
+// LogEntry implements the EntryLogger interface, writing the fields as journal fields
+func (c *JournaldClient) LogEntry(e *Entry) {
+	c.write(syslogSeverity(e.Level), e.Message, e)
+}
+
+// Panic overloads built-in method
+func (c *JournaldClient) Panic(v ...interface{}) {
+	c.write(syslogCritical, fmt.Sprint(v...), nil)
+	os.Exit(1)
+}
+
+// Panicln overloads built-in method
+func (c *JournaldClient) Panicln(v ...interface{}) {
+	c.write(syslogCritical, fmt.Sprintln(v...), nil)
+	os.Exit(1)
+}
+
+// Panicf overloads built-in method
+func (c *JournaldClient) Panicf(format string, v ...interface{}) {
+	c.write(syslogCritical, fmt.Sprintf(format, v...), nil)
+	os.Exit(1)
+}
+
+// Print overloads built-in method
+func (c *JournaldClient) Print(v ...interface{}) {
+	c.write(syslogInfo, fmt.Sprint(v...), nil)
+}
+
+// Println overloads built-in method
+func (c *JournaldClient) Println(v ...interface{}) {
+	c.write(syslogInfo, fmt.Sprintln(v...), nil)
+}
+
+// Printf overloads built-in method
+func (c *JournaldClient) Printf(format string, v ...interface{}) {
+	c.write(syslogInfo, fmt.Sprintf(format, v...), nil)
+}
+
+// Fatal overloads built-in method
+func (c *JournaldClient) Fatal(v ...interface{}) {
+	c.write(syslogCritical, fmt.Sprint(v...), nil)
+	os.Exit(1)
+}
+
+// Fatalln overloads built-in method
+func (c *JournaldClient) Fatalln(v ...interface{}) {
+	c.write(syslogCritical, fmt.Sprintln(v...), nil)
+	os.Exit(1)
+}
+
+// Fatalf overloads built-in method
+func (c *JournaldClient) Fatalf(format string, v ...interface{}) {
+	c.write(syslogCritical, fmt.Sprintf(format, v...), nil)
+	os.Exit(1)
+}
+
+// write sends an entry, printing the message with the log package if that failed
+func (c *JournaldClient) write(priority int, message string, e *Entry) {
+	if err := c.send(c.encode(priority, message, e)); err != nil {
+		log.Print(message)
+		log.Println("go-logger: failed to write to journald:", err) //nolint:gosec // G706: error originates from stdlib network functions
+	}
+}
+
+// encode builds the native protocol form of an entry
+func (c *JournaldClient) encode(priority int, message string, e *Entry) []byte {
+	b := appendJournaldField(nil, "MESSAGE", strings.TrimSuffix(message, "\n"))
+	b = appendJournaldField(b, "PRIORITY", strconv.Itoa(priority))
+	if len(c.identifier) > 0 {
+		b = appendJournaldField(b, "SYSLOG_IDENTIFIER", c.identifier)
+	}
+	if e == nil {
+		return b
+	}
+
+	if len(e.File) > 0 {
+		b = appendJournaldField(b, "CODE_FILE", e.File)
+		b = appendJournaldField(b, "CODE_LINE", strconv.Itoa(e.Line))
+		b = appendJournaldField(b, "CODE_FUNC", e.Method)
+	}
+	for _, kv := range e.KeyValues() {
+		var value string
+		if f, ok := kv.(Field); ok {
+			value = string(f.AppendValue(nil))
+		} else {
+			value = fmt.Sprint(kv.Value())
+		}
+		b = appendJournaldField(b, journaldFieldName(kv.Key()), value)
+	}
+	return b
+}
+
+// appendJournaldField appends a field, in its binary form when the value spans several lines
+func appendJournaldField(b []byte, name, value string) []byte {
+	b = append(b, name...)
+	if !strings.Contains(value, "\n") {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+	b = append(b, '\n')
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// journaldFieldName returns the journal field name of a key: uppercase letters, digits and
+// underscores, not starting with an underscore (reserved for trusted fields) or a digit, and
+// prefixed when it is one of the fields written by the client (IE: message is FIELD_MESSAGE)
+func journaldFieldName(key string) string {
+	name := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		switch c := key[i]; {
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			name = append(name, c)
+		case c >= 'a' && c <= 'z':
+			name = append(name, c-'a'+'A')
+		default:
+			name = append(name, '_')
+		}
+	}
+
+	s := strings.TrimLeft(string(name), "_")
+	switch {
+	case len(s) == 0:
+		s = "FIELD"
+	case s[0] >= '0' && s[0] <= '9':
+		s = "FIELD_" + s
+	}
+	if len(s) > journaldMaxFieldName {
+		s = s[:journaldMaxFieldName]
+	}
+	switch s {
+	case "CODE_FILE", "CODE_FUNC", "CODE_LINE", "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER":
+		s = "FIELD_" + s
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+//go:build linux
+
+package logger
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// memfd_create(2) and fcntl(2) sealing flags
+const (
+	memfdAllowSealing = 0x2
+	memfdCloexec      = 0x1
+	fcntlAddSeals     = 1033
+	sealAll           = 0x1 | 0x2 | 0x4 | 0x8 // F_SEAL_SEAL, F_SEAL_SHRINK, F_SEAL_GROW and F_SEAL_WRITE
+)
+
+// NewJournaldClient connects to the journald socket, an error is returned when the host
+// does not run systemd-journald
+func NewJournaldClient(opts ...JournaldOption) (*JournaldClient, error) {
+	c := &JournaldClient{
+		identifier: filepath.Base(os.Args[0]),
+		socket:     JournaldSocket,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if _, err := os.Stat(c.socket); err != nil {
+		return nil, err
+	}
+
+	// Not connected to the socket: file descriptors are passed with WriteMsgUnix
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	c.addr = &net.UnixAddr{Name: c.socket, Net: "unixgram"}
+	c.conn = conn
+	return c, nil
+}
+
+// send writes an entry in a datagram, or in a file descriptor when it is too large
+func (c *JournaldClient) send(data []byte) error {
+	_, _, err := c.conn.WriteMsgUnix(data, nil, c.addr)
+	if err == nil || (!errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS)) {
+		return err
+	}
+
+	f, err := journaldMemfd(data)
+	if err != nil {
+		// Kernels before 3.17 (or unknown architectures): journald also accepts a file in /dev/shm
+		if f, err = journaldTempFile(data); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	_, _, err = c.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), c.addr) //nolint:gosec // G115: file descriptors fit in an int
+	return err
+}
+
+// journaldMemfd returns a sealed memfd holding the data
+func journaldMemfd(data []byte) (*os.File, error) {
+	trap := memfdCreateTrap()
+	if trap == 0 {
+		return nil, syscall.ENOSYS
+	}
+	name, err := syscall.BytePtrFromString("go-logger-journal")
+	if err != nil {
+		return nil, err
+	}
+
+	fd, _, errno := syscall.Syscall(trap, uintptr(unsafe.Pointer(name)), memfdCloexec|memfdAllowSealing, 0) //nolint:gosec // G103: memfd_create has no wrapper in syscall
+	if errno != 0 {
+		return nil, errno
+	}
+	f := os.NewFile(fd, "memfd:go-logger-journal")
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if _, _, errno = syscall.Syscall(syscall.SYS_FCNTL, fd, fcntlAddSeals, sealAll); errno != 0 {
+		_ = f.Close()
+		return nil, errno
+	}
+	return f, nil
+}
+
+// journaldTempFile returns an unlinked file in /dev/shm holding the data
+func journaldTempFile(data []byte) (*os.File, error) {
+	f, err := os.CreateTemp("/dev/shm", "go-logger-journal-")
+	if err != nil {
+		return nil, err
+	}
+	_ = os.Remove(f.Name()) // journald reads the descriptor, the name is not needed
+
+	if _, err = f.Write(data); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// memfdCreateTrap returns the number of the memfd_create system call (zero if unknown)
+func memfdCreateTrap() uintptr {
+	switch runtime.GOARCH {
+	case "amd64":
+		return 319
+	case "arm64", "loong64", "riscv64":
+		return 279
+	case "386":
+		return 356
+	case "arm":
+		return 385
+	case "ppc64", "ppc64le":
+		return 360
+	case "s390x":
+		return 350
+	}
+	return 0
+}
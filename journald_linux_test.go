@@ -0,0 +1,232 @@
+//go:build linux
+
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// journaldEntry is an entry received by a testJournaldServer
+type journaldEntry struct {
+	fields  map[string][]string
+	fromFd  bool  // Passed in a file descriptor
+	fdSeals int64 // Seals of the file descriptor (memfd only)
+}
+
+// testJournaldServer is a journald socket decoding the received entries
+type testJournaldServer struct {
+	conn    *net.UnixConn
+	entries chan journaldEntry
+	path    string
+}
+
+// newTestJournaldServer starts a testJournaldServer
+func newTestJournaldServer(t *testing.T) *testJournaldServer {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	s := &testJournaldServer{conn: conn, entries: make(chan journaldEntry, 10), path: path}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1<<16)
+		oob := make([]byte, syscall.CmsgSpace(4))
+		for {
+			n, oobn, _, _, readErr := conn.ReadMsgUnix(buf, oob)
+			if readErr != nil {
+				return
+			}
+			if oobn == 0 {
+				s.entries <- journaldEntry{fields: parseJournald(t, buf[:n])}
+				continue
+			}
+			s.entries <- s.readFd(t, oob[:oobn])
+		}
+	}()
+	return s
+}
+
+// readFd decodes an entry passed in a file descriptor
+func (s *testJournaldServer) readFd(t *testing.T, oob []byte) journaldEntry {
+	messages, err := syscall.ParseSocketControlMessage(oob)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	fds, err := syscall.ParseUnixRights(&messages[0])
+	require.NoError(t, err)
+	require.Len(t, fds, 1)
+
+	f := os.NewFile(uintptr(fds[0]), "journal") //nolint:gosec // G115: file descriptors are positive
+	defer func() { _ = f.Close() }()
+	seals, _, _ := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), fcntlAddSeals+1, 0) // F_GET_SEALS
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return journaldEntry{fields: parseJournald(t, data), fromFd: true, fdSeals: int64(seals)} //nolint:gosec // G115: seal flags
+}
+
+// next returns the next entry
+func (s *testJournaldServer) next(t *testing.T) journaldEntry {
+	t.Helper()
+	select {
+	case e := <-s.entries:
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for a journald entry")
+	}
+	return journaldEntry{}
+}
+
+// TestNewJournaldClient will test the NewJournaldClient() method
+func TestNewJournaldClient(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		c := &JournaldClient{identifier: filepath.Base(os.Args[0]), socket: JournaldSocket}
+		if _, err := os.Stat(JournaldSocket); err == nil {
+			client, clientErr := NewJournaldClient()
+			require.NoError(t, clientErr)
+			defer func() { _ = client.Close() }()
+			c = client
+		}
+		assert.Equal(t, filepath.Base(os.Args[0]), c.identifier)
+		assert.Equal(t, JournaldSocket, c.socket)
+	})
+
+	t.Run("options", func(t *testing.T) {
+		server := newTestJournaldServer(t)
+		c, err := NewJournaldClient(WithJournaldSocket(server.path), WithJournaldIdentifier("api"))
+		require.NoError(t, err)
+		defer func() { _ = c.Close() }()
+		assert.Equal(t, "api", c.identifier)
+		assert.Equal(t, server.path, c.socket)
+	})
+
+	t.Run("missing socket", func(t *testing.T) {
+		c, err := NewJournaldClient(WithJournaldSocket(filepath.Join(t.TempDir(), "missing")))
+		require.Error(t, err)
+		assert.Nil(t, c)
+	})
+}
+
+// TestJournaldClient_send will test writing entries
+func TestJournaldClient_send(t *testing.T) {
+	server := newTestJournaldServer(t)
+	c, err := NewJournaldClient(WithJournaldSocket(server.path), WithJournaldIdentifier("api"))
+	require.NoError(t, err)
+	defer func() { _ = c.Close() }()
+
+	t.Run("print", func(t *testing.T) {
+		c.Println("hello")
+		e := server.next(t)
+		assert.False(t, e.fromFd)
+		assert.Equal(t, map[string][]string{"MESSAGE": {"hello"}, "PRIORITY": {"6"}, "SYSLOG_IDENTIFIER": {"api"}}, e.fields)
+	})
+
+	t.Run("entry", func(t *testing.T) {
+		previous := GetImplementation()
+		SetImplementation(c)
+		Data(2, ERROR, "failed", String("request_id", "abc"))
+		SetImplementation(previous)
+
+		e := server.next(t)
+		assert.Equal(t, []string{"failed"}, e.fields["MESSAGE"])
+		assert.Equal(t, []string{"3"}, e.fields["PRIORITY"])
+		assert.Contains(t, e.fields["CODE_FILE"][0], "journald_linux_test.go")
+		assert.Contains(t, e.fields["CODE_FUNC"][0], "TestJournaldClient_send")
+		assert.NotEqual(t, "0", e.fields["CODE_LINE"][0])
+		assert.Equal(t, []string{"abc"}, e.fields["REQUEST_ID"])
+	})
+
+	t.Run("large entry", func(t *testing.T) {
+		message := strings.Repeat("x", 4<<20)
+		c.Print(message)
+		e := server.next(t)
+		assert.True(t, e.fromFd)
+		assert.Equal(t, []string{message}, e.fields["MESSAGE"])
+		if memfdCreateTrap() != 0 {
+			assert.Equal(t, int64(sealAll), e.fdSeals)
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		closed, clientErr := NewJournaldClient(WithJournaldSocket(server.path))
+		require.NoError(t, clientErr)
+		require.NoError(t, closed.Close())
+		assert.NotPanics(t, func() { closed.Println("lost") })
+	})
+}
+
+// TestJournaldTempFile will test the journaldTempFile() method
+func TestJournaldTempFile(t *testing.T) {
+	if _, err := os.Stat("/dev/shm"); err != nil {
+		t.Skip("no /dev/shm")
+	}
+	f, err := journaldTempFile([]byte("MESSAGE=hello\n"))
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	_, err = os.Stat(f.Name())
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "MESSAGE=hello\n", string(data))
+}
+
+// TestJournaldClient_Panic will test the Panic() method
+func TestJournaldClient_Panic(t *testing.T) {
+	if path := os.Getenv("EXIT_FUNCTION_SOCKET"); len(path) > 0 {
+		c, _ := NewJournaldClient(WithJournaldSocket(path))
+		c.Panicf("panicf %d", 1)
+		return
+	}
+	server := newTestJournaldServer(t)
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestJournaldClient_Panic") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION_SOCKET="+server.path)
+	err := cmd.Run()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		entry := server.next(t)
+		assert.Equal(t, []string{"panicf 1"}, entry.fields["MESSAGE"])
+		assert.Equal(t, []string{"2"}, entry.fields["PRIORITY"])
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestJournaldClient_Fatal will test the Fatal() method
+func TestJournaldClient_Fatal(t *testing.T) {
+	if path := os.Getenv("EXIT_FUNCTION_SOCKET"); len(path) > 0 {
+		c, _ := NewJournaldClient(WithJournaldSocket(path))
+		c.Fatalf("fatal %d", 1)
+		return
+	}
+	server := newTestJournaldServer(t)
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestJournaldClient_Fatal") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION_SOCKET="+server.path)
+	err := cmd.Run()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		entry := server.next(t)
+		assert.Equal(t, []string{"fatal 1"}, entry.fields["MESSAGE"])
+		assert.Equal(t, []string{"2"}, entry.fields["PRIORITY"])
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package logger
+
+// NewJournaldClient returns ErrJournaldUnsupported, the journal only exists on linux
+func NewJournaldClient(_ ...JournaldOption) (*JournaldClient, error) {
+	return nil, ErrJournaldUnsupported
+}
+
+// send is never called, there is no client on this platform
+func (c *JournaldClient) send(_ []byte) error {
+	return ErrJournaldUnsupported
+}
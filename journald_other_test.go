@@ -0,0 +1,17 @@
+//go:build !linux
+
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewJournaldClient will test the NewJournaldClient() method
+func TestNewJournaldClient(t *testing.T) {
+	c, err := NewJournaldClient(WithJournaldIdentifier("api"))
+	require.ErrorIs(t, err, ErrJournaldUnsupported)
+	assert.Nil(t, c)
+}
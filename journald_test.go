@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseJournald decodes native protocol fields (test helper)
+func parseJournald(t *testing.T, data []byte) map[string][]string {
+	t.Helper()
+	fields := make(map[string][]string)
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		require.GreaterOrEqual(t, end, 0, "unterminated field")
+		line := string(data[:end])
+		data = data[end+1:]
+
+		if name, value, ok := strings.Cut(line, "="); ok {
+			fields[name] = append(fields[name], value)
+			continue
+		}
+		require.GreaterOrEqual(t, len(data), 8, "missing size of %s", line)
+		size := binary.LittleEndian.Uint64(data)
+		data = data[8:]
+		require.Greater(t, uint64(len(data)), size, "missing value of %s", line)
+		fields[line] = append(fields[line], string(data[:size]))
+		require.Equal(t, byte('\n'), data[size])
+		data = data[size+1:]
+	}
+	return fields
+}
+
+// TestJournaldClient_encode will test the encode() method
+func TestJournaldClient_encode(t *testing.T) {
+	c := &JournaldClient{identifier: "app"}
+
+	t.Run("print", func(t *testing.T) {
+		assert.Equal(t, "MESSAGE=hello\nPRIORITY=6\nSYSLOG_IDENTIFIER=app\n", string(c.encode(syslogInfo, "hello\n", nil)))
+	})
+
+	t.Run("entry", func(t *testing.T) {
+		e := &Entry{Level: WARN, Message: "first\nsecond", File: "main.go", Method: "main.main", Line: 7,
+			Fields: []KeyValue{String("user_id", "42"), Float64("ratio", 0.5), MakeParameter("_private", true),
+				String("message", "user message")}}
+		fields := parseJournald(t, c.encode(syslogSeverity(e.Level), e.Message, e))
+		assert.Equal(t, map[string][]string{
+			"MESSAGE":           {"first\nsecond"},
+			"PRIORITY":          {"4"},
+			"SYSLOG_IDENTIFIER": {"app"},
+			"CODE_FILE":         {"main.go"},
+			"CODE_LINE":         {"7"},
+			"CODE_FUNC":         {"main.main"},
+			"USER_ID":           {"42"},
+			"RATIO":             {"0.5"},
+			"PRIVATE":           {"true"},
+			"FIELD_MESSAGE":     {"user message"},
+		}, fields)
+	})
+
+	t.Run("no identifier", func(t *testing.T) {
+		c := &JournaldClient{}
+		assert.Equal(t, "MESSAGE=\nPRIORITY=2\n", string(c.encode(syslogCritical, "", &Entry{})))
+	})
+}
+
+// TestAppendJournaldField will test the appendJournaldField() method
+func TestAppendJournaldField(t *testing.T) {
+	assert.Equal(t, "KEY=value\n", string(appendJournaldField(nil, "KEY", "value")))
+	assert.Equal(t, "KEY=a=b\n", string(appendJournaldField(nil, "KEY", "a=b")))
+	assert.Equal(t, "KEY\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n", string(appendJournaldField(nil, "KEY", "a\nb")))
+}
+
+// TestJournaldFieldName will test the journaldFieldName() method
+func TestJournaldFieldName(t *testing.T) {
+	tests := map[string]string{
+		"key":                    "KEY",
+		"userId":                 "USERID",
+		"http.status-code":       "HTTP_STATUS_CODE",
+		"__trusted":              "TRUSTED",
+		"1st":                    "FIELD_1ST",
+		"":                       "FIELD",
+		"___":                    "FIELD",
+		"émoji":                  "MOJI",
+		strings.Repeat("a", 100): strings.Repeat("A", journaldMaxFieldName),
+		"message":                "FIELD_MESSAGE",
+		"priority":               "FIELD_PRIORITY",
+		"syslog.identifier":      "FIELD_SYSLOG_IDENTIFIER",
+		"code_line":              "FIELD_CODE_LINE",
+	}
+	for key, expected := range tests {
+		t.Run(key, func(t *testing.T) {
+			assert.Equal(t, expected, journaldFieldName(key))
+		})
+	}
+}
+
+// BenchmarkJournaldClient_encode benchmarks the encode() method
+func BenchmarkJournaldClient_encode(b *testing.B) {
+	c := &JournaldClient{identifier: "app"}
+	e := &Entry{Level: INFO, Message: "benchmark", File: "file.go", Method: "Method", Line: 10,
+		Fields: []KeyValue{String("key", "value"), Int("n", 1)}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.encode(syslogInfo, e.Message, e)
+	}
+}
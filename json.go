@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// JSONEncoder writes each entry as a single JSON object, with typed fields written natively
+type JSONEncoder struct {
+	TimeFormat string // Layout of the "time" key (defaults to time.RFC3339Nano)
+}
+
+// Encode implements the Encoder interface
+func (j *JSONEncoder) Encode(buf *bytes.Buffer, e *Entry) {
+	buf.WriteByte('{')
+	if !e.Time.IsZero() {
+		layout := j.TimeFormat
+		if len(layout) == 0 {
+			layout = time.RFC3339Nano
+		}
+		buf.WriteString(`"time":"`)
+		buf.Write(e.Time.AppendFormat(buf.AvailableBuffer(), layout))
+		buf.WriteString(`",`)
+	}
+	buf.WriteString(`"level":"`)
+	buf.WriteString(e.Level.String())
+	buf.WriteByte('"')
+	if len(e.File) > 0 {
+		buf.WriteString(`,"file":`)
+		writeJSONString(buf, e.File)
+		buf.WriteString(`,"method":`)
+		writeJSONString(buf, e.Method)
+		buf.WriteString(`,"line":`)
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(e.Line), 10))
+	}
+	buf.WriteString(`,"message":`)
+	writeJSONString(buf, e.Message)
+
+	for _, arg := range e.Fields {
+		buf.WriteByte(',')
+		writeJSONString(buf, arg.Key())
+		buf.WriteByte(':')
+		writeJSONValue(buf, arg)
+	}
+	for i := range e.Typed {
+		buf.WriteByte(',')
+		writeJSONString(buf, e.Typed[i].key)
+		buf.WriteByte(':')
+		writeJSONField(buf, &e.Typed[i])
+	}
+	buf.WriteByte('}')
+}
+
+// writeJSONValue writes the value of a key/value pair, natively for typed fields
+func writeJSONValue(buf *bytes.Buffer, kv KeyValue) {
+	f, ok := kv.(Field)
+	if !ok {
+		writeJSONAny(buf, kv.Value())
+		return
+	}
+	writeJSONField(buf, &f)
+}
+
+// writeJSONField writes the value of a typed field without boxing it
+func writeJSONField(buf *bytes.Buffer, f *Field) {
+	switch f.fieldType {
+	case IntType, BoolType:
+		buf.Write(f.AppendValue(buf.AvailableBuffer()))
+	case FloatType:
+		if v := f.float(); math.IsNaN(v) || math.IsInf(v, 0) {
+			writeJSONString(buf, string(f.AppendValue(nil)))
+		} else {
+			buf.Write(f.AppendValue(buf.AvailableBuffer()))
+		}
+	case DurationType:
+		// Durations are written as integer nanoseconds, like encoding/json
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), f.num, 10))
+	case AnyType:
+		writeJSONAny(buf, f.obj)
+	case ErrorType:
+		if err, _ := f.obj.(error); err != nil {
+			info := newErrorInfo(err, 0)
+			writeErrorJSON(buf, &info)
+		} else {
+			buf.WriteString("null")
+		}
+	case StringType:
+		writeJSONString(buf, f.str)
+	case TimeType, StringerType:
+		writeJSONString(buf, string(f.AppendValue(nil)))
+	}
+}
+
+// writeJSONAny writes any value using encoding/json, falling back to its text form
+func writeJSONAny(buf *bytes.Buffer, v interface{}) {
+	if err, ok := v.(error); ok {
+		writeJSONString(buf, err.Error())
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeJSONString(buf, fmt.Sprint(v))
+		return
+	}
+	buf.Write(data)
+}
+
+// writeJSONString writes s as a quoted and escaped JSON string
+func writeJSONString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= 0x20 && c != '"' && c != '\\' && c < utf8.RuneSelf {
+			i++
+			continue
+		}
+		if c < utf8.RuneSelf {
+			buf.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[c>>4])
+				buf.WriteByte(hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`�`)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestJSONEncoder_Encode will test the JSONEncoder
+func TestJSONEncoder_Encode(t *testing.T) {
+	t.Run("entry with typed fields", func(t *testing.T) {
+		var buf bytes.Buffer
+		e := testEntry()
+		e.Time = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		e.Fields = append(e.Fields,
+			Int("rows", 12),
+			Float64("duration_ms", 1.5),
+			Bool("ok", false),
+			Duration("elapsed", time.Millisecond),
+			Err(errors.New("boom")),
+			Any("list", []string{"a"}),
+			Float64("nan", math.NaN()),
+		)
+		(&JSONEncoder{}).Encode(&buf, e)
+
+		assert.JSONEq(t, `{
+			"time":"2024-01-02T03:04:05Z","level":"warn","file":"go-logger/logger_test.go","method":"go-logger.TestData",
+			"line":188,"message":"test this method","another":"value","rows":12,"duration_ms":1.5,"ok":false,
+			"elapsed":1000000,"error":{"message":"boom","type":"*errors.errorString"},"list":["a"],"nan":"NaN"
+		}`, buf.String())
+	})
+
+	t.Run("typed fields after the fields", func(t *testing.T) {
+		var buf bytes.Buffer
+		(&JSONEncoder{}).Encode(&buf, &Entry{Level: INFO, Message: "typed", Fields: []KeyValue{MakeParameter("p", "v")},
+			Typed: []Field{Int("rows", 12), Err(errors.New("boom")), String("s", "x")}})
+		assert.Equal(t, `{"level":"info","message":"typed","p":"v","rows":12,"error":{"message":"boom","type":"*errors.errorString"},"s":"x"}`, buf.String())
+	})
+
+	t.Run("escaping", func(t *testing.T) {
+		var buf bytes.Buffer
+		(&JSONEncoder{}).Encode(&buf, &Entry{Level: INFO, Message: "quote \" slash \\ newline \n tab \t ctrl \x01 bad \xff unicode ✓"})
+
+		var decoded map[string]string
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, "quote \" slash \\ newline \n tab \t ctrl \x01 bad � unicode ✓", decoded["message"])
+		assert.NotContains(t, buf.String(), "time")
+	})
+
+	t.Run("custom time format", func(t *testing.T) {
+		var buf bytes.Buffer
+		(&JSONEncoder{TimeFormat: time.DateOnly}).Encode(&buf, &Entry{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)})
+		assert.Contains(t, buf.String(), `"time":"2024-01-02"`)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		var buf bytes.Buffer
+		(&JSONEncoder{}).Encode(&buf, testEntry())
+
+		d, err := ParseJSONLine(buf.String())
+		require.NoError(t, err)
+		assert.Equal(t, WARN, d.Level)
+		assert.Equal(t, 188, d.Line)
+		assert.Equal(t, []Parameter{{K: "another", V: "value"}}, d.Params)
+	})
+}
+
+// TestParseJSONLineInvalid will test ParseJSONLine() with bad input
+func TestParseJSONLineInvalid(t *testing.T) {
+	for _, line := range []string{
+		`not json`,
+		`[1, 2]`,
+		`{"message":"no level"}`,
+		`{"level":"loud"}`,
+		`{"level":"info","line":"x"}`,
+		`{"level":"info",`,
+	} {
+		_, err := ParseJSONLine(line)
+		require.ErrorIs(t, err, ErrInvalidDataLine, line)
+	}
+}
+
+// BenchmarkJSONEncoder_Encode benchmarks the JSONEncoder
+func BenchmarkJSONEncoder_Encode(b *testing.B) {
+	var buf bytes.Buffer
+	enc := &JSONEncoder{}
+	entry := testEntry()
+	entry.Time = time.Now()
+	entry.Fields = append(entry.Fields, Int("rows", 12), Float64("duration_ms", 1.5))
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		enc.Encode(&buf, entry)
+	}
+}
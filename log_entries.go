@@ -35,6 +35,49 @@ func (m *msgQueue) PushFront(msg *bytes.Buffer) {
 	}
 }
 
+// tokenRoute sends the entries it matches to the log set of its token
+type tokenRoute struct {
+	match func(e *Entry) bool
+	token string
+}
+
+// LogClientOption is an optional setting for NewLogEntriesClient
+type LogClientOption func(l *LogClient)
+
+// WithLevelToken sends the entries of a level to the log set of token
+func WithLevelToken(level LogLevel, token string) LogClientOption {
+	return WithTokenRoute(func(e *Entry) bool {
+		return e.Level == level
+	}, token)
+}
+
+// WithFieldToken sends the entries having a Data() field key equal to value
+// (IE: "component" = "billing") to the log set of token
+func WithFieldToken(key, value, token string) LogClientOption {
+	return WithTokenRoute(func(e *Entry) bool {
+		for _, kv := range e.KeyValues() {
+			if kv.Key() != key {
+				continue
+			}
+			if f, ok := kv.(Field); ok {
+				return string(f.AppendValue(nil)) == value
+			}
+			return fmt.Sprint(kv.Value()) == value
+		}
+		return false
+	}, token)
+}
+
+// WithTokenRoute sends the entries matched by the predicate to the log set of token.
+// Routes are tried in the order given and the first match wins, entries matching no route
+// use the token of NewLogEntriesClient. Print lines are matched as INFO entries and the
+// Fatal and Panic lines as ERROR entries, both without fields.
+func WithTokenRoute(match func(e *Entry) bool, token string) LogClientOption {
+	return func(l *LogClient) {
+		l.routes = append(l.routes, tokenRoute{match: match, token: token})
+	}
+}
+
 // LogClient configuration
 type LogClient struct {
 	conn       *net.TCPConn
@@ -42,17 +85,22 @@ type LogClient struct {
 	messages   msgQueue
 	port       string
 	retryDelay time.Duration
+	routes     []tokenRoute
 	token      string
 }
 
-// NewLogEntriesClient new client
-func NewLogEntriesClient(token, endpoint, port string) (*LogClient, error) {
+// NewLogEntriesClient new client, the token is the default log set of the messages and
+// routes (IE: WithLevelToken) send some of them to other log sets over the same connection
+func NewLogEntriesClient(token, endpoint, port string, opts ...LogClientOption) (*LogClient, error) {
 	l := &LogClient{
 		endpoint:   endpoint,
 		port:       port,
 		retryDelay: RetryDelay,
 		token:      token,
 	}
+	for _, opt := range opts {
+		opt(l)
+	}
 	l.messages.messagesToSend = make(chan *bytes.Buffer, 1000)
 
 	if err := l.Connect(); err != nil {
@@ -69,18 +117,30 @@ func (l *LogClient) Connect() error {
 	}
 	l.conn = nil
 
-	addr, err := net.ResolveTCPAddr("tcp", l.endpoint+":"+l.port)
+	conn, err := dialTCP(l.endpoint, l.port, &l.retryDelay)
 	if err != nil {
 		return err
 	}
+	l.conn = conn
+
+	return nil
+}
+
+// dialTCP connects to endpoint:port (shared by the TCP clients), doubling the retry delay
+// when the connection fails (up to MaxRetryDelay) and resetting it once connected
+func dialTCP(endpoint, port string, retryDelay *time.Duration) (*net.TCPConn, error) {
+	addr, err := net.ResolveTCPAddr("tcp", endpoint+":"+port)
+	if err != nil {
+		return nil, err
+	}
 
 	var conn *net.TCPConn
 	if conn, err = net.DialTCP("tcp", nil, addr); err != nil {
-		l.retryDelay *= 2
-		if l.retryDelay > MaxRetryDelay {
-			l.retryDelay = MaxRetryDelay
+		*retryDelay *= 2
+		if *retryDelay > MaxRetryDelay {
+			*retryDelay = MaxRetryDelay
 		}
-		return err
+		return nil, err
 	}
 
 	_ = conn.SetNoDelay(true)
@@ -92,10 +152,9 @@ func (l *LogClient) Connect() error {
 	//	return err
 	// }
 
-	l.conn = conn
-	l.retryDelay = RetryDelay
+	*retryDelay = RetryDelay
 
-	return nil
+	return conn, nil
 }
 
 // ProcessQueue process the queue
@@ -123,33 +182,21 @@ func (l *LogClient) ProcessQueue() {
 
 // Panic overloads built-in method
 func (l *LogClient) Panic(v ...interface{}) {
-	var buff bytes.Buffer
-	buff.WriteString(l.token)
-	buff.WriteByte(' ')
-	fmt.Fprintln(&buff, v...)
-	_ = l.sendOne(&buff)
+	_ = l.sendOne(l.line(ERROR, fmt.Sprintln(v...)))
 	time.Sleep(2 * time.Second)
 	os.Exit(1)
 }
 
 // Panicln overloads built-in method
 func (l *LogClient) Panicln(v ...interface{}) {
-	var buff bytes.Buffer
-	buff.WriteString(l.token)
-	buff.WriteByte(' ')
-	fmt.Fprintln(&buff, v...)
-	_ = l.sendOne(&buff)
+	_ = l.sendOne(l.line(ERROR, fmt.Sprintln(v...)))
 	time.Sleep(2 * time.Second)
 	os.Exit(1)
 }
 
 // Panicf overloads built-in method
 func (l *LogClient) Panicf(format string, v ...interface{}) {
-	var buff bytes.Buffer
-	buff.WriteString(l.token)
-	buff.WriteByte(' ')
-	fmt.Fprintf(&buff, format, v...)
-	_ = l.sendOne(&buff)
+	_ = l.sendOne(l.line(ERROR, fmt.Sprintf(format, v...)))
 	time.Sleep(2 * time.Second)
 	os.Exit(1)
 }
@@ -171,44 +218,62 @@ func (l *LogClient) Printf(format string, v ...interface{}) {
 
 // Fatal overloads built-in method
 func (l *LogClient) Fatal(v ...interface{}) {
-	var buff bytes.Buffer
-	buff.WriteString(l.token)
-	buff.WriteByte(' ')
-	fmt.Fprintln(&buff, v...)
-	_ = l.sendOne(&buff)
+	_ = l.sendOne(l.line(ERROR, fmt.Sprintln(v...)))
 	time.Sleep(2 * time.Second)
 	os.Exit(1)
 }
 
 // Fatalln overloads built-in method
 func (l *LogClient) Fatalln(v ...interface{}) {
-	var buff bytes.Buffer
-	buff.WriteString(l.token)
-	buff.WriteByte(' ')
-	fmt.Fprintln(&buff, v...)
-	_ = l.sendOne(&buff)
+	_ = l.sendOne(l.line(ERROR, fmt.Sprintln(v...)))
 	time.Sleep(2 * time.Second)
 	os.Exit(1)
 }
 
 // Fatalf overloads built-in method
 func (l *LogClient) Fatalf(format string, v ...interface{}) {
-	var buff bytes.Buffer
-	buff.WriteString(l.token)
-	buff.WriteByte(' ')
-	fmt.Fprintf(&buff, format, v...)
-	_ = l.sendOne(&buff)
+	_ = l.sendOne(l.line(ERROR, fmt.Sprintf(format, v...)))
 	time.Sleep(2 * time.Second)
 	os.Exit(1)
 }
 
+// LogEntry implements the EntryLogger interface, sending the entry to the log set of the
+// first route matching it
+func (l *LogClient) LogEntry(e *Entry) {
+	var buff bytes.Buffer
+	buff.WriteString(l.routeToken(e))
+	buff.WriteByte(' ')
+	encoder.Encode(&buff, e)
+	buff.WriteByte('\n')
+	l.messages.Enqueue(&buff)
+}
+
 // write will write the data to the que
 func (l *LogClient) write(data string) {
+	l.messages.Enqueue(l.line(INFO, data))
+}
+
+// line prefixes the data with the token of its log set, routed as an entry of the level
+func (l *LogClient) line(level LogLevel, data string) *bytes.Buffer {
+	token := l.token
+	if len(l.routes) > 0 {
+		token = l.routeToken(&Entry{Level: level, Message: data})
+	}
 	var buff bytes.Buffer
-	buff.WriteString(l.token)
+	buff.WriteString(token)
 	buff.WriteByte(' ')
 	buff.WriteString(data)
-	l.messages.Enqueue(&buff)
+	return &buff
+}
+
+// routeToken returns the token of the first route matching the entry (or the default token)
+func (l *LogClient) routeToken(e *Entry) string {
+	for _, r := range l.routes {
+		if r.match(e) {
+			return r.token
+		}
+	}
+	return l.token
 }
 
 // sendOne sends one log
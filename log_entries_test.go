@@ -1,11 +1,15 @@
 package logger
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"errors"
+	"net"
 	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -39,6 +43,135 @@ func TestNewLogEntriesClient(t *testing.T) {
 	assert.NotNil(t, client)
 }
 
+// newTestLogEntriesListener starts a listener accepting one connection and returns its lines
+func newTestLogEntriesListener(t *testing.T) (port string, lines chan string, accepted chan struct{}) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	lines = make(chan string, 100)
+	accepted = make(chan struct{}, 10)
+	go func() {
+		for {
+			conn, acceptErr := listener.Accept()
+			if acceptErr != nil {
+				return
+			}
+			accepted <- struct{}{}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}()
+		}
+	}()
+	return strconv.Itoa(listener.Addr().(*net.TCPAddr).Port), lines, accepted
+}
+
+// queuedLines closes the queue of the client and returns the queued messages
+func queuedLines(client *LogClient) []string {
+	close(client.messages.messagesToSend)
+	var lines []string
+	for x := range client.messages.messagesToSend {
+		lines = append(lines, x.String())
+	}
+	return lines
+}
+
+// TestNewLogEntriesClient_Routes will test the routing options of NewLogEntriesClient()
+func TestNewLogEntriesClient_Routes(t *testing.T) {
+	port, _, _ := newTestLogEntriesListener(t)
+	client, err := NewLogEntriesClient(testToken, "127.0.0.1", port,
+		WithLevelToken(ERROR, "errors"),
+		WithFieldToken("component", "billing", "billing"),
+		WithTokenRoute(func(e *Entry) bool { return strings.HasPrefix(e.Message, "audit") }, "audit"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, testToken, client.token)
+	assert.Len(t, client.routes, 3)
+
+	tests := []struct {
+		name     string
+		entry    *Entry
+		expected string
+	}{
+		{"default token", &Entry{Level: INFO, Message: "hello"}, testToken},
+		{"level route", &Entry{Level: ERROR, Message: "failed"}, "errors"},
+		{"field route", &Entry{Level: INFO, Fields: []KeyValue{String("component", "billing")}}, "billing"},
+		{"parameter route", &Entry{Level: INFO, Fields: []KeyValue{MakeParameter("component", "billing")}}, "billing"},
+		{"other field value", &Entry{Level: INFO, Fields: []KeyValue{String("component", "auth")}}, testToken},
+		{"predicate route", &Entry{Level: WARN, Message: "audit login"}, "audit"},
+		{"first match wins", &Entry{Level: ERROR, Message: "audit", Fields: []KeyValue{String("component", "billing")}}, "errors"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, client.routeToken(test.entry))
+		})
+	}
+}
+
+// TestLogClient_LogEntry will test the LogEntry() method
+func TestLogClient_LogEntry(t *testing.T) {
+	port, _, _ := newTestLogEntriesListener(t)
+	client, err := NewLogEntriesClient(testToken, "127.0.0.1", port,
+		WithLevelToken(ERROR, "errors"),
+		WithFieldToken("component", "billing", "billing"),
+	)
+	require.NoError(t, err)
+
+	client.LogEntry(&Entry{Level: INFO, Message: "started", Fields: []KeyValue{String("component", "billing")}})
+	client.LogEntry(&Entry{Level: ERROR, Message: "failed"})
+	client.LogEntry(&Entry{Level: DEBUG, Message: "other"})
+	client.Printf("print line")
+	client.Println("print", "line")
+
+	lines := queuedLines(client)
+	require.Len(t, lines, 5)
+	assert.True(t, strings.HasPrefix(lines[0], "billing "), lines[0])
+	assert.Contains(t, lines[0], "started")
+	assert.True(t, strings.HasSuffix(lines[0], "\n"))
+	assert.True(t, strings.HasPrefix(lines[1], "errors "), lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], testToken+" "), lines[2])
+	assert.Equal(t, testToken+" print line", lines[3])
+	assert.Equal(t, testToken+" print line\n", lines[4])
+}
+
+// TestLogClient_LogEntry_Multiplexed will test sending several log sets over one connection
+func TestLogClient_LogEntry_Multiplexed(t *testing.T) {
+	port, lines, accepted := newTestLogEntriesListener(t)
+	client, err := NewLogEntriesClient(testToken, "127.0.0.1", port, WithLevelToken(ERROR, "errors"))
+	require.NoError(t, err)
+	go client.ProcessQueue()
+
+	theLogger := implementation
+	defer func() { implementation = theLogger }()
+	implementation = client
+
+	Data(2, INFO, "first message", String("component", "api"))
+	Data(2, ERROR, "second message")
+	NoFilePrintln("third message")
+
+	var received []string
+	for len(received) < 3 {
+		select {
+		case line := <-lines:
+			received = append(received, line)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d of 3 lines", len(received))
+		}
+	}
+	assert.True(t, strings.HasPrefix(received[0], testToken+" "), received[0])
+	assert.Contains(t, received[0], "first message")
+	assert.Contains(t, received[0], "component")
+	assert.True(t, strings.HasPrefix(received[1], "errors "), received[1])
+	assert.Contains(t, received[1], "second message")
+	assert.Equal(t, testToken+" third message", received[2])
+	assert.Len(t, accepted, 1)
+}
+
 // TestMsgQueue_Enqueue will test the Enqueue() method
 func TestMsgQueue_Enqueue(t *testing.T) {
 	client, err := NewLogEntriesClient(testToken, LogEntriesTestEndpoint, LogEntriesPort)
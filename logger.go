@@ -6,11 +6,13 @@ package logger
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Logger interface describes the functionality that a log service must implement
@@ -50,6 +52,45 @@ func (l LogLevel) String() string {
 	return ""
 }
 
+// ParseLogLevel turns a level name (debug, info, warn, error) into a log level
+func ParseLogLevel(level string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return DEBUG, nil
+	case "info":
+		return INFO, nil
+	case "warn", "warning":
+		return WARN, nil
+	case "error":
+		return ERROR, nil
+	}
+	return DEBUG, fmt.Errorf("%w: %q", ErrUnknownLogLevel, level)
+}
+
+// Syslog severities (used by the GELF and journald clients)
+const (
+	syslogCritical = 2
+	syslogError    = 3
+	syslogWarning  = 4
+	syslogInfo     = 6
+	syslogDebug    = 7
+)
+
+// syslogSeverity returns the syslog severity of a log level
+func syslogSeverity(level LogLevel) int {
+	switch level {
+	case DEBUG:
+		return syslogDebug
+	case INFO:
+		return syslogInfo
+	case WARN:
+		return syslogWarning
+	case ERROR:
+		return syslogError
+	}
+	return syslogInfo
+}
+
 // Global constants
 const (
 	DEBUG LogLevel = iota
@@ -99,9 +140,32 @@ func init() {
 	} else { // Basic implementation for local logging
 		// log.Println("go-logger: internal logging") // disabled, not needed
 		implementation = &logPkg{}
+
+		// Detect a log file (rotated, and reopened on SIGHUP)
+		if logFile := os.Getenv("LOG_FILE"); len(logFile) > 0 {
+			if fileLogger, err := NewFileLogger(logFile, WithReopenOnSIGHUP()); err != nil {
+				log.Println("go-logger: failed to open log file:", err.Error()) //nolint:gosec // G706: error originates from stdlib file functions
+			} else {
+				implementation = fileLogger
+			}
+		}
+
+		// Detect a custom encoder (IE: "console" for local development)
+		if enc := newEncoder(os.Getenv("LOG_ENCODER"), sinkWriter(implementation)); enc != nil {
+			encoder = enc
+		}
 	}
 }
 
+// sinkWriter returns the writer an implementation logs to, or nil when it is not known
+// (IE: a log file, which is never a terminal)
+func sinkWriter(impl Logger) io.Writer {
+	if _, ok := impl.(*logPkg); ok {
+		return log.Writer()
+	}
+	return nil
+}
+
 // SetImplementation allows the log implementation to be swapped at runtime
 func SetImplementation(impl Logger) {
 	implementation = impl
@@ -249,50 +313,66 @@ func Errorfmt(stackLevel int, format string, v ...interface{}) {
 // format. stackLevel 2 will tag the log with the location from where Data is
 // called. This will print using the implementation's Println function
 func Data(stackLevel int, logLevel LogLevel, message string, args ...KeyValue) {
-	var buf bytes.Buffer
 	comps := FileTagComponents(stackLevel)
-	buf.WriteString(`type="`)
-	buf.WriteString(strings.ToLower(logLevel.String()))
-	buf.WriteString(`" file="`)
-	buf.WriteString(comps[0])
-	buf.WriteString(`" method="`)
-	buf.WriteString(comps[1])
-	buf.WriteString(`" line="`)
-	buf.WriteString(comps[2])
-	buf.WriteString(`" message="`)
-	buf.WriteString(message)
-	buf.WriteString(`"`)
-
-	for _, arg := range args {
-		buf.WriteByte(' ')
-		buf.WriteString(arg.Key())
-		buf.WriteString(`="`)
-		fmt.Fprint(&buf, arg.Value())
-		buf.WriteByte('"')
-	}
-
-	implementation.Println(buf.String())
+	line, _ := strconv.Atoi(comps[2])
+	writeEntry(&Entry{
+		Time:    time.Now(),
+		Level:   logLevel,
+		File:    comps[0],
+		Method:  comps[1],
+		Line:    line,
+		Message: message,
+		Fields:  args,
+	})
+}
+
+// DataFields is Data() with typed fields, which are passed to the encoder without boxing
+// them in KeyValue interfaces (the number of fields doesn't change the allocations)
+func DataFields(stackLevel int, logLevel LogLevel, message string, fields ...Field) {
+	comps := FileTagComponents(stackLevel)
+	line, _ := strconv.Atoi(comps[2])
+	writeEntry(&Entry{
+		Time:    time.Now(),
+		Level:   logLevel,
+		File:    comps[0],
+		Method:  comps[1],
+		Line:    line,
+		Message: message,
+		Typed:   fields,
+	})
 }
 
 // NoFileData will format the log message to a standardized log entries compatible format.
 // This will print using the implementation's Println function
 func NoFileData(logLevel LogLevel, message string, args ...KeyValue) {
-	var buf bytes.Buffer
-	buf.WriteString(`type="`)
-	buf.WriteString(strings.ToLower(logLevel.String()))
-	buf.WriteString(`" message="`)
-	buf.WriteString(message)
-	buf.WriteString(`"`)
-
-	for _, arg := range args {
-		buf.WriteByte(' ')
-		buf.WriteString(arg.Key())
-		buf.WriteString(`="`)
-		fmt.Fprint(&buf, arg.Value())
-		buf.WriteByte('"')
+	writeEntry(&Entry{
+		Time:    time.Now(),
+		Level:   logLevel,
+		Message: message,
+		Fields:  args,
+	})
+}
+
+// NoFileDataFields is NoFileData() with typed fields, see DataFields
+func NoFileDataFields(logLevel LogLevel, message string, fields ...Field) {
+	writeEntry(&Entry{
+		Time:    time.Now(),
+		Level:   logLevel,
+		Message: message,
+		Typed:   fields,
+	})
+}
+
+// writeEntry encodes the entry using the current encoder and prints it
+// (or hands it over if the implementation is an EntryLogger)
+func writeEntry(e *Entry) {
+	if el, ok := implementation.(EntryLogger); ok {
+		el.LogEntry(e)
+		return
 	}
-
-	NoFilePrintln(buf.String())
+	var buf bytes.Buffer
+	encoder.Encode(&buf, e)
+	implementation.Println(buf.String())
 }
 
 // Panic is normal panic
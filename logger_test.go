@@ -97,6 +97,15 @@ func BenchmarkLogLevel_String(b *testing.B) {
 	}
 }
 
+// TestSyslogSeverity will test the syslogSeverity() method
+func TestSyslogSeverity(t *testing.T) {
+	assert.Equal(t, syslogDebug, syslogSeverity(DEBUG))
+	assert.Equal(t, syslogInfo, syslogSeverity(INFO))
+	assert.Equal(t, syslogWarning, syslogSeverity(WARN))
+	assert.Equal(t, syslogError, syslogSeverity(ERROR))
+	assert.Equal(t, syslogInfo, syslogSeverity(LogLevel(42)))
+}
+
 // TestFileTag test file tag method
 func TestFileTag(t *testing.T) {
 	// File tag
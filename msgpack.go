@@ -0,0 +1,280 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// maxMsgpackDepth limits how deep nested slices and maps are encoded (guards against cycles)
+const maxMsgpackDepth = 32
+
+// ErrMsgpack is returned when decoding an unexpected MessagePack value
+var ErrMsgpack = errors.New("invalid msgpack")
+
+// appendMsgpackNil appends nil
+func appendMsgpackNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+// appendMsgpackBool appends a boolean
+func appendMsgpackBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+// appendMsgpackInt appends a signed integer in its smallest form
+func appendMsgpackInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return appendMsgpackUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(v)) //nolint:gosec // G115: negative fixint
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(v)) //nolint:gosec // G115: checked range
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(v)) //nolint:gosec // G115: two's complement
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v)) //nolint:gosec // G115: two's complement
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v)) //nolint:gosec // G115: two's complement
+}
+
+// appendMsgpackUint appends an unsigned integer in its smallest form
+func appendMsgpackUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= 0x7f:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(v))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xcf), v)
+}
+
+// appendMsgpackFloat appends a float64
+func appendMsgpackFloat(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+// appendMsgpackString appends a string
+func appendMsgpackString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n)) //nolint:gosec // G115: log lines are smaller than 4GB
+	}
+	return append(b, s...)
+}
+
+// appendMsgpackBinary appends a byte slice
+func appendMsgpackBinary(b, data []byte) []byte {
+	n := len(data)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n)) //nolint:gosec // G115: batches are smaller than 4GB
+	}
+	return append(b, data...)
+}
+
+// appendMsgpackArrayHeader appends the header of an array of n elements
+func appendMsgpackArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n)) //nolint:gosec // G115: bounded by memory
+}
+
+// appendMsgpackMapHeader appends the header of a map of n pairs
+func appendMsgpackMapHeader(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n)) //nolint:gosec // G115: bounded by memory
+}
+
+// appendMsgpackEventTime appends a Fluentd EventTime (extension type 0, seconds and nanoseconds)
+func appendMsgpackEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Unix()))          //nolint:gosec // G115: valid until 2106
+	return binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond())) //nolint:gosec // G115: less than a second
+}
+
+// appendMsgpackValue appends the value of a key/value pair, natively for typed fields
+func appendMsgpackValue(b []byte, kv KeyValue) []byte {
+	f, ok := kv.(Field)
+	if !ok {
+		return appendMsgpackAny(b, kv.Value(), 0)
+	}
+
+	switch f.fieldType {
+	case IntType:
+		return appendMsgpackInt(b, f.num)
+	case FloatType:
+		return appendMsgpackFloat(b, f.float())
+	case BoolType:
+		return appendMsgpackBool(b, f.num == 1)
+	case DurationType:
+		// Durations are written as integer nanoseconds, like the JSONEncoder
+		return appendMsgpackInt(b, f.num)
+	case AnyType:
+		return appendMsgpackAny(b, f.obj, 0)
+	case ErrorType, StringType, StringerType, TimeType:
+	}
+	return appendMsgpackString(b, string(f.AppendValue(nil)))
+}
+
+// appendMsgpackAny appends common Go values natively, and anything else in its text form
+func appendMsgpackAny(b []byte, v interface{}, depth int) []byte {
+	switch value := v.(type) {
+	case nil:
+		return appendMsgpackNil(b)
+	case bool:
+		return appendMsgpackBool(b, value)
+	case int:
+		return appendMsgpackInt(b, int64(value))
+	case int8:
+		return appendMsgpackInt(b, int64(value))
+	case int16:
+		return appendMsgpackInt(b, int64(value))
+	case int32:
+		return appendMsgpackInt(b, int64(value))
+	case int64:
+		return appendMsgpackInt(b, value)
+	case uint:
+		return appendMsgpackUint(b, uint64(value))
+	case uint8:
+		return appendMsgpackUint(b, uint64(value))
+	case uint16:
+		return appendMsgpackUint(b, uint64(value))
+	case uint32:
+		return appendMsgpackUint(b, uint64(value))
+	case uint64:
+		return appendMsgpackUint(b, value)
+	case float32:
+		return appendMsgpackFloat(b, float64(value))
+	case float64:
+		return appendMsgpackFloat(b, value)
+	case string:
+		return appendMsgpackString(b, value)
+	case []byte:
+		return appendMsgpackBinary(b, value)
+	case time.Duration:
+		return appendMsgpackInt(b, int64(value))
+	case time.Time:
+		return appendMsgpackString(b, value.Format(time.RFC3339Nano))
+	case error:
+		return appendMsgpackString(b, value.Error())
+	case fmt.Stringer:
+		return appendMsgpackString(b, value.String())
+	case []interface{}:
+		if depth < maxMsgpackDepth {
+			b = appendMsgpackArrayHeader(b, len(value))
+			for _, item := range value {
+				b = appendMsgpackAny(b, item, depth+1)
+			}
+			return b
+		}
+	case map[string]interface{}:
+		if depth < maxMsgpackDepth {
+			b = appendMsgpackMapHeader(b, len(value))
+			for key, item := range value {
+				b = appendMsgpackString(b, key)
+				b = appendMsgpackAny(b, item, depth+1)
+			}
+			return b
+		}
+	}
+	return appendMsgpackString(b, fmt.Sprint(v))
+}
+
+// readMsgpackMapHeader reads the header of a map, returning its number of pairs
+func readMsgpackMapHeader(r io.ByteReader) (int, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case c&0xf0 == 0x80:
+		return int(c & 0x0f), nil
+	case c == 0xde:
+		n, err := readMsgpackUint(r, 2)
+		return int(n), err
+	case c == 0xdf:
+		n, err := readMsgpackUint(r, 4)
+		return int(n), err
+	}
+	return 0, fmt.Errorf("%w: expected a map, got 0x%02x", ErrMsgpack, c)
+}
+
+// readMsgpackString reads a string (or binary) value
+func readMsgpackString(r io.ByteReader) (string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	var n uint64
+	switch {
+	case c&0xe0 == 0xa0:
+		n = uint64(c & 0x1f)
+	case c == 0xd9 || c == 0xc4:
+		n, err = readMsgpackUint(r, 1)
+	case c == 0xda || c == 0xc5:
+		n, err = readMsgpackUint(r, 2)
+	case c == 0xdb || c == 0xc6:
+		n, err = readMsgpackUint(r, 4)
+	default:
+		return "", fmt.Errorf("%w: expected a string, got 0x%02x", ErrMsgpack, c)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	for i := uint64(0); i < n; i++ {
+		if c, err = r.ReadByte(); err != nil {
+			return "", err
+		}
+		buf.WriteByte(c)
+	}
+	return buf.String(), nil
+}
+
+// readMsgpackUint reads a big endian unsigned integer of size bytes
+func readMsgpackUint(r io.ByteReader, size int) (uint64, error) {
+	var n uint64
+	for i := 0; i < size; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
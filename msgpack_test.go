@@ -0,0 +1,285 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// msgpackEventTime is a decoded Fluentd EventTime
+type msgpackEventTime struct {
+	seconds uint32
+	nanos   uint32
+}
+
+// decodeMsgpack decodes a single value (test helper, supports the types written by the encoder)
+func decodeMsgpack(r *bufio.Reader) (interface{}, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	readN := func(n int) ([]byte, error) {
+		buf := make([]byte, n)
+		_, readErr := io.ReadFull(r, buf)
+		return buf, readErr
+	}
+	readLen := func(size int) (int, error) {
+		n, lenErr := readMsgpackUint(r, size)
+		return int(n), lenErr
+	}
+	readArray := func(n int) (interface{}, error) {
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			item, itemErr := decodeMsgpack(r)
+			if itemErr != nil {
+				return nil, itemErr
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	}
+	readMap := func(n int) (interface{}, error) {
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			key, keyErr := decodeMsgpack(r)
+			if keyErr != nil {
+				return nil, keyErr
+			}
+			value, valueErr := decodeMsgpack(r)
+			if valueErr != nil {
+				return nil, valueErr
+			}
+			m[fmt.Sprint(key)] = value
+		}
+		return m, nil
+	}
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return readMap(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return readArray(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		data, readErr := readN(int(c & 0x1f))
+		return string(data), readErr
+	}
+
+	var n int
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		if n, err = readLen(1 << (c - 0xc4)); err != nil {
+			return nil, err
+		}
+		return readN(n)
+	case 0xcb:
+		v, readErr := readMsgpackUint(r, 8)
+		return math.Float64frombits(v), readErr
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, readErr := readMsgpackUint(r, 1<<(c-0xcc))
+		return v, readErr
+	case 0xd0:
+		v, readErr := readMsgpackUint(r, 1)
+		return int64(int8(v)), readErr
+	case 0xd1:
+		v, readErr := readMsgpackUint(r, 2)
+		return int64(int16(v)), readErr
+	case 0xd2:
+		v, readErr := readMsgpackUint(r, 4)
+		return int64(int32(v)), readErr
+	case 0xd3:
+		v, readErr := readMsgpackUint(r, 8)
+		return int64(v), readErr
+	case 0xd7:
+		data, readErr := readN(9)
+		if readErr != nil {
+			return nil, readErr
+		}
+		return msgpackEventTime{binary.BigEndian.Uint32(data[1:5]), binary.BigEndian.Uint32(data[5:])}, nil
+	case 0xd9, 0xda, 0xdb:
+		if n, err = readLen(1 << (c - 0xd9)); err != nil {
+			return nil, err
+		}
+		data, readErr := readN(n)
+		return string(data), readErr
+	case 0xdc, 0xdd:
+		if n, err = readLen(2 << (c - 0xdc)); err != nil {
+			return nil, err
+		}
+		return readArray(n)
+	case 0xde, 0xdf:
+		if n, err = readLen(2 << (c - 0xde)); err != nil {
+			return nil, err
+		}
+		return readMap(n)
+	}
+	return nil, fmt.Errorf("%w: unsupported type 0x%02x", ErrMsgpack, c)
+}
+
+// decodeMsgpackBytes decodes a single value from bytes
+func decodeMsgpackBytes(t *testing.T, data []byte) interface{} {
+	t.Helper()
+	v, err := decodeMsgpack(bufio.NewReader(bytes.NewReader(data)))
+	require.NoError(t, err)
+	return v
+}
+
+// TestAppendMsgpackInt will test the appendMsgpackInt() method
+func TestAppendMsgpackInt(t *testing.T) {
+	tests := []struct {
+		value    int64
+		expected string
+	}{
+		{0, "00"},
+		{127, "7f"},
+		{128, "cc80"},
+		{256, "cd0100"},
+		{65536, "ce00010000"},
+		{math.MaxUint32 + 1, "cf0000000100000000"},
+		{-1, "ff"},
+		{-32, "e0"},
+		{-33, "d0df"},
+		{-129, "d1ff7f"},
+		{-32769, "d2ffff7fff"},
+		{math.MinInt64, "d38000000000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.value), func(t *testing.T) {
+			b := appendMsgpackInt(nil, tt.value)
+			assert.Equal(t, tt.expected, hex.EncodeToString(b))
+
+			decoded := decodeMsgpackBytes(t, b)
+			if tt.value >= 0 {
+				assert.EqualValues(t, tt.value, decoded)
+			} else {
+				assert.Equal(t, tt.value, decoded)
+			}
+		})
+	}
+}
+
+// TestAppendMsgpackString will test the appendMsgpackString() and appendMsgpackBinary() methods
+func TestAppendMsgpackString(t *testing.T) {
+	for _, n := range []int{0, 31, 32, 255, 256, 65535, 65536} {
+		s := strings.Repeat("x", n)
+		b := appendMsgpackString(nil, s)
+		assert.Equal(t, s, decodeMsgpackBytes(t, b), n)
+
+		decoded, err := readMsgpackString(bytes.NewReader(b))
+		require.NoError(t, err)
+		assert.Equal(t, s, decoded)
+
+		b = appendMsgpackBinary(nil, []byte(s))
+		assert.Equal(t, []byte(s), decodeMsgpackBytes(t, b), n)
+	}
+	assert.Equal(t, "a161", hex.EncodeToString(appendMsgpackString(nil, "a")))
+	assert.Equal(t, "c40161", hex.EncodeToString(appendMsgpackBinary(nil, []byte("a"))))
+}
+
+// TestAppendMsgpackHeaders will test the array and map headers
+func TestAppendMsgpackHeaders(t *testing.T) {
+	assert.Equal(t, "93", hex.EncodeToString(appendMsgpackArrayHeader(nil, 3)))
+	assert.Equal(t, "dc0010", hex.EncodeToString(appendMsgpackArrayHeader(nil, 16)))
+	assert.Equal(t, "dd00010000", hex.EncodeToString(appendMsgpackArrayHeader(nil, 65536)))
+	assert.Equal(t, "81", hex.EncodeToString(appendMsgpackMapHeader(nil, 1)))
+	assert.Equal(t, "de0010", hex.EncodeToString(appendMsgpackMapHeader(nil, 16)))
+	assert.Equal(t, "df00010000", hex.EncodeToString(appendMsgpackMapHeader(nil, 65536)))
+
+	n, err := readMsgpackMapHeader(bytes.NewReader([]byte{0xde, 0x00, 0x10}))
+	require.NoError(t, err)
+	assert.Equal(t, 16, n)
+
+	_, err = readMsgpackMapHeader(bytes.NewReader([]byte{0x93}))
+	require.ErrorIs(t, err, ErrMsgpack)
+	_, err = readMsgpackString(bytes.NewReader([]byte{0x93}))
+	require.ErrorIs(t, err, ErrMsgpack)
+	_, err = readMsgpackString(bytes.NewReader([]byte{0xa3, 'a'}))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+// TestAppendMsgpackEventTime will test the appendMsgpackEventTime() method
+func TestAppendMsgpackEventTime(t *testing.T) {
+	b := appendMsgpackEventTime(nil, time.Unix(1704164645, 123456789))
+	assert.Equal(t, "d70065937d25075bcd15", hex.EncodeToString(b))
+	assert.Equal(t, msgpackEventTime{1704164645, 123456789}, decodeMsgpackBytes(t, b))
+}
+
+// TestAppendMsgpackValue will test the appendMsgpackValue() method
+func TestAppendMsgpackValue(t *testing.T) {
+	type custom struct{ A int }
+	tests := []struct {
+		name     string
+		kv       KeyValue
+		expected interface{}
+	}{
+		{"int field", Int("k", -5), int64(-5)},
+		{"float field", Float64("k", 1.5), 1.5},
+		{"bool field", Bool("k", true), true},
+		{"duration field", Duration("k", time.Second), uint64(time.Second)},
+		{"string field", String("k", "v"), "v"},
+		{"error field", Err(errors.New("boom")), "boom"},
+		{"time field", Time("k", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)), "2024-01-02T03:04:05Z"},
+		{"any field", Any("k", []interface{}{1, "a"}), []interface{}{int64(1), "a"}},
+		{"nil parameter", MakeParameter("k", nil), nil},
+		{"bool parameter", MakeParameter("k", false), false},
+		{"int parameters", MakeParameter("k", int8(-1)), int64(-1)},
+		{"uint parameter", MakeParameter("k", uint16(300)), uint64(300)},
+		{"float32 parameter", MakeParameter("k", float32(0.5)), 0.5},
+		{"bytes parameter", MakeParameter("k", []byte{1, 2}), []byte{1, 2}},
+		{"duration parameter", MakeParameter("k", -time.Second), int64(-time.Second)},
+		{"map parameter", MakeParameter("k", map[string]interface{}{"a": true}), map[string]interface{}{"a": true}},
+		{"stringer parameter", MakeParameter("k", INFO), "info"},
+		{"struct parameter", MakeParameter("k", custom{1}), "{1}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, decodeMsgpackBytes(t, appendMsgpackValue(nil, tt.kv)))
+		})
+	}
+}
+
+// TestAppendMsgpackAny_Depth will test the nesting limit of appendMsgpackAny()
+func TestAppendMsgpackAny_Depth(t *testing.T) {
+	nested := []interface{}{}
+	for i := 0; i < maxMsgpackDepth+5; i++ {
+		nested = []interface{}{nested}
+	}
+	assert.NotPanics(t, func() {
+		decodeMsgpackBytes(t, appendMsgpackAny(nil, nested, 0))
+	})
+}
+
+// BenchmarkAppendMsgpackValue benchmarks the appendMsgpackValue() method
+func BenchmarkAppendMsgpackValue(b *testing.B) {
+	kvs := []KeyValue{String("key", "value"), Int("n", 42), Float64("f", 1.5)}
+	buf := make([]byte, 0, 128)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = buf[:0]
+		for _, kv := range kvs {
+			buf = appendMsgpackValue(buf, kv)
+		}
+	}
+}
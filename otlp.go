@@ -0,0 +1,643 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OTLP exporter defaults
+const (
+	DefaultOTLPEndpoint = "http://localhost:4318/v1/logs" // Logs endpoint of a local collector
+
+	contentTypeJSON     = "application/json"
+	contentTypeProtobuf = "application/x-protobuf"
+	maxOTLPDepth        = 32 // Limits how deep nested slices and maps are converted (guards against cycles)
+	otlpScopeName       = "github.com/mrz1836/go-logger"
+)
+
+// OTLP severity numbers (the first number of each range)
+const (
+	otlpSeverityDebug = 5
+	otlpSeverityInfo  = 9
+	otlpSeverityWarn  = 13
+	otlpSeverityError = 17
+	otlpSeverityFatal = 21
+)
+
+// OTLPOption is an optional setting for NewOTLPExporter
+type OTLPOption func(e *OTLPExporter)
+
+// WithOTLPBatchSize sets the maximum number of records per request
+func WithOTLPBatchSize(size int) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.batchSize = size
+	}
+}
+
+// WithOTLPFlushInterval sets the maximum time a record waits for its batch to fill up
+func WithOTLPFlushInterval(interval time.Duration) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.flushInterval = interval
+	}
+}
+
+// WithOTLPGzip compresses the requests (Content-Encoding: gzip)
+func WithOTLPGzip() OTLPOption {
+	return func(e *OTLPExporter) {
+		e.sender.compress = true
+	}
+}
+
+// WithOTLPHeader adds a header to the requests (IE: Authorization)
+func WithOTLPHeader(key, value string) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.sender.headers.Add(key, value)
+	}
+}
+
+// WithOTLPHTTPClient sets the client used for the requests
+func WithOTLPHTTPClient(client *http.Client) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.sender.client = client
+	}
+}
+
+// WithOTLPJSON sends the requests as OTLP/JSON instead of binary protobuf
+func WithOTLPJSON() OTLPOption {
+	return func(e *OTLPExporter) {
+		e.json = true
+		e.sender.contentType = contentTypeJSON
+	}
+}
+
+// WithOTLPMaxRetries sets the number of retries of a batch before it is dropped
+func WithOTLPMaxRetries(retries int) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.maxRetries = retries
+	}
+}
+
+// WithOTLPResource adds attributes to the resource of the records (IE: service.name,
+// service.version, deployment.environment)
+func WithOTLPResource(attributes ...KeyValue) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.resource = append(e.resource, attributes...)
+	}
+}
+
+// OTLPExporter is a Logger posting batches of records to an OpenTelemetry collector with
+// OTLP/HTTP, start sending with go exporter.ProcessQueue()
+type OTLPExporter struct {
+	batchSize     int
+	closed        bool
+	done          chan struct{}
+	exiting       atomic.Bool // Set by Fatal and Panic, the queued batches are only sent once
+	flushInterval time.Duration
+	json          bool
+	maxRetries    int
+	messages      msgQueue     // Encoded LogRecord messages
+	mu            sync.RWMutex // Guards closed
+	resource      []KeyValue
+	resourceBytes []byte // Encoded Resource message
+	scopeBytes    []byte // Encoded InstrumentationScope message
+	sender        httpSender
+	started       atomic.Bool // Set by the first ProcessQueue call
+}
+
+// NewOTLPExporter creates an exporter posting protobuf requests to the logs endpoint of a
+// collector (IE: DefaultOTLPEndpoint), the service.name resource attribute defaults to
+// OTEL_SERVICE_NAME or unknown_service:<process name>
+func NewOTLPExporter(endpoint string, opts ...OTLPOption) *OTLPExporter {
+	e := &OTLPExporter{
+		batchSize:     DefaultHTTPBatchSize,
+		done:          make(chan struct{}),
+		flushInterval: DefaultHTTPFlushInterval,
+		maxRetries:    DefaultHTTPMaxRetries,
+		sender:        newHTTPSender(endpoint, contentTypeProtobuf),
+	}
+	e.messages.messagesToSend = make(chan *bytes.Buffer, 1000)
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.batchSize <= 0 {
+		e.batchSize = DefaultHTTPBatchSize
+	}
+	if e.flushInterval <= 0 {
+		e.flushInterval = DefaultHTTPFlushInterval
+	}
+
+	hasServiceName := false
+	for _, kv := range e.resource {
+		hasServiceName = hasServiceName || kv.Key() == "service.name"
+	}
+	if !hasServiceName {
+		serviceName := os.Getenv("OTEL_SERVICE_NAME")
+		if len(serviceName) == 0 {
+			serviceName = "unknown_service:" + filepath.Base(os.Args[0])
+		}
+		e.resource = append([]KeyValue{String("service.name", serviceName)}, e.resource...)
+	}
+
+	if e.json {
+		var buf bytes.Buffer
+		buf.WriteString(`{"attributes":`)
+		writeOTLPJSONAttributes(&buf, e.resource)
+		buf.WriteByte('}')
+		e.resourceBytes = buf.Bytes()
+		e.scopeBytes = []byte(`{"name":"` + otlpScopeName + `"}`)
+	} else {
+		for _, kv := range e.resource {
+			e.resourceBytes = appendProtoBytes(e.resourceBytes, 1, appendOTLPProtoKeyValue(nil, kv.Key(), otlpFieldValue(kv)))
+		}
+		e.scopeBytes = appendProtoString(nil, 1, otlpScopeName)
+	}
+	return e
+}
+
+// ProcessQueue sends the queued records in batches, until Close is called
+// (the calls after the first one return right away)
+func (e *OTLPExporter) ProcessQueue() {
+	if !e.started.CompareAndSwap(false, true) {
+		return
+	}
+	defer close(e.done)
+	processBatches(e.messages.messagesToSend, e.batchSize, e.flushInterval, e.flush)
+}
+
+// Close stops accepting records and waits for the queued ones to be sent (by ProcessQueue, or
+// right away when it was never started)
+func (e *OTLPExporter) Close() {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return
+	}
+	e.closed = true
+	close(e.messages.messagesToSend)
+	e.mu.Unlock()
+
+	e.ProcessQueue() // Sends the queued records when ProcessQueue was never started
+	<-e.done
+}
+
+// flush sends a batch (dropping it if every retry failed)
+func (e *OTLPExporter) flush(batch []*bytes.Buffer) {
+	if len(batch) == 0 {
+		return
+	}
+	maxRetries := e.maxRetries
+	if e.exiting.Load() {
+		maxRetries = 0
+	}
+	if err := e.sender.sendWithRetry(e.request(batch), maxRetries); err != nil {
+		log.Println("go-logger: failed to export", len(batch), "records to otlp endpoint:", err) //nolint:gosec // G706: error originates from net/http
+	}
+}
+
+// request builds the ExportLogsServiceRequest of encoded records
+func (e *OTLPExporter) request(records []*bytes.Buffer) []byte {
+	if e.json {
+		var buf bytes.Buffer
+		buf.WriteString(`{"resourceLogs":[{"resource":`)
+		buf.Write(e.resourceBytes)
+		buf.WriteString(`,"scopeLogs":[{"scope":`)
+		buf.Write(e.scopeBytes)
+		buf.WriteString(`,"logRecords":[`)
+		for i, record := range records {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(record.Bytes())
+		}
+		buf.WriteString(`]}]}]}`)
+		return buf.Bytes()
+	}
+
+	scopeLogs := appendProtoBytes(nil, 1, e.scopeBytes)
+	for _, record := range records {
+		scopeLogs = appendProtoBytes(scopeLogs, 2, record.Bytes())
+	}
+	resourceLogs := appendProtoBytes(nil, 1, e.resourceBytes)
+	resourceLogs = appendProtoBytes(resourceLogs, 2, scopeLogs)
+	return appendProtoBytes(nil, 1, resourceLogs)
+}
+
+// record encodes a LogRecord, with the location of the entry as code.* attributes
+// and its fields as attributes
+func (e *OTLPExporter) record(t time.Time, severity int, message string, entry *Entry) *bytes.Buffer {
+	observed := time.Now()
+	if t.IsZero() {
+		t = observed
+	}
+	message = strings.TrimSuffix(message, "\n")
+	severityText := otlpSeverityText(severity)
+
+	var attributes []KeyValue
+	if entry != nil {
+		if len(entry.File) > 0 {
+			attributes = append(attributes,
+				String("code.filepath", entry.File),
+				String("code.function", entry.Method),
+				Int("code.lineno", entry.Line),
+			)
+		}
+		attributes = append(attributes, entry.KeyValues()...)
+	}
+
+	if e.json {
+		var buf bytes.Buffer
+		buf.WriteString(`{"timeUnixNano":"`)
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), t.UnixNano(), 10))
+		buf.WriteString(`","observedTimeUnixNano":"`)
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), observed.UnixNano(), 10))
+		buf.WriteString(`","severityNumber":`)
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), int64(severity), 10))
+		buf.WriteString(`,"severityText":"`)
+		buf.WriteString(severityText)
+		buf.WriteString(`","body":{"stringValue":`)
+		writeJSONString(&buf, message)
+		buf.WriteByte('}')
+		if len(attributes) > 0 {
+			buf.WriteString(`,"attributes":`)
+			writeOTLPJSONAttributes(&buf, attributes)
+		}
+		buf.WriteByte('}')
+		return &buf
+	}
+
+	b := appendProtoFixed64(nil, 1, uint64(t.UnixNano())) //nolint:gosec // G115: after 1970
+	b = appendProtoUint(b, 2, uint64(severity))           //nolint:gosec // G115: positive constant
+	b = appendProtoString(b, 3, severityText)
+	b = appendProtoBytes(b, 5, appendProtoString(nil, 1, message))
+	for _, kv := range attributes {
+		b = appendProtoBytes(b, 6, appendOTLPProtoKeyValue(nil, kv.Key(), otlpFieldValue(kv)))
+	}
+	b = appendProtoFixed64(b, 11, uint64(observed.UnixNano())) //nolint:gosec // G115: after 1970
+	return bytes.NewBuffer(b)
+}
+
+// LogEntry implements the EntryLogger interface
+func (e *OTLPExporter) LogEntry(entry *Entry) {
+	e.enqueue(e.record(entry.Time, otlpSeverity(entry.Level), entry.Message, entry), entry.Message)
+}
+
+// Panic overloads built-in method
+func (e *OTLPExporter) Panic(v ...interface{}) {
+	e.exit(fmt.Sprint(v...))
+}
+
+// Panicln overloads built-in method
+func (e *OTLPExporter) Panicln(v ...interface{}) {
+	e.exit(fmt.Sprintln(v...))
+}
+
+// Panicf overloads built-in method
+func (e *OTLPExporter) Panicf(format string, v ...interface{}) {
+	e.exit(fmt.Sprintf(format, v...))
+}
+
+// Print overloads built-in method
+func (e *OTLPExporter) Print(v ...interface{}) {
+	e.write(fmt.Sprint(v...))
+}
+
+// Println overloads built-in method
+func (e *OTLPExporter) Println(v ...interface{}) {
+	e.write(fmt.Sprintln(v...))
+}
+
+// Printf overloads built-in method
+func (e *OTLPExporter) Printf(format string, v ...interface{}) {
+	e.write(fmt.Sprintf(format, v...))
+}
+
+// Fatal overloads built-in method
+func (e *OTLPExporter) Fatal(v ...interface{}) {
+	e.exit(fmt.Sprint(v...))
+}
+
+// Fatalln overloads built-in method
+func (e *OTLPExporter) Fatalln(v ...interface{}) {
+	e.exit(fmt.Sprintln(v...))
+}
+
+// Fatalf overloads built-in method
+func (e *OTLPExporter) Fatalf(format string, v ...interface{}) {
+	e.exit(fmt.Sprintf(format, v...))
+}
+
+// write queues a message as an INFO record
+func (e *OTLPExporter) write(message string) {
+	e.enqueue(e.record(time.Now(), otlpSeverityInfo, message, nil), message)
+}
+
+// enqueue queues a record (or prints its message with the log package once closed)
+func (e *OTLPExporter) enqueue(record *bytes.Buffer, message string) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		log.Print(message)
+		return
+	}
+	e.messages.Enqueue(record)
+}
+
+// exit sends the queued records and then the line, before exiting (used by Fatal and Panic)
+//
+// The records are sent without retrying, and the queue is given up after exitTimeout
+func (e *OTLPExporter) exit(line string) {
+	e.exiting.Store(true)
+	if !closeWithin(e.Close, exitTimeout) {
+		log.Println("go-logger: timed out exporting the queued records to otlp endpoint")
+	}
+	e.sendOne(line)
+	os.Exit(1)
+}
+
+// sendOne sends one FATAL record right away, bypassing the queue (used before exiting)
+func (e *OTLPExporter) sendOne(message string) {
+	record := e.record(time.Now(), otlpSeverityFatal, message, nil)
+	if err := e.sender.sendWithRetry(e.request([]*bytes.Buffer{record}), 0); err != nil {
+		log.Print(message)
+		log.Println("go-logger: failed to export to otlp endpoint:", err) //nolint:gosec // G706: error originates from net/http
+	}
+}
+
+// otlpSeverity returns the severity number of a log level
+func otlpSeverity(level LogLevel) int {
+	switch level {
+	case DEBUG:
+		return otlpSeverityDebug
+	case INFO:
+		return otlpSeverityInfo
+	case WARN:
+		return otlpSeverityWarn
+	case ERROR:
+		return otlpSeverityError
+	}
+	return otlpSeverityInfo
+}
+
+// otlpSeverityText returns the short name of a severity number
+func otlpSeverityText(severity int) string {
+	switch {
+	case severity >= otlpSeverityFatal:
+		return "FATAL"
+	case severity >= otlpSeverityError:
+		return "ERROR"
+	case severity >= otlpSeverityWarn:
+		return "WARN"
+	case severity >= otlpSeverityInfo:
+		return "INFO"
+	case severity >= otlpSeverityDebug:
+		return "DEBUG"
+	}
+	return "TRACE"
+}
+
+// otlpKind is the kind of value held by an AnyValue
+type otlpKind uint8
+
+// AnyValue kinds
+const (
+	otlpEmpty otlpKind = iota
+	otlpString
+	otlpBool
+	otlpInt
+	otlpDouble
+	otlpArray
+	otlpKVList
+	otlpBytes
+)
+
+// otlpValue is an attribute value, converted once and encoded as protobuf or JSON
+type otlpValue struct {
+	kind   otlpKind
+	str    string      // otlpString
+	num    int64       // otlpInt, otlpBool (1 for true)
+	float  float64     // otlpDouble
+	bytes  []byte      // otlpBytes
+	keys   []string    // otlpKVList
+	values []otlpValue // otlpArray, otlpKVList
+}
+
+// otlpFieldValue converts the value of a key/value pair, natively for typed fields
+func otlpFieldValue(kv KeyValue) otlpValue {
+	f, ok := kv.(Field)
+	if !ok {
+		return otlpAnyValue(kv.Value(), 0)
+	}
+
+	switch f.fieldType {
+	case IntType, DurationType:
+		// Durations are written as integer nanoseconds, like the JSONEncoder
+		return otlpValue{kind: otlpInt, num: f.num}
+	case FloatType:
+		return otlpValue{kind: otlpDouble, float: f.float()}
+	case BoolType:
+		return otlpValue{kind: otlpBool, num: f.num}
+	case AnyType:
+		return otlpAnyValue(f.obj, 0)
+	case ErrorType, StringType, StringerType, TimeType:
+	}
+	return otlpValue{kind: otlpString, str: string(f.AppendValue(nil))}
+}
+
+// otlpAnyValue converts common Go values natively, and anything else to its text form
+func otlpAnyValue(v interface{}, depth int) otlpValue {
+	switch value := v.(type) {
+	case nil:
+		return otlpValue{}
+	case bool:
+		if value {
+			return otlpValue{kind: otlpBool, num: 1}
+		}
+		return otlpValue{kind: otlpBool}
+	case int:
+		return otlpValue{kind: otlpInt, num: int64(value)}
+	case int8:
+		return otlpValue{kind: otlpInt, num: int64(value)}
+	case int16:
+		return otlpValue{kind: otlpInt, num: int64(value)}
+	case int32:
+		return otlpValue{kind: otlpInt, num: int64(value)}
+	case int64:
+		return otlpValue{kind: otlpInt, num: value}
+	case uint8:
+		return otlpValue{kind: otlpInt, num: int64(value)}
+	case uint16:
+		return otlpValue{kind: otlpInt, num: int64(value)}
+	case uint32:
+		return otlpValue{kind: otlpInt, num: int64(value)}
+	case uint:
+		if uint64(value) <= math.MaxInt64 {
+			return otlpValue{kind: otlpInt, num: int64(value)}
+		}
+	case uint64:
+		if value <= math.MaxInt64 {
+			return otlpValue{kind: otlpInt, num: int64(value)}
+		}
+	case float32:
+		return otlpValue{kind: otlpDouble, float: float64(value)}
+	case float64:
+		return otlpValue{kind: otlpDouble, float: value}
+	case string:
+		return otlpValue{kind: otlpString, str: value}
+	case []byte:
+		return otlpValue{kind: otlpBytes, bytes: value}
+	case time.Duration:
+		return otlpValue{kind: otlpInt, num: int64(value)}
+	case time.Time:
+		return otlpValue{kind: otlpString, str: value.Format(time.RFC3339Nano)}
+	case error:
+		return otlpValue{kind: otlpString, str: value.Error()}
+	case fmt.Stringer:
+		return otlpValue{kind: otlpString, str: value.String()}
+	case []interface{}:
+		if depth < maxOTLPDepth {
+			array := otlpValue{kind: otlpArray, values: make([]otlpValue, 0, len(value))}
+			for _, item := range value {
+				array.values = append(array.values, otlpAnyValue(item, depth+1))
+			}
+			return array
+		}
+	case map[string]interface{}:
+		if depth < maxOTLPDepth {
+			list := otlpValue{kind: otlpKVList, keys: make([]string, 0, len(value))}
+			for key := range value {
+				list.keys = append(list.keys, key)
+			}
+			sort.Strings(list.keys)
+			for _, key := range list.keys {
+				list.values = append(list.values, otlpAnyValue(value[key], depth+1))
+			}
+			return list
+		}
+	}
+	return otlpValue{kind: otlpString, str: fmt.Sprint(v)}
+}
+
+// appendOTLPProtoKeyValue appends the fields of a KeyValue message
+func appendOTLPProtoKeyValue(b []byte, key string, v otlpValue) []byte {
+	b = appendProtoString(b, 1, key)
+	return appendProtoBytes(b, 2, appendOTLPProtoValue(nil, v))
+}
+
+// appendOTLPProtoValue appends the fields of an AnyValue message
+func appendOTLPProtoValue(b []byte, v otlpValue) []byte {
+	switch v.kind {
+	case otlpEmpty:
+	case otlpString:
+		b = appendProtoString(b, 1, v.str)
+	case otlpBool:
+		b = appendProtoUint(b, 2, uint64(v.num)) //nolint:gosec // G115: 0 or 1
+	case otlpInt:
+		b = appendProtoInt(b, 3, v.num)
+	case otlpDouble:
+		b = appendProtoDouble(b, 4, v.float)
+	case otlpArray:
+		var array []byte
+		for _, item := range v.values {
+			array = appendProtoBytes(array, 1, appendOTLPProtoValue(nil, item))
+		}
+		b = appendProtoBytes(b, 5, array)
+	case otlpKVList:
+		var list []byte
+		for i, item := range v.values {
+			list = appendProtoBytes(list, 1, appendOTLPProtoKeyValue(nil, v.keys[i], item))
+		}
+		b = appendProtoBytes(b, 6, list)
+	case otlpBytes:
+		b = appendProtoBytes(b, 7, v.bytes)
+	}
+	return b
+}
+
+// writeOTLPJSONAttributes writes a list of KeyValue objects
+func writeOTLPJSONAttributes(buf *bytes.Buffer, attributes []KeyValue) {
+	buf.WriteByte('[')
+	for i, kv := range attributes {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeOTLPJSONKeyValue(buf, kv.Key(), otlpFieldValue(kv))
+	}
+	buf.WriteByte(']')
+}
+
+// writeOTLPJSONKeyValue writes a KeyValue object
+func writeOTLPJSONKeyValue(buf *bytes.Buffer, key string, v otlpValue) {
+	buf.WriteString(`{"key":`)
+	writeJSONString(buf, key)
+	buf.WriteString(`,"value":`)
+	writeOTLPJSONValue(buf, v)
+	buf.WriteByte('}')
+}
+
+// writeOTLPJSONValue writes an AnyValue object (with the protobuf JSON mapping: 64 bit
+// integers are strings and bytes are base64)
+func writeOTLPJSONValue(buf *bytes.Buffer, v otlpValue) {
+	switch v.kind {
+	case otlpEmpty:
+		buf.WriteString(`{}`)
+	case otlpString:
+		buf.WriteString(`{"stringValue":`)
+		writeJSONString(buf, v.str)
+		buf.WriteByte('}')
+	case otlpBool:
+		buf.WriteString(`{"boolValue":`)
+		buf.WriteString(strconv.FormatBool(v.num == 1))
+		buf.WriteByte('}')
+	case otlpInt:
+		buf.WriteString(`{"intValue":"`)
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), v.num, 10))
+		buf.WriteString(`"}`)
+	case otlpDouble:
+		buf.WriteString(`{"doubleValue":`)
+		switch {
+		case math.IsNaN(v.float):
+			buf.WriteString(`"NaN"`)
+		case math.IsInf(v.float, 1):
+			buf.WriteString(`"Infinity"`)
+		case math.IsInf(v.float, -1):
+			buf.WriteString(`"-Infinity"`)
+		default:
+			buf.Write(strconv.AppendFloat(buf.AvailableBuffer(), v.float, 'g', -1, 64))
+		}
+		buf.WriteByte('}')
+	case otlpArray:
+		buf.WriteString(`{"arrayValue":{"values":[`)
+		for i, item := range v.values {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeOTLPJSONValue(buf, item)
+		}
+		buf.WriteString(`]}}`)
+	case otlpKVList:
+		buf.WriteString(`{"kvlistValue":{"values":[`)
+		for i, item := range v.values {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeOTLPJSONKeyValue(buf, v.keys[i], item)
+		}
+		buf.WriteString(`]}}`)
+	case otlpBytes:
+		buf.WriteString(`{"bytesValue":"`)
+		buf.WriteString(base64.StdEncoding.EncodeToString(v.bytes))
+		buf.WriteString(`"}`)
+	}
+}
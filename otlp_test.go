@@ -0,0 +1,439 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// otlpJSONRequest is the part of an OTLP/JSON ExportLogsServiceRequest checked by the tests
+type otlpJSONRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpJSONKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			Scope struct {
+				Name string `json:"name"`
+			} `json:"scope"`
+			LogRecords []otlpJSONRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+// otlpJSONRecord is an OTLP/JSON LogRecord
+type otlpJSONRecord struct {
+	TimeUnixNano         string                 `json:"timeUnixNano"`
+	ObservedTimeUnixNano string                 `json:"observedTimeUnixNano"`
+	SeverityNumber       int                    `json:"severityNumber"`
+	SeverityText         string                 `json:"severityText"`
+	Body                 map[string]interface{} `json:"body"`
+	Attributes           []otlpJSONKeyValue     `json:"attributes"`
+}
+
+// otlpJSONKeyValue is an OTLP/JSON KeyValue
+type otlpJSONKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+// records decodes the records of an OTLP/JSON request body
+func (r otlpJSONRequest) records(t *testing.T) []otlpJSONRecord {
+	t.Helper()
+	require.Len(t, r.ResourceLogs, 1)
+	require.Len(t, r.ResourceLogs[0].ScopeLogs, 1)
+	return r.ResourceLogs[0].ScopeLogs[0].LogRecords
+}
+
+// decodeOTLPJSON decodes an OTLP/JSON request body
+func decodeOTLPJSON(t *testing.T, body string) otlpJSONRequest {
+	t.Helper()
+	var request otlpJSONRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &request), body)
+	return request
+}
+
+// newTestOTLPExporter creates an exporter for a test server, recording the retry delays
+func newTestOTLPExporter(url string, delays *[]time.Duration, opts ...OTLPOption) *OTLPExporter {
+	e := NewOTLPExporter(url, opts...)
+	e.sender.sleep = func(d time.Duration) { *delays = append(*delays, d) }
+	return e
+}
+
+// TestNewOTLPExporter will test the NewOTLPExporter() method
+func TestNewOTLPExporter(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		t.Setenv("OTEL_SERVICE_NAME", "")
+		e := NewOTLPExporter(DefaultOTLPEndpoint)
+		assert.Equal(t, DefaultHTTPBatchSize, e.batchSize)
+		assert.Equal(t, DefaultHTTPFlushInterval, e.flushInterval)
+		assert.Equal(t, DefaultHTTPMaxRetries, e.maxRetries)
+		assert.False(t, e.json)
+		assert.Equal(t, contentTypeProtobuf, e.sender.contentType)
+		assert.Equal(t, DefaultOTLPEndpoint, e.sender.url)
+		require.Len(t, e.resource, 1)
+		assert.Equal(t, "service.name", e.resource[0].Key())
+		assert.Contains(t, e.resource[0].Value(), "unknown_service:")
+	})
+
+	t.Run("options", func(t *testing.T) {
+		client := &http.Client{}
+		e := NewOTLPExporter(
+			DefaultOTLPEndpoint,
+			WithOTLPBatchSize(5),
+			WithOTLPFlushInterval(time.Minute),
+			WithOTLPGzip(),
+			WithOTLPHeader("Authorization", "Bearer token"),
+			WithOTLPHTTPClient(client),
+			WithOTLPJSON(),
+			WithOTLPMaxRetries(2),
+			WithOTLPResource(String("service.name", "api"), String("service.version", "1.0.0")),
+		)
+		assert.Equal(t, 5, e.batchSize)
+		assert.Equal(t, time.Minute, e.flushInterval)
+		assert.True(t, e.sender.compress)
+		assert.Equal(t, "Bearer token", e.sender.headers.Get("Authorization"))
+		assert.Equal(t, client, e.sender.client)
+		assert.True(t, e.json)
+		assert.Equal(t, contentTypeJSON, e.sender.contentType)
+		assert.Equal(t, 2, e.maxRetries)
+		assert.Equal(t, []KeyValue{String("service.name", "api"), String("service.version", "1.0.0")}, e.resource)
+	})
+
+	t.Run("service name from the environment", func(t *testing.T) {
+		t.Setenv("OTEL_SERVICE_NAME", "worker")
+		e := NewOTLPExporter(DefaultOTLPEndpoint, WithOTLPBatchSize(0), WithOTLPFlushInterval(-time.Second))
+		assert.Equal(t, []KeyValue{String("service.name", "worker")}, e.resource)
+		assert.Equal(t, DefaultHTTPBatchSize, e.batchSize)
+		assert.Equal(t, DefaultHTTPFlushInterval, e.flushInterval)
+	})
+}
+
+// TestOTLPExporter_JSON will test exporting OTLP/JSON requests
+func TestOTLPExporter_JSON(t *testing.T) {
+	server := newTestHTTPServer(t)
+	var delays []time.Duration
+	e := newTestOTLPExporter(server.URL, &delays, WithOTLPJSON(), WithOTLPBatchSize(3),
+		WithOTLPFlushInterval(time.Hour), WithOTLPResource(String("service.name", "api")))
+	go e.ProcessQueue()
+
+	previous := GetImplementation()
+	SetImplementation(e)
+	Data(2, WARN, "first", Int("n", 1), Float64("f", 1.5), Bool("ok", true), String("s", "v"),
+		Any("list", []interface{}{1, "a"}), Any("map", map[string]interface{}{"b": nil, "a": []byte("x")}))
+	NoFileData(ERROR, "second", Float64("nan", math.NaN()), Duration("d", time.Second))
+	SetImplementation(previous)
+	e.Println("third")
+	e.Print("fourth")
+	e.Close()
+
+	require.Len(t, server.requests, 2)
+	assert.Equal(t, contentTypeJSON, server.requests[0].header.Get("Content-Type"))
+
+	request := decodeOTLPJSON(t, server.bodies()[0])
+	assert.Equal(t, []otlpJSONKeyValue{{"service.name", map[string]interface{}{"stringValue": "api"}}},
+		request.ResourceLogs[0].Resource.Attributes)
+	assert.Equal(t, otlpScopeName, request.ResourceLogs[0].ScopeLogs[0].Scope.Name)
+
+	records := request.records(t)
+	require.Len(t, records, 3)
+	assert.Equal(t, otlpSeverityWarn, records[0].SeverityNumber)
+	assert.Equal(t, "WARN", records[0].SeverityText)
+	assert.Equal(t, map[string]interface{}{"stringValue": "first"}, records[0].Body)
+	assert.NotEmpty(t, records[0].TimeUnixNano)
+	assert.NotEmpty(t, records[0].ObservedTimeUnixNano)
+
+	attributes := make(map[string]map[string]interface{})
+	for _, kv := range records[0].Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	assert.Contains(t, attributes["code.filepath"]["stringValue"], "otlp_test.go")
+	assert.Contains(t, attributes["code.function"]["stringValue"], "TestOTLPExporter_JSON")
+	assert.NotEmpty(t, attributes["code.lineno"]["intValue"])
+	assert.Equal(t, map[string]interface{}{"intValue": "1"}, attributes["n"])
+	assert.Equal(t, map[string]interface{}{"doubleValue": 1.5}, attributes["f"])
+	assert.Equal(t, map[string]interface{}{"boolValue": true}, attributes["ok"])
+	assert.Equal(t, map[string]interface{}{"stringValue": "v"}, attributes["s"])
+	assert.Equal(t, map[string]interface{}{"arrayValue": map[string]interface{}{"values": []interface{}{
+		map[string]interface{}{"intValue": "1"}, map[string]interface{}{"stringValue": "a"},
+	}}}, attributes["list"])
+	assert.Equal(t, map[string]interface{}{"kvlistValue": map[string]interface{}{"values": []interface{}{
+		map[string]interface{}{"key": "a", "value": map[string]interface{}{"bytesValue": "eA=="}},
+		map[string]interface{}{"key": "b", "value": map[string]interface{}{}},
+	}}}, attributes["map"])
+
+	assert.Equal(t, otlpSeverityError, records[1].SeverityNumber)
+	assert.Equal(t, []otlpJSONKeyValue{
+		{"nan", map[string]interface{}{"doubleValue": "NaN"}},
+		{"d", map[string]interface{}{"intValue": "1000000000"}},
+	}, records[1].Attributes)
+
+	assert.Equal(t, otlpSeverityInfo, records[2].SeverityNumber)
+	assert.Equal(t, map[string]interface{}{"stringValue": "third"}, records[2].Body)
+	assert.Empty(t, records[2].Attributes)
+
+	records = decodeOTLPJSON(t, server.bodies()[1]).records(t)
+	require.Len(t, records, 1)
+	assert.Equal(t, map[string]interface{}{"stringValue": "fourth"}, records[0].Body)
+	assert.Empty(t, delays)
+}
+
+// TestOTLPExporter_Protobuf will test exporting binary protobuf requests
+func TestOTLPExporter_Protobuf(t *testing.T) {
+	server := newTestHTTPServer(t)
+	var delays []time.Duration
+	e := newTestOTLPExporter(server.URL, &delays, WithOTLPGzip(), WithOTLPResource(String("service.name", "api")))
+	go e.ProcessQueue()
+
+	when := time.Unix(1704164645, 123456789)
+	e.LogEntry(&Entry{Time: when, Level: DEBUG, Message: "first", File: "main.go", Method: "main.main", Line: 7,
+		Fields: []KeyValue{Int("n", -1), Bool("ok", false), Float64("f", 0.5)}})
+	e.Println("second")
+	e.Close()
+
+	require.Len(t, server.requests, 1)
+	assert.Equal(t, contentTypeProtobuf, server.requests[0].header.Get("Content-Type"))
+	assert.Equal(t, "gzip", server.requests[0].header.Get("Content-Encoding"))
+
+	// ExportLogsServiceRequest > ResourceLogs > (Resource, ScopeLogs > (Scope, LogRecords))
+	request := decodeProto(t, []byte(server.bodies()[0]))
+	require.Len(t, request, 1)
+	resourceLogs := decodeProto(t, request[0].data)
+	resource := decodeProto(t, protoFields(resourceLogs, 1)[0].data)
+	serviceName := decodeProto(t, resource[0].data)
+	assert.Equal(t, "service.name", string(serviceName[0].data))
+	assert.Equal(t, "api", string(decodeProto(t, serviceName[1].data)[0].data))
+
+	scopeLogs := decodeProto(t, protoFields(resourceLogs, 2)[0].data)
+	scope := decodeProto(t, protoFields(scopeLogs, 1)[0].data)
+	assert.Equal(t, otlpScopeName, string(scope[0].data))
+
+	records := protoFields(scopeLogs, 2)
+	require.Len(t, records, 2)
+
+	first := decodeProto(t, records[0].data)
+	assert.Equal(t, uint64(when.UnixNano()), protoFields(first, 1)[0].value)
+	assert.Equal(t, uint64(otlpSeverityDebug), protoFields(first, 2)[0].value)
+	assert.Equal(t, "DEBUG", string(protoFields(first, 3)[0].data))
+	assert.Equal(t, []protoField{{number: 1, data: []byte("first")}}, decodeProto(t, protoFields(first, 5)[0].data))
+	assert.NotZero(t, protoFields(first, 11)[0].value)
+
+	attributes := make(map[string]protoField)
+	for _, attribute := range protoFields(first, 6) {
+		kv := decodeProto(t, attribute.data)
+		attributes[string(kv[0].data)] = decodeProto(t, kv[1].data)[0]
+	}
+	assert.Equal(t, protoField{number: 1, data: []byte("main.go")}, attributes["code.filepath"])
+	assert.Equal(t, protoField{number: 1, data: []byte("main.main")}, attributes["code.function"])
+	assert.Equal(t, protoField{number: 3, value: 7}, attributes["code.lineno"])
+	assert.Equal(t, protoField{number: 3, value: math.MaxUint64}, attributes["n"])
+	assert.Equal(t, protoField{number: 2, value: 0}, attributes["ok"])
+	assert.Equal(t, protoField{number: 4, value: math.Float64bits(0.5)}, attributes["f"])
+
+	second := decodeProto(t, records[1].data)
+	assert.Equal(t, uint64(otlpSeverityInfo), protoFields(second, 2)[0].value)
+	assert.Equal(t, []protoField{{number: 1, data: []byte("second")}}, decodeProto(t, protoFields(second, 5)[0].data))
+	assert.Empty(t, protoFields(second, 6))
+}
+
+// TestOTLPExporter_InvalidUTF8 will test that the protobuf strings are valid UTF-8
+func TestOTLPExporter_InvalidUTF8(t *testing.T) {
+	server := newTestHTTPServer(t)
+	var delays []time.Duration
+	e := newTestOTLPExporter(server.URL, &delays)
+	go e.ProcessQueue()
+
+	e.LogEntry(&Entry{Level: INFO, Message: "bad \xff message", Fields: []KeyValue{String("bad\xfekey", "bad \xc3 value")}})
+	e.Close()
+
+	require.Len(t, server.requests, 1)
+	request := decodeProto(t, []byte(server.bodies()[0]))
+	resourceLogs := decodeProto(t, request[0].data)
+	scopeLogs := decodeProto(t, protoFields(resourceLogs, 2)[0].data)
+	record := decodeProto(t, protoFields(scopeLogs, 2)[0].data)
+	assert.Equal(t, "bad \uFFFD message", string(decodeProto(t, protoFields(record, 5)[0].data)[0].data))
+
+	kv := decodeProto(t, protoFields(record, 6)[0].data)
+	assert.Equal(t, "bad\uFFFDkey", string(kv[0].data))
+	assert.Equal(t, "bad \uFFFD value", string(decodeProto(t, kv[1].data)[0].data))
+}
+
+// TestOTLPExporter_Retry will test retrying failed batches
+func TestOTLPExporter_Retry(t *testing.T) {
+	server := newTestHTTPServer(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	var delays []time.Duration
+	e := newTestOTLPExporter(server.URL, &delays, WithOTLPMaxRetries(3))
+	go e.ProcessQueue()
+
+	e.Println("retry")
+	e.Close()
+	e.Close()
+	e.Println("closed")
+
+	assert.Equal(t, []time.Duration{RetryDelay, 3 * time.Second}, delays)
+	assert.Len(t, server.bodies(), 3)
+}
+
+// TestOTLPExporter_Close will test closing an exporter whose queue was never processed
+func TestOTLPExporter_Close(t *testing.T) {
+	server := newTestHTTPServer(t)
+	var delays []time.Duration
+	e := newTestOTLPExporter(server.URL, &delays, WithOTLPJSON())
+	e.Println("queued")
+	e.Close()
+	go e.ProcessQueue() // Returns right away
+
+	require.Len(t, server.bodies(), 1)
+	records := decodeOTLPJSON(t, server.bodies()[0]).records(t)
+	assert.Equal(t, map[string]interface{}{"stringValue": "queued"}, records[0].Body)
+}
+
+// TestOTLPSeverity will test the otlpSeverity() and otlpSeverityText() methods
+func TestOTLPSeverity(t *testing.T) {
+	tests := []struct {
+		level    LogLevel
+		severity int
+		text     string
+	}{
+		{DEBUG, 5, "DEBUG"},
+		{INFO, 9, "INFO"},
+		{WARN, 13, "WARN"},
+		{ERROR, 17, "ERROR"},
+		{LogLevel(42), 9, "INFO"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			assert.Equal(t, tt.severity, otlpSeverity(tt.level))
+			assert.Equal(t, tt.text, otlpSeverityText(tt.severity))
+		})
+	}
+	assert.Equal(t, "FATAL", otlpSeverityText(otlpSeverityFatal))
+	assert.Equal(t, "TRACE", otlpSeverityText(1))
+}
+
+// TestOTLPAnyValue will test the otlpAnyValue() method
+func TestOTLPAnyValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected otlpValue
+	}{
+		{"nil", nil, otlpValue{}},
+		{"uint32", uint32(7), otlpValue{kind: otlpInt, num: 7}},
+		{"large uint64", uint64(math.MaxUint64), otlpValue{kind: otlpString, str: "18446744073709551615"}},
+		{"float32", float32(0.5), otlpValue{kind: otlpDouble, float: 0.5}},
+		{"duration", time.Second, otlpValue{kind: otlpInt, num: int64(time.Second)}},
+		{"time", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), otlpValue{kind: otlpString, str: "2024-01-02T03:04:05Z"}},
+		{"error", errors.New("boom"), otlpValue{kind: otlpString, str: "boom"}},
+		{"stringer", INFO, otlpValue{kind: otlpString, str: "info"}},
+		{"struct", struct{ A int }{1}, otlpValue{kind: otlpString, str: "{1}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, otlpAnyValue(tt.value, 0))
+		})
+	}
+
+	t.Run("depth", func(t *testing.T) {
+		nested := []interface{}{}
+		for i := 0; i < maxOTLPDepth+5; i++ {
+			nested = []interface{}{nested}
+		}
+		assert.NotPanics(t, func() { appendOTLPProtoValue(nil, otlpAnyValue(nested, 0)) })
+	})
+}
+
+// TestOTLPExporter_Panic will test the Panic() method
+func TestOTLPExporter_Panic(t *testing.T) {
+	if url := os.Getenv("EXIT_FUNCTION_URL"); len(url) > 0 {
+		NewOTLPExporter(url, WithOTLPJSON()).Panicf("panicf %d", 1)
+		return
+	}
+	server := newTestHTTPServer(t)
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestOTLPExporter_Panic") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION_URL="+server.URL)
+	err := cmd.Run()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		require.Len(t, server.bodies(), 1)
+		records := decodeOTLPJSON(t, server.bodies()[0]).records(t)
+		assert.Equal(t, otlpSeverityFatal, records[0].SeverityNumber)
+		assert.Equal(t, map[string]interface{}{"stringValue": "panicf 1"}, records[0].Body)
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestOTLPExporter_Fatal will test the Fatal() method
+func TestOTLPExporter_Fatal(t *testing.T) {
+	if url := os.Getenv("EXIT_FUNCTION_URL"); len(url) > 0 {
+		e := NewOTLPExporter(url, WithOTLPJSON(), WithOTLPFlushInterval(time.Hour))
+		go e.ProcessQueue()
+		e.Println("queued")
+		e.Fatalf("fatal %d", 1)
+		return
+	}
+	server := newTestHTTPServer(t)
+
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestOTLPExporter_Fatal") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION_URL="+server.URL)
+	err := cmd.Run()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		require.Len(t, server.bodies(), 2)
+		assert.Equal(t, map[string]interface{}{"stringValue": "queued"}, decodeOTLPJSON(t, server.bodies()[0]).records(t)[0].Body)
+		records := decodeOTLPJSON(t, server.bodies()[1]).records(t)
+		assert.Equal(t, "FATAL", records[0].SeverityText)
+		assert.Equal(t, map[string]interface{}{"stringValue": "fatal 1"}, records[0].Body)
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestOTLPExporter_FatalFailingEndpoint will test that Fatal() doesn't retry when the collector fails
+func TestOTLPExporter_FatalFailingEndpoint(t *testing.T) {
+	if url := os.Getenv("EXIT_FUNCTION_URL"); len(url) > 0 {
+		e := NewOTLPExporter(url, WithOTLPJSON(), WithOTLPFlushInterval(time.Hour))
+		go e.ProcessQueue()
+		e.Println("queued")
+		e.Fatalf("fatal %d", 1)
+		return
+	}
+	server := newTestHTTPServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable,
+		http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	start := time.Now()
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestOTLPExporter_FatalFailingEndpoint") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION_URL="+server.URL)
+	err := cmd.Run()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		assert.Less(t, time.Since(start), exitTimeout)
+		assert.Len(t, server.bodies(), 2)
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// BenchmarkOTLPExporter_record benchmarks the record() method
+func BenchmarkOTLPExporter_record(b *testing.B) {
+	e := NewOTLPExporter(DefaultOTLPEndpoint)
+	entry := &Entry{Level: INFO, Message: "benchmark", File: "file.go", Method: "Method", Line: 10,
+		Fields: []KeyValue{String("key", "value"), Int("n", 1)}}
+	now := time.Now()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = e.record(now, otlpSeverityInfo, entry.Message, entry)
+	}
+}
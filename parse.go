@@ -0,0 +1,311 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MaxScanLineSize is the longest line the Scanner will read (large SQL statements, etc.)
+const MaxScanLineSize = 1024 * 1024
+
+var (
+	// ErrInvalidDataLine is returned when a line is not in the Data() format
+	ErrInvalidDataLine = errors.New("invalid data line")
+
+	// ErrUnknownLogLevel is returned when a log level name is not recognized
+	ErrUnknownLogLevel = errors.New("unknown log level")
+)
+
+// DataLine is a log line produced by Data() or NoFileData(), parsed back into its parts
+type DataLine struct {
+	Prefix  string      `json:"prefix,omitempty"`
+	Level   LogLevel    `json:"level"`
+	File    string      `json:"file,omitempty"`
+	Method  string      `json:"method,omitempty"`
+	Line    int         `json:"line,omitempty"`
+	Message string      `json:"message"`
+	Params  []Parameter `json:"params,omitempty"`
+}
+
+// Param returns the value of the first parameter with the given key
+func (d *DataLine) Param(key string) (string, bool) {
+	for _, p := range d.Params {
+		if p.K == key {
+			return fmt.Sprint(p.V), true
+		}
+	}
+	return "", false
+}
+
+// Entry converts the parsed line into an Entry, so it can be re-encoded
+func (d *DataLine) Entry() *Entry {
+	fields := make([]KeyValue, 0, len(d.Params))
+	for i := range d.Params {
+		fields = append(fields, &d.Params[i])
+	}
+	return &Entry{
+		Level:   d.Level,
+		File:    d.File,
+		Method:  d.Method,
+		Line:    d.Line,
+		Message: d.Message,
+		Fields:  fields,
+	}
+}
+
+// ParseDataLine parses a line in the key="value" format produced by Data() and NoFileData()
+//
+// Anything before the leading type="..." pair (a log timestamp, a Log Entries token, etc.)
+// is kept as the Prefix. Values may contain quotes and backslashes, which are returned as
+// Data() wrote them (nothing is unescaped), and the remaining key/value pairs are returned
+// as parameters in the order they appear.
+func ParseDataLine(line string) (*DataLine, error) {
+	line = strings.TrimRight(line, "\r\n")
+
+	start := findDataStart(line)
+	if start < 0 {
+		return nil, fmt.Errorf("%w: missing type field", ErrInvalidDataLine)
+	}
+
+	d := &DataLine{Prefix: strings.TrimSpace(line[:start])}
+	rest := line[start:]
+	for {
+		rest = strings.TrimLeft(rest, " ")
+		if len(rest) == 0 {
+			break
+		}
+
+		key, value, remaining, err := nextPair(rest)
+		if err != nil {
+			return nil, err
+		}
+		rest = remaining
+
+		switch key {
+		case "type":
+			if d.Level, err = ParseLogLevel(value); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidDataLine, err)
+			}
+		case "file":
+			d.File = value
+		case "method":
+			d.Method = value
+		case "line":
+			if d.Line, err = strconv.Atoi(value); err != nil {
+				return nil, fmt.Errorf("%w: bad line number %q", ErrInvalidDataLine, value)
+			}
+		case "message":
+			d.Message = value
+		default:
+			d.Params = append(d.Params, Parameter{K: key, V: value})
+		}
+	}
+
+	return d, nil
+}
+
+// ParseJSONLine parses a line written by the JSONEncoder
+//
+// The "time" key is kept as the Prefix, and the remaining keys are returned as
+// parameters in the order they appear (numbers are kept as json.Number)
+func ParseJSONLine(line string) (*DataLine, error) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, fmt.Errorf("%w: not a JSON object", ErrInvalidDataLine)
+	}
+
+	d := &DataLine{}
+	hasLevel := false
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidDataLine, err)
+		}
+		key, _ := t.(string)
+
+		var value interface{}
+		if err = dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidDataLine, err)
+		}
+
+		switch key {
+		case "time":
+			d.Prefix = fmt.Sprint(value)
+		case "level":
+			if d.Level, err = ParseLogLevel(fmt.Sprint(value)); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidDataLine, err)
+			}
+			hasLevel = true
+		case "file":
+			d.File = fmt.Sprint(value)
+		case "method":
+			d.Method = fmt.Sprint(value)
+		case "line":
+			if d.Line, err = strconv.Atoi(fmt.Sprint(value)); err != nil {
+				return nil, fmt.Errorf("%w: bad line number %v", ErrInvalidDataLine, value)
+			}
+		case "message":
+			d.Message = fmt.Sprint(value)
+		default:
+			d.Params = append(d.Params, Parameter{K: key, V: value})
+		}
+	}
+
+	if !hasLevel {
+		return nil, fmt.Errorf("%w: missing level field", ErrInvalidDataLine)
+	}
+	return d, nil
+}
+
+// parseLine parses a line in either the Data() or the JSON format
+func parseLine(line string) (*DataLine, error) {
+	if strings.HasPrefix(strings.TrimSpace(line), "{") {
+		return ParseJSONLine(line)
+	}
+	return ParseDataLine(line)
+}
+
+// findDataStart finds the leading type=" pair, which must start the line or follow a space
+func findDataStart(line string) int {
+	offset := 0
+	for {
+		i := strings.Index(line[offset:], `type="`)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		if i == 0 || line[i-1] == ' ' {
+			return i
+		}
+		offset = i + 1
+	}
+}
+
+// nextPair reads one key="value" (or key=value) pair from the start of s
+func nextPair(s string) (key, value, rest string, err error) {
+	eq := strings.IndexByte(s, '=')
+	if eq <= 0 || strings.ContainsAny(s[:eq], ` "`) {
+		return "", "", "", fmt.Errorf("%w: expected key= at %q", ErrInvalidDataLine, truncate(s, 32))
+	}
+	key = s[:eq]
+	s = s[eq+1:]
+
+	// Bare value, runs to the next space
+	if len(s) == 0 || s[0] != '"' {
+		if sp := strings.IndexByte(s, ' '); sp >= 0 {
+			return key, s[:sp], s[sp:], nil
+		}
+		return key, s, "", nil
+	}
+
+	s = s[1:]
+	end := closingQuote(s)
+	if end < 0 {
+		return "", "", "", fmt.Errorf("%w: unterminated value for %q", ErrInvalidDataLine, key)
+	}
+
+	// Data() writes the values as they are (without escaping quotes or backslashes), so
+	// they are returned as they are
+	return key, s[:end], s[end+1:], nil
+}
+
+// closingQuote finds the quote that ends a value: one that is followed by the end
+// of the line or by another key= pair. Quotes not preceded by a backslash are preferred,
+// but since Data() does not escape its values, a quote after a backslash is accepted as
+// a fallback (a value ending in a backslash).
+func closingQuote(s string) int {
+	fallback := -1
+	for i := 0; i < len(s); i++ {
+		if s[i] != '"' || !atPairBoundary(s[i+1:]) {
+			continue
+		}
+		if i > 0 && s[i-1] == '\\' {
+			if fallback < 0 {
+				fallback = i
+			}
+			continue
+		}
+		return i
+	}
+	return fallback
+}
+
+// atPairBoundary reports whether s is empty or starts with a space and a new key=
+func atPairBoundary(s string) bool {
+	if strings.TrimSpace(s) == "" {
+		return true
+	}
+	if s[0] != ' ' {
+		return false
+	}
+	s = strings.TrimLeft(s, " ")
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '=':
+			return i > 0
+		case ' ', '"':
+			return false
+		}
+	}
+	return false
+}
+
+// truncate shortens s for use in error messages
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// Scanner reads Data() log lines from a stream, one line at a time
+//
+// Lines written by the JSONEncoder are detected and parsed as well
+type Scanner struct {
+	data    *DataLine
+	err     error
+	scanner *bufio.Scanner
+}
+
+// NewScanner returns a Scanner reading from r
+func NewScanner(r io.Reader) *Scanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), MaxScanLineSize)
+	return &Scanner{scanner: s}
+}
+
+// Scan advances to the next line, returning false at the end of the input or on a read error
+func (s *Scanner) Scan() bool {
+	if !s.scanner.Scan() {
+		return false
+	}
+	s.data, s.err = parseLine(s.scanner.Text())
+	return true
+}
+
+// Data returns the parsed current line, or nil if it is not in the Data() or JSON format
+func (s *Scanner) Data() *DataLine {
+	return s.data
+}
+
+// ParseErr returns why the current line could not be parsed, if it could not
+func (s *Scanner) ParseErr() error {
+	return s.err
+}
+
+// Text returns the raw current line
+func (s *Scanner) Text() string {
+	return s.scanner.Text()
+}
+
+// Err returns the first read error encountered by the Scanner
+func (s *Scanner) Err() error {
+	return s.scanner.Err()
+}
@@ -0,0 +1,220 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseDataLine will test the ParseDataLine() method
+func TestParseDataLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected *DataLine
+	}{
+		{
+			name: "full data line with log prefix",
+			line: `2019/06/17 12:59:32 type="warn" file="go-logger/logger_test.go" method="go-logger.TestData.func1" line="188" message="test this method" another="value"`,
+			expected: &DataLine{
+				Prefix:  "2019/06/17 12:59:32",
+				Level:   WARN,
+				File:    "go-logger/logger_test.go",
+				Method:  "go-logger.TestData.func1",
+				Line:    188,
+				Message: "test this method",
+				Params:  []Parameter{{K: "another", V: "value"}},
+			},
+		},
+		{
+			name: "no file data line",
+			line: `type="info" message="hello world"` + "\n",
+			expected: &DataLine{
+				Level:   INFO,
+				Message: "hello world",
+			},
+		},
+		{
+			name: "token prefix and ordered params",
+			line: `token type="error" message="failed" b="2" a="1"`,
+			expected: &DataLine{
+				Prefix:  "token",
+				Level:   ERROR,
+				Message: "failed",
+				Params:  []Parameter{{K: "b", V: "2"}, {K: "a", V: "1"}},
+			},
+		},
+		{
+			name: "backslashes before quotes are kept",
+			line: `type="debug" message="say \"hi\"" sql="SELECT * FROM t WHERE a = \"b\""`,
+			expected: &DataLine{
+				Level:   DEBUG,
+				Message: `say \"hi\"`,
+				Params:  []Parameter{{K: "sql", V: `SELECT * FROM t WHERE a = \"b\"`}},
+			},
+		},
+		{
+			name: "bare quotes inside a value",
+			line: `type="info" message="quote " inside" other="x"`,
+			expected: &DataLine{
+				Level:   INFO,
+				Message: `quote " inside`,
+				Params:  []Parameter{{K: "other", V: "x"}},
+			},
+		},
+		{
+			name: "value ending in a backslash",
+			line: `type="info" message="path" dir="C:\"`,
+			expected: &DataLine{
+				Level:   INFO,
+				Message: "path",
+				Params:  []Parameter{{K: "dir", V: `C:\`}},
+			},
+		},
+		{
+			name: "bare values",
+			line: `type="info" message="query" rows=12 duration_ms=1.5`,
+			expected: &DataLine{
+				Level:   INFO,
+				Message: "query",
+				Params:  []Parameter{{K: "rows", V: "12"}, {K: "duration_ms", V: "1.5"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ParseDataLine(tt.line)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, d)
+		})
+	}
+}
+
+// TestParseDataLineInvalid will test ParseDataLine() with bad input
+func TestParseDataLineInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "plain text", line: "just a regular print line"},
+		{name: "embedded type key", line: `subtype="info" message="x"`},
+		{name: "unknown level", line: `type="loud" message="x"`},
+		{name: "bad line number", line: `type="info" line="abc" message="x"`},
+		{name: "unterminated value", line: `type="info" message="never ends`},
+		{name: "missing equals", line: `type="info" message`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ParseDataLine(tt.line)
+			require.ErrorIs(t, err, ErrInvalidDataLine)
+			assert.Nil(t, d)
+		})
+	}
+}
+
+// TestParseDataLineRoundTrip will test parsing the output of Data()
+func TestParseDataLineRoundTrip(t *testing.T) {
+	captured := captureOutput(func() {
+		implementation = &logPkg{}
+		Data(2, ERROR, "round trip", MakeParameter("key", "value"), MakeParameter("number", 42))
+	})
+
+	d, err := ParseDataLine(captured)
+	require.NoError(t, err)
+	assert.NotEmpty(t, d.Prefix)
+	assert.Equal(t, ERROR, d.Level)
+	assert.Equal(t, strings.Replace(testFileTag, "logger_test.go", "parse_test.go", 1), d.File)
+	assert.Equal(t, "go-logger.TestParseDataLineRoundTrip.func1", d.Method)
+	assert.Positive(t, d.Line)
+	assert.Equal(t, "round trip", d.Message)
+
+	value, ok := d.Param("number")
+	assert.True(t, ok)
+	assert.Equal(t, "42", value)
+
+	_, ok = d.Param("missing")
+	assert.False(t, ok)
+}
+
+// TestParseDataLineRoundTripValues will test parsing values written by Data() as they were
+func TestParseDataLineRoundTripValues(t *testing.T) {
+	values := []string{
+		`say "hi"`,
+		`escaped \"quote\"`,
+		`C:\`,
+		`C:\dir\`,
+		`a \\ b`,
+	}
+	for _, value := range values {
+		t.Run(value, func(t *testing.T) {
+			captured := captureOutput(func() {
+				implementation = &logPkg{}
+				NoFileData(INFO, value, MakeParameter("value", value))
+			})
+
+			d, err := ParseDataLine(captured)
+			require.NoError(t, err)
+			assert.Equal(t, value, d.Message)
+			parsed, ok := d.Param("value")
+			assert.True(t, ok)
+			assert.Equal(t, value, parsed)
+		})
+	}
+}
+
+// TestParseLogLevel will test the ParseLogLevel() method
+func TestParseLogLevel(t *testing.T) {
+	for _, level := range []LogLevel{DEBUG, INFO, WARN, ERROR} {
+		parsed, err := ParseLogLevel(strings.ToUpper(level.String()))
+		require.NoError(t, err)
+		assert.Equal(t, level, parsed)
+	}
+
+	parsed, err := ParseLogLevel("warning")
+	require.NoError(t, err)
+	assert.Equal(t, WARN, parsed)
+
+	_, err = ParseLogLevel("verbose")
+	require.ErrorIs(t, err, ErrUnknownLogLevel)
+}
+
+// TestScanner will test the Scanner
+func TestScanner(t *testing.T) {
+	input := strings.Join([]string{
+		`type="info" message="first"`,
+		`not a data line`,
+		`type="error" message="second" ` + strings.Repeat("x", 100*1024) + `="big"`,
+	}, "\n")
+
+	s := NewScanner(strings.NewReader(input))
+
+	require.True(t, s.Scan())
+	require.NotNil(t, s.Data())
+	assert.Equal(t, "first", s.Data().Message)
+	require.NoError(t, s.ParseErr())
+
+	require.True(t, s.Scan())
+	assert.Nil(t, s.Data())
+	require.ErrorIs(t, s.ParseErr(), ErrInvalidDataLine)
+	assert.Equal(t, "not a data line", s.Text())
+
+	require.True(t, s.Scan())
+	require.NotNil(t, s.Data())
+	assert.Equal(t, ERROR, s.Data().Level)
+	assert.Len(t, s.Data().Params, 1)
+
+	assert.False(t, s.Scan())
+	require.NoError(t, s.Err())
+}
+
+// BenchmarkParseDataLine benchmarks the ParseDataLine() method
+func BenchmarkParseDataLine(b *testing.B) {
+	line := `type="warn" file="go-logger/logger_test.go" method="go-logger.TestData.func1" line="188" message="test this method" another="value"`
+	for i := 0; i < b.N; i++ {
+		_, _ = ParseDataLine(line)
+	}
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Protocol Buffers wire types
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+)
+
+// appendProtoVarint appends a base 128 varint
+func appendProtoVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// appendProtoTag appends the key of a field (its number and wire type)
+func appendProtoTag(b []byte, field, wireType int) []byte {
+	return appendProtoVarint(b, uint64(field)<<3|uint64(wireType)) //nolint:gosec // G115: field numbers are positive
+}
+
+// appendProtoUint appends a varint field (uint32, uint64, bool or enum)
+func appendProtoUint(b []byte, field int, v uint64) []byte {
+	return appendProtoVarint(appendProtoTag(b, field, protoVarint), v)
+}
+
+// appendProtoInt appends an int64 field (two's complement varint, not zigzag)
+func appendProtoInt(b []byte, field int, v int64) []byte {
+	return appendProtoUint(b, field, uint64(v)) //nolint:gosec // G115: two's complement
+}
+
+// appendProtoFixed64 appends a fixed64 field
+func appendProtoFixed64(b []byte, field int, v uint64) []byte {
+	return binary.LittleEndian.AppendUint64(appendProtoTag(b, field, protoFixed64), v)
+}
+
+// appendProtoDouble appends a double field
+func appendProtoDouble(b []byte, field int, v float64) []byte {
+	return appendProtoFixed64(b, field, math.Float64bits(v))
+}
+
+// appendProtoBytes appends a length delimited field (bytes or an embedded message)
+func appendProtoBytes(b []byte, field int, data []byte) []byte {
+	b = appendProtoVarint(appendProtoTag(b, field, protoBytes), uint64(len(data)))
+	return append(b, data...)
+}
+
+// appendProtoString appends a string field
+//
+// Strings must be valid UTF-8 (a collector rejects the whole request otherwise), so invalid
+// bytes are replaced with the Unicode replacement character
+func appendProtoString(b []byte, field int, s string) []byte {
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, string(utf8.RuneError))
+	}
+	b = appendProtoVarint(appendProtoTag(b, field, protoBytes), uint64(len(s)))
+	return append(b, s...)
+}
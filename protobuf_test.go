@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// protoField is a decoded field (test helper)
+type protoField struct {
+	number int
+	value  uint64 // Varint and fixed64 fields
+	data   []byte // Length delimited fields
+}
+
+// decodeProto decodes the fields of a message (test helper, supports the wire types written
+// by the encoder)
+func decodeProto(t *testing.T, b []byte) []protoField {
+	t.Helper()
+	var fields []protoField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		require.Positive(t, n, "invalid key")
+		b = b[n:]
+
+		field := protoField{number: int(key >> 3)} //nolint:gosec // G115: test data
+		switch key & 7 {
+		case protoVarint:
+			field.value, n = binary.Uvarint(b)
+			require.Positive(t, n, "invalid varint")
+			b = b[n:]
+		case protoFixed64:
+			require.GreaterOrEqual(t, len(b), 8)
+			field.value = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case protoBytes:
+			size, sizeLen := binary.Uvarint(b)
+			require.Positive(t, sizeLen, "invalid length")
+			b = b[sizeLen:]
+			require.GreaterOrEqual(t, uint64(len(b)), size)
+			field.data = b[:size]
+			b = b[size:]
+		default:
+			require.Failf(t, "unsupported wire type", "%d", key&7)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// protoFields returns the fields with a number
+func protoFields(fields []protoField, number int) []protoField {
+	var matching []protoField
+	for _, f := range fields {
+		if f.number == number {
+			matching = append(matching, f)
+		}
+	}
+	return matching
+}
+
+// TestAppendProtoVarint will test the appendProtoVarint() method
+func TestAppendProtoVarint(t *testing.T) {
+	tests := []struct {
+		value    uint64
+		expected string
+	}{
+		{0, "00"},
+		{1, "01"},
+		{127, "7f"},
+		{128, "8001"},
+		{300, "ac02"},
+		{math.MaxUint64, "ffffffffffffffffff01"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, hex.EncodeToString(appendProtoVarint(nil, tt.value)), tt.value)
+	}
+}
+
+// TestAppendProtoFields will test the field encoders
+func TestAppendProtoFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		b        []byte
+		expected string
+	}{
+		{"uint", appendProtoUint(nil, 1, 150), "089601"},
+		{"negative int", appendProtoInt(nil, 2, -1), "10ffffffffffffffffff01"},
+		{"fixed64", appendProtoFixed64(nil, 1, 1), "090100000000000000"},
+		{"double", appendProtoDouble(nil, 4, 1.5), "21000000000000f83f"},
+		{"string", appendProtoString(nil, 2, "testing"), "120774657374696e67"},
+		{"invalid utf-8 string", appendProtoString(nil, 2, "a\xffb"), "120561efbfbd62"},
+		{"bytes", appendProtoBytes(nil, 7, []byte{1, 2}), "3a020102"},
+		{"large field number", appendProtoString(nil, 16, ""), "820100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, hex.EncodeToString(tt.b))
+		})
+	}
+}
+
+// TestDecodeProto will test the decodeProto() test helper against the encoders
+func TestDecodeProto(t *testing.T) {
+	b := appendProtoString(nil, 1, "key")
+	b = appendProtoUint(b, 2, 300)
+	b = appendProtoFixed64(b, 3, 42)
+
+	fields := decodeProto(t, b)
+	require.Len(t, fields, 3)
+	assert.Equal(t, protoField{number: 1, data: []byte("key")}, fields[0])
+	assert.Equal(t, protoField{number: 2, value: 300}, fields[1])
+	assert.Equal(t, protoField{number: 3, value: 42}, fields[2])
+	assert.Len(t, protoFields(fields, 2), 1)
+}
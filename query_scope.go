@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"context"
+	"sync"
+)
+
+// queryScopeKey is the context key for a QueryScope
+type queryScopeKey struct{}
+
+// QueryScope counts the queries run within one unit of work (IE: an HTTP request) to
+// detect N+1 patterns, see WithNPlusOneThreshold
+type QueryScope struct {
+	counts map[string]int // Executions per query fingerprint
+	mu     sync.Mutex
+}
+
+// NewQueryScope returns a context that groups the queries run with it (and its children)
+//
+//	ctx := logger.NewQueryScope(r.Context())
+//	db.WithContext(ctx).Find(&users)
+func NewQueryScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryScopeKey{}, &QueryScope{counts: make(map[string]int)})
+}
+
+// QueryScopeFromContext returns the scope of a context (or nil if there is none)
+func QueryScopeFromContext(ctx context.Context) *QueryScope {
+	if ctx == nil {
+		return nil
+	}
+	scope, _ := ctx.Value(queryScopeKey{}).(*QueryScope)
+	return scope
+}
+
+// Count returns how many times a query (by fingerprint) ran in the scope
+func (s *QueryScope) Count(fingerprint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[fingerprint]
+}
+
+// add counts an execution of a query, and returns the new count
+func (s *QueryScope) add(fingerprint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[fingerprint]++
+	return s.counts[fingerprint]
+}
@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewQueryScope will test attaching a scope to a context
+func TestNewQueryScope(t *testing.T) {
+	assert.Nil(t, QueryScopeFromContext(context.Background()))
+
+	ctx := NewQueryScope(context.Background())
+	scope := QueryScopeFromContext(ctx)
+	require.NotNil(t, scope)
+
+	assert.Equal(t, 1, scope.add("aaaa"))
+	assert.Equal(t, 2, scope.add("aaaa"))
+	assert.Equal(t, 2, scope.Count("aaaa"))
+	assert.Equal(t, 0, scope.Count("bbbb"))
+
+	// Child contexts share the scope
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	assert.Same(t, scope, QueryScopeFromContext(child))
+
+	// A new scope starts over
+	assert.Equal(t, 0, QueryScopeFromContext(NewQueryScope(ctx)).Count("aaaa"))
+}
+
+// TestBasicLogger_NPlusOne will test warning about N+1 queries
+func TestBasicLogger_NPlusOne(t *testing.T) {
+	run := func(ctx context.Context, l GormLoggerInterface, queries int) []string {
+		var messages []string
+		for i := 0; i < queries; i++ {
+			sql := fmt.Sprintf("SELECT * FROM orders WHERE user_id = %d", i)
+			messages = append(messages, traceMessages(ctx, l, time.Millisecond, sql, nil)...)
+		}
+		return messages
+	}
+
+	t.Run("warns once above the threshold", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithNPlusOneThreshold(3))
+		messages := run(NewQueryScope(context.Background()), l, 10)
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0], `type="warn"`)
+		assert.Contains(t, messages[0], `message="possible N+1 query"`)
+		assert.Contains(t, messages[0], `count=4 threshold=3`)
+		assert.Contains(t, messages[0], `query_normalized="select * from orders where user_id = ?"`)
+		assert.Contains(t, messages[0], `caller="`)
+	})
+
+	t.Run("below the threshold", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithNPlusOneThreshold(3))
+		assert.Empty(t, run(NewQueryScope(context.Background()), l, 3))
+	})
+
+	t.Run("per scope", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithNPlusOneThreshold(3))
+		assert.Empty(t, run(NewQueryScope(context.Background()), l, 2))
+		assert.Empty(t, run(NewQueryScope(context.Background()), l, 2))
+	})
+
+	t.Run("without a scope", func(t *testing.T) {
+		l := NewGormLogger(false, 3, WithNPlusOneThreshold(3))
+		assert.Empty(t, run(context.Background(), l, 10))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		l := NewGormLogger(false, 3)
+		assert.Empty(t, run(NewQueryScope(context.Background()), l, 10))
+	})
+}
@@ -0,0 +1,188 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxQueries is the default number of distinct queries tracked by QueryStats
+	DefaultMaxQueries = 1000
+
+	// querySamples is the number of recent durations kept per query for the p95
+	querySamples = 256
+)
+
+// QueryStat is a summary of the executions of a single query (by fingerprint)
+type QueryStat struct {
+	Avg         time.Duration `json:"avg"`         // Average duration (nanoseconds in JSON)
+	Count       int64         `json:"count"`       // Number of executions
+	Errors      int64         `json:"errors"`      // Number of executions that returned an error
+	Fingerprint string        `json:"fingerprint"` // See FingerprintSQL
+	Max         time.Duration `json:"max"`         // Slowest execution
+	P95         time.Duration `json:"p95"`         // 95th percentile of the recent executions
+	Query       string        `json:"query"`       // Normalized query
+	Rows        int64         `json:"rows"`        // Total rows affected
+	Total       time.Duration `json:"total"`       // Total time spent
+}
+
+// queryStat is the running state behind a QueryStat
+type queryStat struct {
+	QueryStat
+	samples []time.Duration // Ring buffer of recent durations
+	next    int             // Next position in samples
+}
+
+// QueryStats keeps rolling statistics per query fingerprint, see WithQueryStats
+type QueryStats struct {
+	MaxQueries int // Distinct queries tracked, others are counted in Dropped (defaults to DefaultMaxQueries)
+
+	dropped int64
+	mu      sync.Mutex
+	queries map[string]*queryStat
+}
+
+// NewQueryStats creates an empty QueryStats
+func NewQueryStats() *QueryStats {
+	return &QueryStats{
+		MaxQueries: DefaultMaxQueries,
+		queries:    make(map[string]*queryStat),
+	}
+}
+
+// record adds an execution of a query
+func (s *QueryStats) record(fingerprint, normalized string, elapsed time.Duration, rows int64, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.queries == nil {
+		s.queries = make(map[string]*queryStat)
+	}
+	stat, ok := s.queries[fingerprint]
+	if !ok {
+		maxQueries := s.MaxQueries
+		if maxQueries <= 0 {
+			maxQueries = DefaultMaxQueries
+		}
+		if len(s.queries) >= maxQueries {
+			s.dropped++
+			return
+		}
+		stat = &queryStat{QueryStat: QueryStat{Fingerprint: fingerprint, Query: normalized}}
+		s.queries[fingerprint] = stat
+	}
+
+	stat.Count++
+	if failed {
+		stat.Errors++
+	}
+	if rows > 0 {
+		stat.Rows += rows
+	}
+	stat.Total += elapsed
+	if elapsed > stat.Max {
+		stat.Max = elapsed
+	}
+	if len(stat.samples) < querySamples {
+		stat.samples = append(stat.samples, elapsed)
+	} else {
+		stat.samples[stat.next] = elapsed
+		stat.next = (stat.next + 1) % querySamples
+	}
+}
+
+// Snapshot returns the statistics of every query, by total time spent (slowest first)
+func (s *QueryStats) Snapshot() []QueryStat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]QueryStat, 0, len(s.queries))
+	for _, stat := range s.queries {
+		summary := stat.QueryStat
+		summary.Avg = summary.Total / time.Duration(summary.Count)
+		summary.P95 = percentile(stat.samples, 0.95)
+		stats = append(stats, summary)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Total != stats[j].Total {
+			return stats[i].Total > stats[j].Total
+		}
+		return stats[i].Fingerprint < stats[j].Fingerprint
+	})
+	return stats
+}
+
+// Dropped returns the number of executions not tracked because MaxQueries was reached
+func (s *QueryStats) Dropped() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Reset clears all statistics
+func (s *QueryStats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = make(map[string]*queryStat)
+	s.dropped = 0
+}
+
+// Emit logs one INFO line per query with its statistics
+func (s *QueryStats) Emit() {
+	for _, stat := range s.Snapshot() {
+		NoFileData(
+			INFO, "query statistics",
+			String("query_fingerprint", stat.Fingerprint),
+			String("query_normalized", stat.Query),
+			Int64("count", stat.Count),
+			Int64("errors", stat.Errors),
+			Int64("rows", stat.Rows),
+			Duration("total", stat.Total),
+			Duration("avg", stat.Avg),
+			Duration("p95", stat.P95),
+			Duration("max", stat.Max),
+		)
+	}
+}
+
+// Run calls Emit every interval until the context is done
+func (s *QueryStats) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Emit()
+		}
+	}
+}
+
+// ServeHTTP implements the http.Handler interface, writing the Snapshot as JSON
+func (s *QueryStats) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(s.Snapshot())
+}
+
+// percentile returns the p-th percentile (0-1) of the durations
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	index := int(p*float64(len(sorted))+0.5) - 1
+	if index < 0 {
+		index = 0
+	} else if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestQueryStats will test recording and summarizing queries
+func TestQueryStats(t *testing.T) {
+	s := NewQueryStats()
+	for i := 1; i <= 20; i++ {
+		s.record("aaaa", "select * from users where id = ?", time.Duration(i)*time.Millisecond, 1, i == 20)
+	}
+	s.record("bbbb", "select * from orders", 500*time.Millisecond, 10, false)
+
+	stats := s.Snapshot()
+	require.Len(t, stats, 2)
+
+	// Slowest total first
+	assert.Equal(t, "bbbb", stats[0].Fingerprint)
+	assert.Equal(t, int64(10), stats[0].Rows)
+	assert.Equal(t, 500*time.Millisecond, stats[0].P95)
+
+	users := stats[1]
+	assert.Equal(t, "select * from users where id = ?", users.Query)
+	assert.Equal(t, int64(20), users.Count)
+	assert.Equal(t, int64(1), users.Errors)
+	assert.Equal(t, int64(20), users.Rows)
+	assert.Equal(t, 210*time.Millisecond, users.Total)
+	assert.Equal(t, 10500*time.Microsecond, users.Avg)
+	assert.Equal(t, 19*time.Millisecond, users.P95)
+	assert.Equal(t, 20*time.Millisecond, users.Max)
+
+	s.Reset()
+	assert.Empty(t, s.Snapshot())
+}
+
+// TestQueryStats_Samples will test that only the recent durations are used for the p95
+func TestQueryStats_Samples(t *testing.T) {
+	s := NewQueryStats()
+	for i := 0; i < querySamples; i++ {
+		s.record("aaaa", "select ?", time.Second, 0, false)
+	}
+	for i := 0; i < querySamples; i++ {
+		s.record("aaaa", "select ?", time.Millisecond, 0, false)
+	}
+
+	stats := s.Snapshot()
+	require.Len(t, stats, 1)
+	assert.Equal(t, time.Millisecond, stats[0].P95)
+	assert.Equal(t, time.Second, stats[0].Max)
+}
+
+// TestQueryStats_MaxQueries will test limiting the number of queries tracked
+func TestQueryStats_MaxQueries(t *testing.T) {
+	s := NewQueryStats()
+	s.MaxQueries = 1
+	s.record("aaaa", "select ?", time.Millisecond, 0, false)
+	s.record("bbbb", "select ?, ?", time.Millisecond, 0, false)
+	s.record("aaaa", "select ?", time.Millisecond, 0, false)
+
+	stats := s.Snapshot()
+	require.Len(t, stats, 1)
+	assert.Equal(t, int64(2), stats[0].Count)
+	assert.Equal(t, int64(1), s.Dropped())
+}
+
+// TestQueryStats_ServeHTTP will test the http.Handler
+func TestQueryStats_ServeHTTP(t *testing.T) {
+	s := NewQueryStats()
+	s.record("aaaa", "select ?", 2*time.Millisecond, 3, false)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/queries", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var stats []QueryStat
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	require.Len(t, stats, 1)
+	assert.Equal(t, "select ?", stats[0].Query)
+	assert.Equal(t, 2*time.Millisecond, stats[0].Max)
+}
+
+// TestQueryStats_Emit will test logging the statistics
+func TestQueryStats_Emit(t *testing.T) {
+	oldImpl := GetImplementation()
+	defer SetImplementation(oldImpl)
+
+	testLogger := &testLoggerImpl{}
+	SetImplementation(testLogger)
+
+	s := NewQueryStats()
+	s.record("aaaa", "select ?", 2*time.Millisecond, 3, false)
+	s.Emit()
+
+	require.Len(t, testLogger.messages, 1)
+	assert.Contains(t, testLogger.messages[0], `type="info" message="query statistics" query_fingerprint="aaaa" query_normalized="select ?" count=1 errors=0 rows=3 total="2ms"`)
+}
+
+// TestQueryStats_Run will test emitting the statistics periodically
+func TestQueryStats_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewQueryStats().Run(ctx, time.Millisecond)
+		close(done)
+	}()
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was canceled")
+	}
+}
+
+// TestBasicLogger_QueryStats will test collecting statistics from Trace
+func TestBasicLogger_QueryStats(t *testing.T) {
+	stats := NewQueryStats()
+	l := NewGormLogger(false, 3, WithQueryStats(stats)).SetMode(Silent)
+
+	calls := 0
+	fc := func() (string, int64) {
+		calls++
+		return "SELECT * FROM users WHERE id = 1", 1
+	}
+	l.Trace(context.Background(), time.Now().Add(-time.Millisecond), fc, nil)
+	l.Trace(context.Background(), time.Now().Add(-time.Millisecond), fc, errors.New("connection reset"))
+	l.Trace(context.Background(), time.Now().Add(-time.Millisecond), fc, errors.New("record not found"))
+	assert.Equal(t, 3, calls)
+
+	snapshot := stats.Snapshot()
+	require.Len(t, snapshot, 1)
+	assert.Equal(t, "select * from users where id = ?", snapshot[0].Query)
+	assert.Equal(t, int64(3), snapshot[0].Count)
+	assert.Equal(t, int64(1), snapshot[0].Errors)
+
+	// The callback is only run once when logging too
+	calls = 0
+	_ = captureOutput(func() {
+		l.SetMode(Info).Trace(context.Background(), time.Now(), fc, nil)
+	})
+	assert.Equal(t, 1, calls)
+}
+
+// BenchmarkQueryStats_record benchmarks recording a query
+func BenchmarkQueryStats_record(b *testing.B) {
+	s := NewQueryStats()
+	for i := 0; i < b.N; i++ {
+		s.record("aaaa", "select ?", time.Millisecond, 1, false)
+	}
+}
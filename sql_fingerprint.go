@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// FingerprintSQL returns a stable identifier for the shape of a SQL statement, along
+// with the normalized statement it was computed from
+//
+// Normalizing lower-cases keywords and identifiers, replaces literals and placeholders
+// with ?, collapses IN lists to IN (...) and repeated VALUES tuples to a single
+// tuple, removes comments and normalizes whitespace. Queries that only differ by their
+// values share the same fingerprint (a 64-bit FNV-1a hash, as 16 hex characters)
+func FingerprintSQL(sql string) (fingerprint, normalized string) {
+	normalized = NormalizeSQL(sql)
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(normalized))
+	fingerprint = strconv.FormatUint(h.Sum64(), 16)
+	if pad := 16 - len(fingerprint); pad > 0 {
+		fingerprint = strings.Repeat("0", pad) + fingerprint
+	}
+	return fingerprint, normalized
+}
+
+// NormalizeSQL returns the normalized form of a SQL statement (see FingerprintSQL)
+func NormalizeSQL(sql string) string {
+	// Keep the significant tokens, replacing values with ?
+	tokens := make([]sqlToken, 0, 32)
+	for _, t := range lexSQL(sql) {
+		switch {
+		case t.kind == sqlSpace || t.kind == sqlComment:
+			continue
+		case t.isLiteral() || t.kind == sqlPlaceholder:
+			// Drop the sign of negative numbers (IE: a = -1)
+			if n := len(tokens); t.kind == sqlNumber && n > 0 && (tokens[n-1].text == "-" || tokens[n-1].text == "+") &&
+				(n == 1 || isSQLOperand(tokens[n-2])) {
+				tokens = tokens[:n-1]
+			}
+			t = sqlToken{kind: sqlPlaceholder, text: "?"}
+		case t.kind == sqlIdent:
+			t.text = strings.ToLower(t.text)
+		}
+		tokens = append(tokens, t)
+	}
+
+	// Remove trailing semicolons
+	for len(tokens) > 0 && tokens[len(tokens)-1].text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	tokens = collapseSQLLists(tokens)
+
+	var b strings.Builder
+	b.Grow(len(sql))
+	for i, t := range tokens {
+		if i > 0 && (sqlNeedsSpace(tokens[i-1], t) || i > 1 && t.text == "(" && tokens[i-2].text == "into") {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// collapseSQLLists collapses IN lists to (...) and removes repeated VALUES tuples
+func collapseSQLLists(tokens []sqlToken) []sqlToken {
+	out := tokens[:0]
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		// IN (?, ?, ?) => IN (...)
+		if t.text == "(" && len(out) > 0 && out[len(out)-1].text == "in" {
+			if end := placeholderListEnd(tokens, i+1); end > 0 {
+				out = append(out, t, sqlToken{kind: sqlPunct, text: "..."}, tokens[end])
+				i = end
+				continue
+			}
+		}
+
+		// VALUES (?, ?), (?, ?) => VALUES (?, ?)
+		if t.text == "," && len(out) > 0 && out[len(out)-1].text == ")" {
+			if start := tupleStart(out); start > 0 && (out[start-1].text == "values" || out[start-1].text == "value") {
+				tuple := out[start:]
+				if end := i + 1 + len(tuple); end <= len(tokens) && sameTokens(tokens[i+1:end], tuple) &&
+					(end == len(tokens) || tokens[end].text == ",") {
+					// Skip this comma and the duplicate tuple, the next comma is looked at again
+					i = end - 1
+					continue
+				}
+			}
+		}
+
+		out = append(out, t)
+	}
+	return out
+}
+
+// placeholderListEnd returns the index of the ")" closing a list of ? starting at i (or 0)
+func placeholderListEnd(tokens []sqlToken, i int) int {
+	for expectValue := true; i < len(tokens); i++ {
+		switch {
+		case expectValue && tokens[i].kind == sqlPlaceholder:
+			expectValue = false
+		case !expectValue && tokens[i].text == ",":
+			expectValue = true
+		case !expectValue && tokens[i].text == ")":
+			return i
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+// tupleStart returns the index of the "(" opening the tuple that ends the tokens (or 0)
+func tupleStart(tokens []sqlToken) int {
+	depth := 0
+	for i := len(tokens) - 1; i >= 0; i-- {
+		switch tokens[i].text {
+		case ")":
+			depth++
+		case "(":
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return 0
+}
+
+// sameTokens reports whether both lists hold the same tokens
+func sameTokens(a, b []sqlToken) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// isSQLOperand reports whether a token is an operator or opening that can precede a
+// signed number (IE: "=" in a = -1, but not "a" in a - 1)
+func isSQLOperand(t sqlToken) bool {
+	switch t.kind {
+	case sqlPunct:
+		return t.text != ")"
+	case sqlIdent:
+		return isSQLKeyword(strings.ToUpper(t.text))
+	case sqlSpace, sqlComment, sqlString, sqlNumber, sqlQuotedIdent, sqlPlaceholder:
+		return false
+	}
+	return false
+}
+
+// sqlNeedsSpace reports whether a space separates two tokens in the normalized form
+func sqlNeedsSpace(prev, next sqlToken) bool {
+	switch {
+	case prev.text == "(" || prev.text == "." || prev.text == "::":
+		return false
+	case next.text == ")" || next.text == "," || next.text == "." || next.text == "::" || next.text == ";":
+		return false
+	case next.text == "(":
+		// Function calls (IE: count(*)) but not keywords (IE: in (...))
+		return prev.kind != sqlIdent && prev.kind != sqlQuotedIdent || isSQLKeyword(strings.ToUpper(prev.text))
+	}
+	return true
+}
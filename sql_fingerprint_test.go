@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNormalizeSQL will test normalizing SQL statements
+func TestNormalizeSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+	}{
+		{"literals", `SELECT * FROM users WHERE email = 'jane@example.com' AND age > 30`, `select * from users where email = ? and age > ?`},
+		{"placeholders", `SELECT * FROM users WHERE id = $1 OR name = :name`, `select * from users where id = ? or name = ?`},
+		{"negative numbers", `SELECT * FROM t WHERE a = -1 AND b - 2 > 0`, `select * from t where a = ? and b - ? > ?`},
+		{"whitespace and comments", "SELECT  *\n\tFROM users /* admin */ WHERE id=1 -- note\n;", `select * from users where id = ?`},
+		{"in lists", `SELECT * FROM users WHERE id IN (1, 2, 3) AND role IN ($1)`, `select * from users where id in (...) and role in (...)`},
+		{"in subquery", `SELECT * FROM users WHERE id IN (SELECT user_id FROM orders)`, `select * from users where id in (select user_id from orders)`},
+		{"values tuples", `INSERT INTO users (name, age) VALUES ('a', 1), ('b', 2),('c',3)`, `insert into users (name, age) values (?, ?)`},
+		{"function calls", `SELECT COUNT(*), users.id FROM users`, `select count(*), users.id from users`},
+		{"quoted identifiers", "SELECT `Name` FROM \"Users\"", "select `Name` from \"Users\""},
+		{"casts", `SELECT id::text FROM t`, `select id::text from t`},
+		{"empty", ``, ``},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, NormalizeSQL(test.sql))
+		})
+	}
+}
+
+// TestFingerprintSQL will test fingerprinting SQL statements
+func TestFingerprintSQL(t *testing.T) {
+	fingerprint, normalized := FingerprintSQL(`SELECT * FROM users WHERE id IN (1, 2)`)
+	assert.Len(t, fingerprint, 16)
+	assert.Equal(t, `select * from users where id in (...)`, normalized)
+
+	same, _ := FingerprintSQL("select *\nfrom users\nwhere id in (7, 8, 9, 10)")
+	assert.Equal(t, fingerprint, same)
+
+	different, _ := FingerprintSQL(`SELECT * FROM orders WHERE id IN (1, 2)`)
+	assert.NotEqual(t, fingerprint, different)
+}
+
+// BenchmarkFingerprintSQL benchmarks the FingerprintSQL method
+func BenchmarkFingerprintSQL(b *testing.B) {
+	const sql = `SELECT * FROM users WHERE email = 'jane@example.com' AND id IN (1, 2, 3) ORDER BY id LIMIT 10`
+	for i := 0; i < b.N; i++ {
+		_, _ = FingerprintSQL(sql)
+	}
+}
+
+// FuzzFingerprintSQL will fuzz FingerprintSQL, which must never panic
+func FuzzFingerprintSQL(f *testing.F) {
+	f.Add(`SELECT * FROM t WHERE a IN (1, 2)`)
+	f.Add(`INSERT INTO t VALUES (1), (2), (`)
+	f.Add(`) , ( values`)
+
+	f.Fuzz(func(t *testing.T, sql string) {
+		fingerprint, _ := FingerprintSQL(sql)
+		if len(fingerprint) != 16 {
+			t.Fatalf("FingerprintSQL(%q) returned %q", sql, fingerprint)
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package logger
+
+import (
+	"strings"
+)
+
+// sqlTokenKind is the kind of a SQL token
+type sqlTokenKind uint8
+
+// SQL token kinds
+const (
+	sqlSpace       sqlTokenKind = iota // Whitespace
+	sqlComment                         // -- comment or /* comment */
+	sqlString                          // 'string' (including E'', N'', X'' prefixes) or $$string$$
+	sqlNumber                          // 123, 1.5, 1e10, 0xFF
+	sqlIdent                           // identifier or keyword
+	sqlQuotedIdent                     // "identifier" or `identifier`
+	sqlPlaceholder                     // ?, $1, :name, @name
+	sqlPunct                           // operators and punctuation
+)
+
+// sqlToken is a single token of a SQL statement
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+}
+
+// lexSQL splits a SQL statement into tokens, keeping all of the original text
+//
+// This is a forgiving lexer for logging purposes: it understands enough of the
+// common dialects (MySQL, PostgreSQL, SQLite) to find literals, and never fails
+func lexSQL(sql string) []sqlToken {
+	tokens := make([]sqlToken, 0, len(sql)/4)
+	for i := 0; i < len(sql); {
+		kind, end := lexSQLToken(sql, i)
+		tokens = append(tokens, sqlToken{kind: kind, text: sql[i:end]})
+		i = end
+	}
+	return tokens
+}
+
+// lexSQLToken returns the kind and end offset of the token starting at i
+func lexSQLToken(sql string, i int) (sqlTokenKind, int) {
+	c := sql[i]
+	switch {
+	case isSQLSpace(c):
+		end := i + 1
+		for end < len(sql) && isSQLSpace(sql[end]) {
+			end++
+		}
+		return sqlSpace, end
+	case c == '-' && strings.HasPrefix(sql[i:], "--"):
+		if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+			return sqlComment, i + end
+		}
+		return sqlComment, len(sql)
+	case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+		if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+			return sqlComment, i + 2 + end + 2
+		}
+		return sqlComment, len(sql)
+	case c == '\'':
+		return sqlString, scanQuoted(sql, i, '\'', false)
+	case c == '"' || c == '`':
+		return sqlQuotedIdent, scanQuoted(sql, i, c, false)
+	case (c == 'E' || c == 'e') && i+1 < len(sql) && sql[i+1] == '\'':
+		// PostgreSQL escape strings are the only ones using backslash escapes
+		return sqlString, scanQuoted(sql, i+1, '\'', true)
+	case strings.IndexByte("NnXxBb", c) >= 0 && i+1 < len(sql) && sql[i+1] == '\'':
+		return sqlString, scanQuoted(sql, i+1, '\'', false)
+	case c == '$' && dollarTag(sql, i) > 0:
+		tag := sql[i : i+dollarTag(sql, i)]
+		if end := strings.Index(sql[i+len(tag):], tag); end >= 0 {
+			return sqlString, i + len(tag) + end + len(tag)
+		}
+		return sqlString, len(sql)
+	case isSQLDigit(c) || (c == '.' && i+1 < len(sql) && isSQLDigit(sql[i+1])):
+		end := scanNumber(sql, i)
+		// A number running into letters is part of an identifier (IE: 1st_table)
+		if end < len(sql) && isSQLIdentStart(sql[end]) {
+			for end < len(sql) && isSQLIdentPart(sql[end]) {
+				end++
+			}
+			return sqlIdent, end
+		}
+		return sqlNumber, end
+	case isSQLIdentStart(c):
+		end := i + 1
+		for end < len(sql) && isSQLIdentPart(sql[end]) {
+			end++
+		}
+		return sqlIdent, end
+	case c == '?':
+		return sqlPlaceholder, i + 1
+	case (c == '$' || c == ':' || c == '@') && i+1 < len(sql) && isSQLIdentPart(sql[i+1]) &&
+		!(c == ':' && i > 0 && sql[i-1] == ':'):
+		end := i + 1
+		for end < len(sql) && isSQLIdentPart(sql[end]) {
+			end++
+		}
+		return sqlPlaceholder, end
+	}
+
+	// Two character operators
+	if i+1 < len(sql) {
+		switch sql[i : i+2] {
+		case "<=", ">=", "<>", "!=", "::", "||", "->":
+			return sqlPunct, i + 2
+		}
+	}
+	return sqlPunct, i + 1
+}
+
+// scanQuoted returns the end of a quoted token starting at i, handling doubled quotes
+// and, when backslash is set, backslash escapes (an unterminated token runs to the end)
+//
+// Quotes are only escaped by doubling them in standard SQL (and by GORM when it
+// writes the values of a statement), so a plain string may end with a backslash.
+func scanQuoted(sql string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(sql); j++ {
+		switch sql[j] {
+		case '\\':
+			if backslash {
+				j++
+			}
+		case quote:
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(sql)
+}
+
+// dollarTag returns the length of the $$ or $tag$ opening a PostgreSQL dollar-quoted
+// string at i, or 0 if there is none ($1 is a placeholder)
+func dollarTag(sql string, i int) int {
+	for j := i + 1; j < len(sql); j++ {
+		c := sql[j]
+		if c == '$' {
+			return j + 1 - i
+		}
+		if !isSQLIdentStart(c) && (j == i+1 || !isSQLDigit(c)) {
+			return 0
+		}
+	}
+	return 0
+}
+
+// scanNumber returns the end of a numeric literal starting at i
+func scanNumber(sql string, i int) int {
+	end := i
+	if strings.HasPrefix(sql[i:], "0x") || strings.HasPrefix(sql[i:], "0X") {
+		end += 2
+		for end < len(sql) && strings.IndexByte("0123456789abcdefABCDEF", sql[end]) >= 0 {
+			end++
+		}
+		return end
+	}
+	for end < len(sql) && (isSQLDigit(sql[end]) || sql[end] == '.') {
+		end++
+	}
+	if end < len(sql) && (sql[end] == 'e' || sql[end] == 'E') {
+		exp := end + 1
+		if exp < len(sql) && (sql[exp] == '+' || sql[exp] == '-') {
+			exp++
+		}
+		if exp < len(sql) && isSQLDigit(sql[exp]) {
+			end = exp
+			for end < len(sql) && isSQLDigit(sql[end]) {
+				end++
+			}
+		}
+	}
+	return end
+}
+
+// isSQLSpace reports whether c is whitespace
+func isSQLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// isSQLDigit reports whether c is a decimal digit
+func isSQLDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isSQLIdentStart reports whether c can start an identifier
+func isSQLIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// isSQLIdentPart reports whether c can continue an identifier
+func isSQLIdentPart(c byte) bool {
+	return isSQLIdentStart(c) || isSQLDigit(c) || c == '$'
+}
+
+// isLiteral reports whether the token is a string or numeric literal
+func (t sqlToken) isLiteral() bool {
+	return t.kind == sqlString || t.kind == sqlNumber
+}
+
+// keyword returns the upper-cased text of an identifier token (or "" for other tokens)
+func (t sqlToken) keyword() string {
+	if t.kind != sqlIdent {
+		return ""
+	}
+	return strings.ToUpper(t.text)
+}
+
+// unquoteIdent removes identifier quotes ("name" or `name`)
+func unquoteIdent(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '`') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
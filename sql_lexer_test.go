@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLexSQL will test splitting SQL into tokens
+func TestLexSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		literals []string
+	}{
+		{"strings and numbers", `SELECT * FROM t WHERE a = 'x' AND b = 42`, []string{`'x'`, `42`}},
+		{"doubled quotes", `SELECT 'it''s'`, []string{`'it''s'`}},
+		{"backslashes", `SELECT 'C:\', 'a\b'`, []string{`'C:\'`, `'a\b'`}},
+		{"backslash escapes", `SELECT E'a\'b'`, []string{`E'a\'b'`}},
+		{"prefixed strings", `SELECT E'\n', N'abc', X'FF'`, []string{`E'\n'`, `N'abc'`, `X'FF'`}},
+		{"dollar quoted strings", `SELECT $$it's$$, $tag$a $$ b$tag$, $1`, []string{`$$it's$$`, `$tag$a $$ b$tag$`}},
+		{"unterminated dollar quoted string", `SELECT $$abc`, []string{`$$abc`}},
+		{"numbers", `SELECT 1.5, .5, 1e10, 2E-3, 0xFF`, []string{`1.5`, `.5`, `1e10`, `2E-3`, `0xFF`}},
+		{"quoted identifiers", "SELECT \"name\", `email` FROM t", nil},
+		{"placeholders", `SELECT ? , $1, :name, @id`, nil},
+		{"comments", "SELECT 1 -- 'not a string'\n/* 'nor this' */", []string{`1`}},
+		{"casts", `SELECT a::int FROM t`, nil},
+		{"identifiers with digits", `SELECT 1st_col FROM t2`, []string{}},
+		{"unterminated string", `SELECT 'abc`, []string{`'abc`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens := lexSQL(test.sql)
+
+			var text strings.Builder
+			var literals []string
+			for _, token := range tokens {
+				text.WriteString(token.text)
+				if token.isLiteral() {
+					literals = append(literals, token.text)
+				}
+			}
+			assert.Equal(t, test.sql, text.String(), "lexing must not lose any text")
+			assert.ElementsMatch(t, test.literals, literals)
+		})
+	}
+}
+
+// TestLexSQL_Kinds will test the kinds of the tokens
+func TestLexSQL_Kinds(t *testing.T) {
+	var kinds []sqlTokenKind
+	for _, token := range lexSQL("SELECT \"a\" FROM t WHERE b <> $1 -- c") {
+		kinds = append(kinds, token.kind)
+	}
+	assert.Equal(t, []sqlTokenKind{
+		sqlIdent, sqlSpace, sqlQuotedIdent, sqlSpace, sqlIdent, sqlSpace, sqlIdent, sqlSpace,
+		sqlIdent, sqlSpace, sqlIdent, sqlSpace, sqlPunct, sqlSpace, sqlPlaceholder, sqlSpace, sqlComment,
+	}, kinds)
+}
+
+// BenchmarkLexSQL benchmarks the lexSQL method
+func BenchmarkLexSQL(b *testing.B) {
+	const sql = `SELECT * FROM users WHERE email = 'jane@example.com' AND age > 30 ORDER BY id LIMIT 10`
+	for i := 0; i < b.N; i++ {
+		_ = lexSQL(sql)
+	}
+}
+
+// FuzzLexSQL will fuzz lexSQL, which must never lose or add text
+func FuzzLexSQL(f *testing.F) {
+	f.Add(`SELECT * FROM t WHERE a = 'x'`)
+	f.Add(`SELECT 'unterminated`)
+	f.Add(`/* comment`)
+	f.Add("`q` \"q\" E'x' 0x 1e $ : @")
+
+	f.Fuzz(func(t *testing.T, sql string) {
+		var text strings.Builder
+		for _, token := range lexSQL(sql) {
+			if len(token.text) == 0 {
+				t.Fatalf("empty token for %q", sql)
+			}
+			text.WriteString(token.text)
+		}
+		if text.String() != sql {
+			t.Fatalf("lexSQL(%q) produced %q", sql, text.String())
+		}
+	})
+}
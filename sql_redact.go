@@ -0,0 +1,286 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// RedactionMode is how the LiteralRedactor replaces literals
+type RedactionMode uint8
+
+const (
+	// RedactPlaceholder replaces literals with ?
+	RedactPlaceholder RedactionMode = iota
+
+	// RedactHash replaces literals with a short hash (#1a2b3c4d), so equal values can still be correlated
+	RedactHash
+)
+
+// SQLRedactor removes sensitive values from a SQL statement before it is logged
+type SQLRedactor interface {
+	Redact(sql string) string
+}
+
+// LiteralRedactor is a SQLRedactor that replaces string and numeric literals
+//
+// Literals compared with, or assigned to, an allow-listed column are kept, as are all
+// the literals of a statement that only touches allow-listed tables. Columns can be
+// listed as "column" or "table.column" (table aliases are not resolved).
+type LiteralRedactor struct {
+	AllowColumns []string      // Columns whose values are safe to log (IE: "status" or "users.status")
+	AllowTables  []string      // Tables whose values are safe to log
+	Mode         RedactionMode // How literals are replaced
+	Salt         string        // Salt for RedactHash (makes hashes of small value sets harder to reverse)
+}
+
+// NewLiteralRedactor creates a LiteralRedactor using the given mode
+func NewLiteralRedactor(mode RedactionMode) *LiteralRedactor {
+	return &LiteralRedactor{Mode: mode}
+}
+
+// Redact implements the SQLRedactor interface
+func (r *LiteralRedactor) Redact(sql string) string {
+	tokens := lexSQL(sql)
+	columns, tables := sqlLiteralColumns(tokens)
+	if len(columns) == 0 {
+		return sql
+	}
+
+	keepAll := len(tables) > 0
+	for _, table := range tables {
+		if !containsFold(r.AllowTables, table) {
+			keepAll = false
+			break
+		}
+	}
+	if keepAll {
+		return sql
+	}
+
+	var b strings.Builder
+	b.Grow(len(sql))
+	for i, t := range tokens {
+		column, isLiteral := columns[i]
+		if !isLiteral || r.allowedColumn(column, tables) {
+			b.WriteString(t.text)
+			continue
+		}
+		if r.Mode == RedactHash {
+			sum := sha256.Sum256([]byte(r.Salt + t.text))
+			b.WriteByte('#')
+			b.WriteString(hex.EncodeToString(sum[:4]))
+		} else {
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// allowedColumn reports whether the values of a column (maybe qualified) are safe to log
+func (r *LiteralRedactor) allowedColumn(column string, tables []string) bool {
+	if len(column) == 0 || len(r.AllowColumns) == 0 {
+		return false
+	}
+
+	table, name := "", column
+	if dot := strings.LastIndexByte(column, '.'); dot >= 0 {
+		table, name = column[:dot], column[dot+1:]
+	}
+
+	for _, allowed := range r.AllowColumns {
+		allowedTable, allowedName, qualified := strings.Cut(allowed, ".")
+		if !qualified {
+			if strings.EqualFold(allowed, name) {
+				return true
+			}
+			continue
+		}
+		if !strings.EqualFold(allowedName, name) {
+			continue
+		}
+		if len(table) > 0 && strings.EqualFold(allowedTable, table) {
+			return true
+		}
+		if len(table) == 0 && containsFold(tables, allowedTable) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether list contains s (case-insensitive)
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlLiteralColumns finds the literals of a statement (by token index) along with the
+// column each one is compared with or assigned to (or "" if unknown), and the tables
+// the statement touches
+func sqlLiteralColumns(tokens []sqlToken) (map[int]string, []string) {
+	var (
+		columns = map[int]string{}
+		tables  []string
+
+		name      string // Last (maybe qualified) identifier
+		nameValid bool   // The previous significant token ended name
+		afterDot  bool   // The previous significant token was a "."
+
+		column      string // Column that literals are currently compared with
+		columnDepth int
+		between     bool // Inside BETWEEN x AND y
+		depth       int
+		expectTable bool // The next identifier is a table name
+		valueNext   bool // The next token is in value position (IE: after "=")
+
+		insertColumns []string
+		insertDepth   = -1 // Depth of the INSERT column list
+		insertState   int  // 0: none, 1: after INTO, 2: in the column list, 3: in VALUES
+		tupleIndex    int
+	)
+
+	for i, t := range tokens {
+		if t.kind == sqlSpace || t.kind == sqlComment {
+			continue
+		}
+
+		// Double quoted strings in value position are literals (GORM quotes the values of
+		// SQLite statements with double quotes)
+		isValue := t.isLiteral() || (t.kind == sqlQuotedIdent && t.text[0] == '"' &&
+			(valueNext || (insertState == 3 && depth > 0)))
+		valueNext = false
+
+		// Extend a qualified name (IE: schema.table or table.column)
+		isName := !isValue && (t.kind == sqlQuotedIdent || (t.kind == sqlIdent && !isSQLKeyword(t.keyword())))
+		if isName && afterDot && nameValid {
+			name += "." + unquoteIdent(t.text)
+			afterDot = false
+			continue
+		}
+		if t.text == "." {
+			afterDot = true
+			continue
+		}
+		afterDot = false
+
+		// The previous name is complete
+		wasTable := false
+		if nameValid {
+			if expectTable {
+				tables = append(tables, name)
+				wasTable, expectTable = true, false
+			} else if insertState == 2 && depth == insertDepth+1 {
+				insertColumns = append(insertColumns, name)
+			}
+		}
+
+		if isName {
+			name, nameValid = unquoteIdent(t.text), true
+			continue
+		}
+		expectTable = false
+
+		switch {
+		case t.kind == sqlIdent:
+			switch t.keyword() {
+			case "FROM", "JOIN", "UPDATE", "TABLE":
+				expectTable = true
+			case "INTO":
+				expectTable, insertState = true, 1
+			case "VALUES", "VALUE":
+				if insertState > 0 {
+					insertState = 3
+				}
+			case "LIKE", "ILIKE", "IN", "IS", "REGEXP":
+				if nameValid {
+					column, columnDepth = name, depth
+				}
+				valueNext = true
+			case "BETWEEN":
+				if nameValid {
+					column, columnDepth = name, depth
+				}
+				between, valueNext = true, true
+			case "AND":
+				if between {
+					between, valueNext = false, true
+				} else {
+					column = ""
+				}
+			case "NOT":
+				// Keep the column for NOT IN, NOT LIKE, etc.
+				continue
+			default:
+				column, between = "", false
+				if insertState == 3 && depth == 0 {
+					insertState = 0
+				}
+			}
+
+		case isValue:
+			if insertState == 3 && depth > 0 && tupleIndex < len(insertColumns) {
+				columns[i] = insertColumns[tupleIndex]
+			} else {
+				columns[i] = column
+			}
+
+		case t.text == "(":
+			if insertState == 1 && nameValid && wasTable {
+				insertState, insertDepth = 2, depth
+			}
+			if insertState == 3 && depth == 0 {
+				tupleIndex = 0
+			}
+			valueNext = len(column) > 0
+			depth++
+
+		case t.text == ")":
+			depth--
+			if depth < columnDepth {
+				column = ""
+			}
+			if insertState == 2 && depth == insertDepth {
+				insertState = 1
+			}
+
+		case t.text == ",":
+			if insertState == 3 && depth == 1 {
+				tupleIndex++
+			} else if depth == columnDepth {
+				column = ""
+			}
+			valueNext = len(column) > 0
+
+		case t.text == "=" || t.text == "<>" || t.text == "!=" || t.text == "<" || t.text == ">" ||
+			t.text == "<=" || t.text == ">=":
+			if nameValid {
+				column, columnDepth = name, depth
+			}
+			valueNext = true
+		}
+		nameValid = false
+	}
+
+	// A trailing table name (IE: "DELETE FROM users")
+	if nameValid && expectTable {
+		tables = append(tables, name)
+	}
+	return columns, tables
+}
+
+// isSQLKeyword reports whether an upper-cased identifier is a keyword the redactor cares about
+func isSQLKeyword(keyword string) bool {
+	switch keyword {
+	case "SELECT", "FROM", "JOIN", "UPDATE", "TABLE", "INTO", "VALUES", "VALUE", "LIKE", "ILIKE",
+		"IN", "IS", "REGEXP", "BETWEEN", "AND", "OR", "NOT", "WHERE", "SET", "ON", "HAVING", "ORDER",
+		"GROUP", "BY", "LIMIT", "OFFSET", "THEN", "WHEN", "ELSE", "END", "CASE", "RETURNING", "INSERT",
+		"DELETE", "AS", "INNER", "LEFT", "RIGHT", "OUTER", "CROSS", "DISTINCT", "UNION", "DO", "CONFLICT":
+		return true
+	}
+	return false
+}
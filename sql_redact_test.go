@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLiteralRedactor_Redact will test redacting literals
+func TestLiteralRedactor_Redact(t *testing.T) {
+	tests := []struct {
+		name     string
+		redactor *LiteralRedactor
+		sql      string
+		expected string
+	}{
+		{
+			"select",
+			NewLiteralRedactor(RedactPlaceholder),
+			`SELECT * FROM users WHERE email = 'jane@example.com' AND age >= 30`,
+			`SELECT * FROM users WHERE email = ? AND age >= ?`,
+		},
+		{
+			"in list and between",
+			NewLiteralRedactor(RedactPlaceholder),
+			`SELECT id FROM users WHERE id IN (1, 2, 3) AND age BETWEEN 18 AND 65`,
+			`SELECT id FROM users WHERE id IN (?, ?, ?) AND age BETWEEN ? AND ?`,
+		},
+		{
+			"no literals",
+			NewLiteralRedactor(RedactPlaceholder),
+			`SELECT * FROM "users" WHERE "id" = $1`,
+			`SELECT * FROM "users" WHERE "id" = $1`,
+		},
+		{
+			"comments and identifiers are kept",
+			NewLiteralRedactor(RedactPlaceholder),
+			"SELECT `col1` FROM t2 WHERE name = 'x' -- 'note'",
+			"SELECT `col1` FROM t2 WHERE name = ? -- 'note'",
+		},
+		{
+			"allowed column",
+			&LiteralRedactor{AllowColumns: []string{"status"}},
+			`SELECT * FROM orders WHERE status = 'paid' AND email = 'jane@example.com'`,
+			`SELECT * FROM orders WHERE status = 'paid' AND email = ?`,
+		},
+		{
+			"allowed column with not in",
+			&LiteralRedactor{AllowColumns: []string{"status"}},
+			`SELECT * FROM orders WHERE status NOT IN ('paid', 'refunded') AND total > 10`,
+			`SELECT * FROM orders WHERE status NOT IN ('paid', 'refunded') AND total > ?`,
+		},
+		{
+			"allowed qualified column",
+			&LiteralRedactor{AllowColumns: []string{"orders.status"}},
+			`SELECT * FROM orders JOIN users ON users.id = orders.user_id WHERE orders.status = 'paid' AND users.status = 'banned'`,
+			`SELECT * FROM orders JOIN users ON users.id = orders.user_id WHERE orders.status = 'paid' AND users.status = ?`,
+		},
+		{
+			"allowed qualified column without qualifier",
+			&LiteralRedactor{AllowColumns: []string{"orders.status"}},
+			`UPDATE orders SET status = 'shipped', note = 'call me' WHERE id = 7`,
+			`UPDATE orders SET status = 'shipped', note = ? WHERE id = ?`,
+		},
+		{
+			"allowed insert column",
+			&LiteralRedactor{AllowColumns: []string{"country"}},
+			`INSERT INTO users (email, country, age) VALUES ('jane@example.com', 'US', 30), ('joe@example.com', 'CA', 40)`,
+			`INSERT INTO users (email, country, age) VALUES (?, 'US', ?), (?, 'CA', ?)`,
+		},
+		{
+			"allowed table",
+			&LiteralRedactor{AllowTables: []string{"countries"}},
+			`SELECT * FROM countries WHERE code = 'US'`,
+			`SELECT * FROM countries WHERE code = 'US'`,
+		},
+		{
+			"allowed table joined with another table",
+			&LiteralRedactor{AllowTables: []string{"countries"}},
+			`SELECT * FROM countries JOIN users ON users.country = countries.code WHERE users.email = 'x'`,
+			`SELECT * FROM countries JOIN users ON users.country = countries.code WHERE users.email = ?`,
+		},
+		{
+			"string ending with a backslash",
+			NewLiteralRedactor(RedactPlaceholder),
+			`SELECT * FROM files WHERE path = 'C:\' AND email = 'jane@example.com'`,
+			`SELECT * FROM files WHERE path = ? AND email = ?`,
+		},
+		{
+			"escape string",
+			NewLiteralRedactor(RedactPlaceholder),
+			`SELECT * FROM users WHERE name = E'o\'neil' AND email = 'jane@example.com'`,
+			`SELECT * FROM users WHERE name = ? AND email = ?`,
+		},
+		{
+			"double quoted values",
+			NewLiteralRedactor(RedactPlaceholder),
+			`SELECT * FROM "users" WHERE "users"."email" = "jane@example.com" AND "name" IN ("Jane","Joe") AND "age" BETWEEN "18" AND "65"`,
+			`SELECT * FROM "users" WHERE "users"."email" = ? AND "name" IN (?,?) AND "age" BETWEEN ? AND ?`,
+		},
+		{
+			"double quoted insert values",
+			&LiteralRedactor{AllowColumns: []string{"country"}},
+			`INSERT INTO "users" ("email","country") VALUES ("jane@example.com","US")`,
+			`INSERT INTO "users" ("email","country") VALUES (?,"US")`,
+		},
+		{
+			"dollar quoted values",
+			NewLiteralRedactor(RedactPlaceholder),
+			`UPDATE users SET bio = $$jane's bio$$, email = $q$jane@example.com$q$ WHERE id = $1`,
+			`UPDATE users SET bio = ?, email = ? WHERE id = $1`,
+		},
+		{
+			"hash",
+			&LiteralRedactor{Mode: RedactHash},
+			`SELECT * FROM users WHERE email = 'jane@example.com' OR backup = 'jane@example.com'`,
+			`SELECT * FROM users WHERE email = #fd4ffe8b OR backup = #fd4ffe8b`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.redactor.Redact(test.sql))
+		})
+	}
+}
+
+// TestLiteralRedactor_Salt will test that the salt changes the hashes
+func TestLiteralRedactor_Salt(t *testing.T) {
+	const sql = `SELECT * FROM users WHERE email = 'jane@example.com'`
+	plain := (&LiteralRedactor{Mode: RedactHash}).Redact(sql)
+	salted := (&LiteralRedactor{Mode: RedactHash, Salt: "pepper"}).Redact(sql)
+	assert.NotEqual(t, plain, salted)
+	assert.NotContains(t, salted, "jane@example.com")
+}
+
+// TestSQLLiteralColumns will test finding the tables and columns of a statement
+func TestSQLLiteralColumns(t *testing.T) {
+	tokens := lexSQL(`INSERT INTO app.users (email, "name") VALUES ('a', 'b')`)
+	columns, tables := sqlLiteralColumns(tokens)
+	assert.Equal(t, []string{"app.users"}, tables)
+
+	var found []string
+	for i := range tokens {
+		if column, ok := columns[i]; ok {
+			found = append(found, column)
+		}
+	}
+	assert.Equal(t, []string{"email", "name"}, found)
+
+	_, tables = sqlLiteralColumns(lexSQL(`DELETE FROM sessions`))
+	assert.Equal(t, []string{"sessions"}, tables)
+}
+
+// BenchmarkLiteralRedactor_Redact benchmarks the Redact method
+func BenchmarkLiteralRedactor_Redact(b *testing.B) {
+	const sql = `SELECT * FROM users WHERE email = 'jane@example.com' AND age > 30 ORDER BY id LIMIT 10`
+	r := NewLiteralRedactor(RedactPlaceholder)
+	for i := 0; i < b.N; i++ {
+		_ = r.Redact(sql)
+	}
+}
@@ -0,0 +1,459 @@
+package sqllog
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/mrz1836/go-logger"
+)
+
+// Statements reported for transactions and prepared statements
+const (
+	sqlBegin    = "BEGIN"
+	sqlCommit   = "COMMIT"
+	sqlPrepare  = "PREPARE "
+	sqlRollback = "ROLLBACK"
+)
+
+// Interfaces implemented by the wrappers (database/sql falls back when the parent does not)
+var (
+	_ driver.Conn               = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.Validator          = (*conn)(nil)
+
+	_ driver.Stmt             = (*stmt)(nil)
+	_ driver.StmtExecContext  = (*stmt)(nil)
+	_ driver.StmtQueryContext = (*stmt)(nil)
+
+	_ driver.ColumnConverter = (*columnConverterStmt)(nil) //nolint:staticcheck // still used by some drivers
+
+	_ driver.Rows                           = (*rows)(nil)
+	_ driver.RowsColumnTypeDatabaseTypeName = (*rows)(nil)
+	_ driver.RowsColumnTypeLength           = (*rows)(nil)
+	_ driver.RowsColumnTypeNullable         = (*rows)(nil)
+	_ driver.RowsColumnTypePrecisionScale   = (*rows)(nil)
+	_ driver.RowsColumnTypeScanType         = (*rows)(nil)
+	_ driver.RowsNextResultSet              = (*rows)(nil)
+)
+
+// Errors returned when the parent does not support a feature (same as database/sql)
+var (
+	errIsolationLevel  = errors.New("sql: driver does not support non-default isolation level")
+	errNamedParameters = errors.New("sql: driver does not support the use of Named Parameters")
+)
+
+// tracer reports the statements of a wrapped driver to the logger
+type tracer struct {
+	logger logger.GormLoggerInterface
+	values bool // Render the bound values into the SQL (see WithBoundValues)
+}
+
+// trace reports a statement to the logger
+func (t *tracer) trace(ctx context.Context, begin time.Time, query string,
+	args []driver.NamedValue, rowsAffected int64, err error,
+) {
+	if errors.Is(err, driver.ErrSkip) {
+		return // database/sql retries another way
+	}
+	t.logger.Trace(ctx, begin, func() (string, int64) {
+		if t.values {
+			return explain(query, args), rowsAffected
+		}
+		return query, rowsAffected
+	}, err)
+}
+
+// conn logs the statements of a connection
+type conn struct {
+	tracer *tracer
+	parent driver.Conn
+}
+
+// Prepare implements the driver.Conn interface
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements the driver.ConnPrepareContext interface
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	begin := time.Now()
+	var (
+		s   driver.Stmt
+		err error
+	)
+	if p, ok := c.parent.(driver.ConnPrepareContext); ok {
+		s, err = p.PrepareContext(ctx, query)
+	} else if err = ctx.Err(); err == nil {
+		s, err = c.parent.Prepare(query)
+	}
+	c.tracer.trace(ctx, begin, sqlPrepare+query, nil, 0, err)
+	if err != nil {
+		return nil, err
+	}
+	return newStmt(c.tracer, s, query), nil
+}
+
+// Close implements the driver.Conn interface
+func (c *conn) Close() error {
+	return c.parent.Close()
+}
+
+// Begin implements the driver.Conn interface
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements the driver.ConnBeginTx interface
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	begin := time.Now()
+	var (
+		t   driver.Tx
+		err error
+	)
+	switch p, ok := c.parent.(driver.ConnBeginTx); {
+	case ok:
+		t, err = p.BeginTx(ctx, opts)
+	case opts.Isolation != 0 || opts.ReadOnly:
+		err = errIsolationLevel
+	default:
+		if err = ctx.Err(); err == nil {
+			t, err = c.parent.Begin() //nolint:staticcheck // fallback for drivers without BeginTx
+		}
+	}
+	c.tracer.trace(ctx, begin, sqlBegin, nil, 0, err)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{ctx: ctx, tracer: c.tracer, parent: t}, nil
+}
+
+// ExecContext implements the driver.ExecerContext interface
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	begin := time.Now()
+	var (
+		result driver.Result
+		err    error
+	)
+	if e, ok := c.parent.(driver.ExecerContext); ok {
+		result, err = e.ExecContext(ctx, query, args)
+	} else if e, ok := c.parent.(driver.Execer); ok { //nolint:staticcheck // fallback for older drivers
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			result, err = e.Exec(query, values)
+		}
+	} else {
+		return nil, driver.ErrSkip
+	}
+	c.tracer.trace(ctx, begin, query, args, rowsAffected(result), err)
+	return result, err
+}
+
+// QueryContext implements the driver.QueryerContext interface
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	begin := time.Now()
+	var (
+		r   driver.Rows
+		err error
+	)
+	if q, ok := c.parent.(driver.QueryerContext); ok {
+		r, err = q.QueryContext(ctx, query, args)
+	} else if q, ok := c.parent.(driver.Queryer); ok { //nolint:staticcheck // fallback for older drivers
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			r, err = q.Query(query, values)
+		}
+	} else {
+		return nil, driver.ErrSkip
+	}
+	if err != nil {
+		c.tracer.trace(ctx, begin, query, args, 0, err)
+		return nil, err
+	}
+	return &rows{args: args, begin: begin, ctx: ctx, tracer: c.tracer, parent: r, query: query}, nil
+}
+
+// Ping implements the driver.Pinger interface
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.parent.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession implements the driver.SessionResetter interface
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.parent.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements the driver.Validator interface
+func (c *conn) IsValid() bool {
+	if v, ok := c.parent.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue implements the driver.NamedValueChecker interface
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.parent.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip // database/sql uses its default conversion
+}
+
+// stmt logs the executions of a prepared statement
+type stmt struct {
+	tracer *tracer
+	parent driver.Stmt
+	query  string
+}
+
+// newStmt wraps a prepared statement, keeping its driver.ColumnConverter (database/sql
+// converts the arguments with it)
+func newStmt(t *tracer, parent driver.Stmt, query string) driver.Stmt {
+	s := &stmt{tracer: t, parent: parent, query: query}
+	if _, ok := parent.(driver.ColumnConverter); ok { //nolint:staticcheck // still used by some drivers
+		return &columnConverterStmt{stmt: s}
+	}
+	return s
+}
+
+// Close implements the driver.Stmt interface
+func (s *stmt) Close() error {
+	return s.parent.Close()
+}
+
+// NumInput implements the driver.Stmt interface
+func (s *stmt) NumInput() int {
+	return s.parent.NumInput()
+}
+
+// Exec implements the driver.Stmt interface
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamedValues(args))
+}
+
+// Query implements the driver.Stmt interface
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamedValues(args))
+}
+
+// ExecContext implements the driver.StmtExecContext interface
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	begin := time.Now()
+	var (
+		result driver.Result
+		err    error
+	)
+	if e, ok := s.parent.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			result, err = s.parent.Exec(values) //nolint:staticcheck // fallback for older drivers
+		}
+	}
+	s.tracer.trace(ctx, begin, s.query, args, rowsAffected(result), err)
+	return result, err
+}
+
+// QueryContext implements the driver.StmtQueryContext interface
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	begin := time.Now()
+	var (
+		r   driver.Rows
+		err error
+	)
+	if q, ok := s.parent.(driver.StmtQueryContext); ok {
+		r, err = q.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			r, err = s.parent.Query(values) //nolint:staticcheck // fallback for older drivers
+		}
+	}
+	if err != nil {
+		s.tracer.trace(ctx, begin, s.query, args, 0, err)
+		return nil, err
+	}
+	return &rows{args: args, begin: begin, ctx: ctx, tracer: s.tracer, parent: r, query: s.query}, nil
+}
+
+// columnConverterStmt is a stmt whose parent implements driver.ColumnConverter
+type columnConverterStmt struct {
+	*stmt
+}
+
+// ColumnConverter implements the driver.ColumnConverter interface
+func (s *columnConverterStmt) ColumnConverter(idx int) driver.ValueConverter {
+	return s.parent.(driver.ColumnConverter).ColumnConverter(idx) //nolint:staticcheck,forcetypeassert // checked by newStmt
+}
+
+// tx logs the end of a transaction
+type tx struct {
+	ctx    context.Context //nolint:containedctx // Commit and Rollback have no context of their own
+	tracer *tracer
+	parent driver.Tx
+}
+
+// Commit implements the driver.Tx interface
+func (t *tx) Commit() error {
+	begin := time.Now()
+	err := t.parent.Commit()
+	t.tracer.trace(t.ctx, begin, sqlCommit, nil, 0, err)
+	return err
+}
+
+// Rollback implements the driver.Tx interface
+func (t *tx) Rollback() error {
+	begin := time.Now()
+	err := t.parent.Rollback()
+	t.tracer.trace(t.ctx, begin, sqlRollback, nil, 0, err)
+	return err
+}
+
+// rows counts the rows of a query, which is traced when the rows are closed
+type rows struct {
+	args   []driver.NamedValue
+	begin  time.Time
+	closed bool
+	count  int64
+	ctx    context.Context //nolint:containedctx // the query is traced on Close, which has no context
+	err    error
+	tracer *tracer
+	parent driver.Rows
+	query  string
+}
+
+// Columns implements the driver.Rows interface
+func (r *rows) Columns() []string {
+	return r.parent.Columns()
+}
+
+// Next implements the driver.Rows interface
+func (r *rows) Next(dest []driver.Value) error {
+	err := r.parent.Next(dest)
+	switch {
+	case err == nil:
+		r.count++
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+	return err
+}
+
+// Close implements the driver.Rows interface
+func (r *rows) Close() error {
+	err := r.parent.Close()
+	if !r.closed {
+		r.closed = true
+		traceErr := r.err
+		if traceErr == nil {
+			traceErr = err
+		}
+		r.tracer.trace(r.ctx, r.begin, r.query, r.args, r.count, traceErr)
+	}
+	return err
+}
+
+// HasNextResultSet implements the driver.RowsNextResultSet interface
+func (r *rows) HasNextResultSet() bool {
+	if n, ok := r.parent.(driver.RowsNextResultSet); ok {
+		return n.HasNextResultSet()
+	}
+	return false
+}
+
+// NextResultSet implements the driver.RowsNextResultSet interface
+func (r *rows) NextResultSet() error {
+	if n, ok := r.parent.(driver.RowsNextResultSet); ok {
+		return n.NextResultSet()
+	}
+	return io.EOF
+}
+
+// ColumnTypeScanType implements the driver.RowsColumnTypeScanType interface
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	if c, ok := r.parent.(driver.RowsColumnTypeScanType); ok {
+		return c.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+// ColumnTypeDatabaseTypeName implements the driver.RowsColumnTypeDatabaseTypeName interface
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if c, ok := r.parent.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return c.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+// ColumnTypeLength implements the driver.RowsColumnTypeLength interface
+func (r *rows) ColumnTypeLength(index int) (int64, bool) {
+	if c, ok := r.parent.(driver.RowsColumnTypeLength); ok {
+		return c.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+// ColumnTypeNullable implements the driver.RowsColumnTypeNullable interface
+func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	if c, isNullable := r.parent.(driver.RowsColumnTypeNullable); isNullable {
+		return c.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+// ColumnTypePrecisionScale implements the driver.RowsColumnTypePrecisionScale interface
+func (r *rows) ColumnTypePrecisionScale(index int) (precision, scale int64, ok bool) {
+	if c, isDecimal := r.parent.(driver.RowsColumnTypePrecisionScale); isDecimal {
+		return c.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+// rowsAffected returns the rows affected by a statement (or -1 if unknown)
+func rowsAffected(result driver.Result) int64 {
+	if result == nil {
+		return -1
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// namedValuesToValues converts arguments for the older driver interfaces
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if len(arg.Name) > 0 {
+			return nil, errNamedParameters
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// valuesToNamedValues converts arguments from the older driver interfaces
+func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
@@ -0,0 +1,158 @@
+package sqllog
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// explain renders the arguments into the placeholders of a query (?, $1, :name or @name),
+// so the logged SQL can be redacted and fingerprinted like the SQL logged by GORM
+func explain(query string, args []driver.NamedValue) string {
+	if len(args) == 0 {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 16*len(args))
+	next := 0 // Next positional argument for ?
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := skipQuoted(query, i, c)
+			b.WriteString(query[i:end])
+			i = end
+			continue
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end
+			continue
+		case c == '?':
+			if next < len(args) {
+				b.WriteString(formatValue(args[next].Value))
+				next++
+				i++
+				continue
+			}
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			end := i + 1
+			for end < len(query) && isDigit(query[end]) {
+				end++
+			}
+			if arg, ok := ordinalArg(args, query[i+1:end]); ok {
+				b.WriteString(formatValue(arg.Value))
+				i = end
+				continue
+			}
+		case (c == ':' || c == '@') && i+1 < len(query) && isNameStart(query[i+1]) && (i == 0 || query[i-1] != ':'):
+			end := i + 1
+			for end < len(query) && isNamePart(query[end]) {
+				end++
+			}
+			if arg, ok := namedArg(args, query[i+1:end]); ok {
+				b.WriteString(formatValue(arg.Value))
+				i = end
+				continue
+			}
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String()
+}
+
+// ordinalArg returns the argument for $N
+func ordinalArg(args []driver.NamedValue, n string) (driver.NamedValue, bool) {
+	ordinal, err := strconv.Atoi(n)
+	if err != nil {
+		return driver.NamedValue{}, false
+	}
+	for _, arg := range args {
+		if arg.Ordinal == ordinal {
+			return arg, true
+		}
+	}
+	return driver.NamedValue{}, false
+}
+
+// namedArg returns the argument for :name or @name
+func namedArg(args []driver.NamedValue, name string) (driver.NamedValue, bool) {
+	for _, arg := range args {
+		if len(arg.Name) > 0 && arg.Name == name {
+			return arg, true
+		}
+	}
+	return driver.NamedValue{}, false
+}
+
+// formatValue renders an argument as a SQL literal
+func formatValue(v driver.Value) string {
+	switch value := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return quote(value)
+	case []byte:
+		if utf8.Valid(value) {
+			return quote(string(value))
+		}
+		return "X'" + hex.EncodeToString(value) + "'"
+	case time.Time:
+		return quote(value.Format("2006-01-02 15:04:05.999999999-07:00"))
+	case bool:
+		return strconv.FormatBool(value)
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case fmt.Stringer:
+		return quote(value.String())
+	}
+	return fmt.Sprint(v)
+}
+
+// quote returns a SQL string literal
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// skipQuoted returns the end of a quoted string or identifier starting at i
+func skipQuoted(query string, i int, q byte) int {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			j++
+		case q:
+			if j+1 < len(query) && query[j+1] == q {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+// isDigit reports whether c is a decimal digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isNameStart reports whether c can start a parameter name
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isNamePart reports whether c can continue a parameter name
+func isNamePart(c byte) bool {
+	return isNameStart(c) || isDigit(c)
+}
@@ -0,0 +1,48 @@
+package sqllog
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestExplain will test rendering arguments into placeholders
+func TestExplain(t *testing.T) {
+	positional := func(values ...driver.Value) []driver.NamedValue {
+		return valuesToNamedValues(values)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		args     []driver.NamedValue
+		expected string
+	}{
+		{"no args", "SELECT 1", nil, "SELECT 1"},
+		{"question marks", "SELECT * FROM t WHERE a = ? AND b = ?", positional("x", int64(2)), "SELECT * FROM t WHERE a = 'x' AND b = 2"},
+		{"dollar ordinals", "SELECT * FROM t WHERE a = $2 AND b = $1", positional("x", int64(2)), "SELECT * FROM t WHERE a = 2 AND b = 'x'"},
+		{"named", "SELECT * FROM t WHERE a = :a OR b = @b", []driver.NamedValue{{Name: "a", Ordinal: 1, Value: true}, {Name: "b", Ordinal: 2, Value: nil}}, "SELECT * FROM t WHERE a = true OR b = NULL"},
+		{"quoted placeholders are kept", "SELECT '?', \"$1\" -- ?\nFROM t WHERE a = ?", positional(1.5), "SELECT '?', \"$1\" -- ?\nFROM t WHERE a = 1.5"},
+		{"casts are not names", "SELECT a::text FROM t WHERE b = ?", positional("x"), "SELECT a::text FROM t WHERE b = 'x'"},
+		{"missing args", "SELECT ?, ?", positional(int64(1)), "SELECT 1, ?"},
+		{"quotes are escaped", "SELECT ?", positional("it's"), "SELECT 'it''s'"},
+		{"bytes", "SELECT ?, ?", positional([]byte("text"), []byte{0xff, 0x00}), "SELECT 'text', X'ff00'"},
+		{"time", "SELECT ?", positional(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)), "SELECT '2024-01-02 03:04:05+00:00'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, explain(test.query, test.args))
+		})
+	}
+}
+
+// BenchmarkExplain benchmarks the explain method
+func BenchmarkExplain(b *testing.B) {
+	args := valuesToNamedValues([]driver.Value{"jane@example.com", int64(30)})
+	for i := 0; i < b.N; i++ {
+		_ = explain("SELECT * FROM users WHERE email = ? AND age > ?", args)
+	}
+}
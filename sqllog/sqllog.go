@@ -0,0 +1,145 @@
+/*
+Package sqllog wraps a database/sql driver to log every query through go-logger
+
+Queries are reported to a logger.GormLoggerInterface, so the slow query threshold,
+redaction, fingerprints, statistics and ignored errors work like they do with GORM:
+
+	gormLogger := logger.NewGormLogger(false, 3, logger.WithSQLRedactor(logger.NewLiteralRedactor(logger.RedactPlaceholder)))
+	db, err := sqllog.Open("postgres", dsn, gormLogger)
+
+The SQL is logged as written, with its placeholders: the bound values (emails, tokens,
+...) are only logged with the WithBoundValues option, which should be paired with a
+redactor.
+*/
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/mrz1836/go-logger"
+)
+
+// defaultStackLevel is the stack level of the default logger
+const defaultStackLevel = 3
+
+// Option is an optional setting for Open, WrapDriver and WrapConnector
+type Option func(t *tracer)
+
+// WithBoundValues renders the bound values into the logged SQL, like GORM does
+// (IE: WHERE email = 'jane@example.com' instead of WHERE email = ?)
+//
+// The values may be personal data or secrets: use it with a redactor (see
+// logger.WithSQLRedactor), or only where the logs can hold them
+func WithBoundValues() Option {
+	return func(t *tracer) {
+		t.values = true
+	}
+}
+
+// Open opens a database like sql.Open, logging its queries
+func Open(driverName, dataSourceName string, l logger.GormLoggerInterface, opts ...Option) (*sql.DB, error) {
+	// sql.Open does not connect, it is only used to look up the registered driver
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	if err = db.Close(); err != nil {
+		return nil, err
+	}
+
+	connector, err := WrapDriver(d, l, opts...).(driver.DriverContext).OpenConnector(dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
+
+// WrapDriver returns a driver that logs the queries of the given driver
+// (a nil logger uses logger.NewGormLogger with the default settings)
+func WrapDriver(d driver.Driver, l logger.GormLoggerInterface, opts ...Option) driver.Driver {
+	return &wrappedDriver{parent: d, tracer: newTracer(l, opts)}
+}
+
+// WrapConnector returns a connector that logs the queries of the given connector
+// (use with sql.OpenDB, a nil logger uses logger.NewGormLogger with the default settings)
+func WrapConnector(c driver.Connector, l logger.GormLoggerInterface, opts ...Option) driver.Connector {
+	return &wrappedConnector{parent: c, tracer: newTracer(l, opts)}
+}
+
+// newTracer creates the tracer of a wrapper, using a default GORM logger if l is nil
+func newTracer(l logger.GormLoggerInterface, opts []Option) *tracer {
+	if l == nil {
+		l = logger.NewGormLogger(false, defaultStackLevel)
+	}
+	t := &tracer{logger: l}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// wrappedDriver logs the queries of a driver
+type wrappedDriver struct {
+	tracer *tracer
+	parent driver.Driver
+}
+
+// Open implements the driver.Driver interface
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.parent.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{tracer: d.tracer, parent: c}, nil
+}
+
+// OpenConnector implements the driver.DriverContext interface
+func (d *wrappedDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.parent.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &wrappedConnector{parent: c, tracer: d.tracer}, nil
+	}
+	return &dsnConnector{driver: d, name: name}, nil
+}
+
+// wrappedConnector logs the queries of a connector
+type wrappedConnector struct {
+	tracer *tracer
+	parent driver.Connector
+}
+
+// Connect implements the driver.Connector interface
+func (c *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	parent, err := c.parent.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{tracer: c.tracer, parent: parent}, nil
+}
+
+// Driver implements the driver.Connector interface
+func (c *wrappedConnector) Driver() driver.Driver {
+	return &wrappedDriver{parent: c.parent.Driver(), tracer: c.tracer}
+}
+
+// dsnConnector is the connector of a driver that does not implement driver.DriverContext
+type dsnConnector struct {
+	driver *wrappedDriver
+	name   string
+}
+
+// Connect implements the driver.Connector interface
+func (c *dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+// Driver implements the driver.Connector interface
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
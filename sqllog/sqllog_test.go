@@ -0,0 +1,368 @@
+package sqllog
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mrz1836/go-logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errQueryFailed = errors.New("query failed")
+
+// captureLogger records the lines written by go-logger
+type captureLogger struct {
+	lines []string
+	mu    sync.Mutex
+}
+
+func (c *captureLogger) add(line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lines = append(c.lines, line)
+}
+
+func (c *captureLogger) Lines() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.lines...)
+}
+
+func (c *captureLogger) Fatal(v ...interface{})                 { c.add(fmt.Sprint(v...)) }
+func (c *captureLogger) Fatalf(format string, v ...interface{}) { c.add(fmt.Sprintf(format, v...)) }
+func (c *captureLogger) Fatalln(v ...interface{})               { c.add(fmt.Sprint(v...)) }
+func (c *captureLogger) Panic(v ...interface{})                 { c.add(fmt.Sprint(v...)) }
+func (c *captureLogger) Panicf(format string, v ...interface{}) { c.add(fmt.Sprintf(format, v...)) }
+func (c *captureLogger) Panicln(v ...interface{})               { c.add(fmt.Sprint(v...)) }
+func (c *captureLogger) Print(v ...interface{})                 { c.add(fmt.Sprint(v...)) }
+func (c *captureLogger) Printf(format string, v ...interface{}) { c.add(fmt.Sprintf(format, v...)) }
+func (c *captureLogger) Println(v ...interface{})               { c.add(fmt.Sprint(v...)) }
+
+// capture swaps the go-logger implementation for the duration of a test
+func capture(t *testing.T) *captureLogger {
+	old := logger.GetImplementation()
+	t.Cleanup(func() { logger.SetImplementation(old) })
+
+	c := &captureLogger{}
+	logger.SetImplementation(c)
+	return c
+}
+
+// fakeDriver is a database/sql driver: queries return three rows, statements affect
+// two rows and anything containing "fail" returns errQueryFailed
+type fakeDriver struct {
+	legacy bool // Only implement the required interfaces
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	if d.legacy {
+		return &legacyConn{}, nil
+	}
+	return &fakeConn{}, nil
+}
+
+// legacyConn only implements driver.Conn
+type legacyConn struct{}
+
+func (c *legacyConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.Contains(query, "fail") {
+		return nil, errQueryFailed
+	}
+	return &fakeStmt{}, nil
+}
+func (c *legacyConn) Close() error              { return nil }
+func (c *legacyConn) Begin() (driver.Tx, error) { return &fakeTx{}, nil }
+
+// fakeConn implements the context interfaces
+type fakeConn struct {
+	legacyConn
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "fail") {
+		return nil, errQueryFailed
+	}
+	return driver.RowsAffected(2), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(query, "fail") {
+		return nil, errQueryFailed
+	}
+	return &fakeRows{}, nil
+}
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return &fakeTx{}, nil
+}
+
+// fakeStmt is a prepared statement
+type fakeStmt struct{}
+
+func (s *fakeStmt) Close() error                               { return nil }
+func (s *fakeStmt) NumInput() int                              { return -1 }
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(2), nil }
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error)  { return &fakeRows{}, nil }
+
+// fakeTx is a transaction
+type fakeTx struct{}
+
+func (t *fakeTx) Commit() error   { return nil }
+func (t *fakeTx) Rollback() error { return nil }
+
+// fakeRows returns three rows
+type fakeRows struct {
+	n int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"id"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n == 3 {
+		return io.EOF
+	}
+	r.n++
+	dest[0] = int64(r.n)
+	return nil
+}
+
+// openDB opens a database using the fake driver
+func openDB(t *testing.T, legacy bool, l logger.GormLoggerInterface, opts ...Option) *sql.DB {
+	connector, err := WrapDriver(&fakeDriver{legacy: legacy}, l, opts...).(driver.DriverContext).OpenConnector("dsn")
+	require.NoError(t, err)
+
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// TestWrapConnector_Query will test logging queries
+func TestWrapConnector_Query(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("legacy=%v", legacy), func(t *testing.T) {
+			db := openDB(t, legacy, logger.NewGormLogger(true, 3), WithBoundValues())
+			c := capture(t)
+
+			rows, err := db.QueryContext(context.Background(), "SELECT id FROM users WHERE email = ?", "jane@example.com")
+			require.NoError(t, err)
+			count := 0
+			for rows.Next() {
+				count++
+			}
+			require.NoError(t, rows.Err())
+			require.NoError(t, rows.Close())
+			assert.Equal(t, 3, count)
+
+			lines := c.Lines()
+			require.NotEmpty(t, lines)
+			last := lines[len(lines)-1]
+			assert.Contains(t, last, `message="executing sql query"`)
+			assert.Contains(t, last, `rows=3 sql="SELECT id FROM users WHERE email = 'jane@example.com'"`)
+		})
+	}
+}
+
+// TestWrapConnector_Exec will test logging statements
+func TestWrapConnector_Exec(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("legacy=%v", legacy), func(t *testing.T) {
+			db := openDB(t, legacy, logger.NewGormLogger(true, 3), WithBoundValues())
+			c := capture(t)
+
+			result, err := db.ExecContext(context.Background(), "UPDATE users SET name = $1 WHERE id = $2", "O'Brien", 7)
+			require.NoError(t, err)
+			n, err := result.RowsAffected()
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), n)
+
+			lines := c.Lines()
+			require.NotEmpty(t, lines)
+			assert.Contains(t, lines[len(lines)-1], `rows=2 sql="UPDATE users SET name = 'O''Brien' WHERE id = 7"`)
+		})
+	}
+}
+
+// TestWrapConnector_Placeholders will test that the bound values are not logged by default
+func TestWrapConnector_Placeholders(t *testing.T) {
+	db := openDB(t, false, logger.NewGormLogger(true, 3))
+	c := capture(t)
+
+	_, err := db.ExecContext(context.Background(), "UPDATE users SET email = ? WHERE id = ?", "jane@example.com", 7)
+	require.NoError(t, err)
+
+	lines := c.Lines()
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `sql="UPDATE users SET email = ? WHERE id = ?"`)
+	assert.NotContains(t, lines[0], "jane@example.com")
+}
+
+// TestWrapConnector_Error will test logging failed queries
+func TestWrapConnector_Error(t *testing.T) {
+	db := openDB(t, false, logger.NewGormLogger(true, 3))
+	c := capture(t)
+
+	_, err := db.ExecContext(context.Background(), "DELETE FROM fail")
+	require.ErrorIs(t, err, errQueryFailed)
+
+	lines := c.Lines()
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `type="error"`)
+	assert.Contains(t, lines[0], `error="query failed"`)
+}
+
+// TestWrapConnector_Transaction will test logging transactions and prepared statements
+func TestWrapConnector_Transaction(t *testing.T) {
+	db := openDB(t, false, logger.NewGormLogger(true, 3), WithBoundValues())
+	c := capture(t)
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	require.NoError(t, err)
+	stmt, err := tx.Prepare("INSERT INTO users (name) VALUES (?)")
+	require.NoError(t, err)
+	_, err = stmt.Exec("jane")
+	require.NoError(t, err)
+	require.NoError(t, stmt.Close())
+	require.NoError(t, tx.Commit())
+
+	tx, err = db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	var statements []string
+	for _, line := range c.Lines() {
+		start := strings.Index(line, ` sql="`) + len(` sql="`)
+		statements = append(statements, line[start:start+strings.Index(line[start:], `"`)])
+	}
+	assert.Equal(t, []string{
+		"BEGIN",
+		"PREPARE INSERT INTO users (name) VALUES (?)",
+		"INSERT INTO users (name) VALUES ('jane')",
+		"COMMIT",
+		"BEGIN",
+		"ROLLBACK",
+	}, statements)
+}
+
+// TestWrapConnector_SlowAndRedacted will test using the options of the GORM logger
+func TestWrapConnector_SlowAndRedacted(t *testing.T) {
+	db := openDB(t, false, logger.NewGormLogger(false, 3,
+		logger.WithSlowThreshold(time.Nanosecond),
+		logger.WithSQLRedactor(logger.NewLiteralRedactor(logger.RedactPlaceholder)),
+	))
+	c := capture(t)
+
+	_, err := db.ExecContext(context.Background(), "UPDATE users SET email = ? WHERE id = ?", "jane@example.com", 7)
+	require.NoError(t, err)
+
+	lines := c.Lines()
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `slow_log="SLOW SQL >= 1ns"`)
+	assert.Contains(t, lines[0], `sql="UPDATE users SET email = ? WHERE id = ?"`)
+}
+
+// converterConn prepares converterStmts
+type converterConn struct {
+	legacyConn
+	stmt *converterStmt
+}
+
+func (c *converterConn) Prepare(string) (driver.Stmt, error) { return c.stmt, nil }
+
+// converterStmt converts its arguments with a driver.ColumnConverter and records them
+type converterStmt struct {
+	fakeStmt
+	args []driver.Value
+}
+
+func (s *converterStmt) ColumnConverter(int) driver.ValueConverter { return upperConverter{} }
+
+func (s *converterStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.args = args
+	return driver.RowsAffected(1), nil
+}
+
+// upperConverter upper cases strings
+type upperConverter struct{}
+
+func (upperConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	if str, ok := v.(string); ok {
+		return strings.ToUpper(str), nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+// converterConnector is a connector for a converterConn
+type converterConnector struct {
+	conn *converterConn
+}
+
+func (c converterConnector) Connect(context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c converterConnector) Driver() driver.Driver                        { return &fakeDriver{} }
+
+// TestWrapConnector_ColumnConverter will test converting arguments with the ColumnConverter of a statement
+func TestWrapConnector_ColumnConverter(t *testing.T) {
+	s := &converterStmt{}
+	db := sql.OpenDB(WrapConnector(converterConnector{conn: &converterConn{stmt: s}}, logger.NewGormLogger(true, 3)))
+	defer func() { _ = db.Close() }()
+	capture(t)
+
+	_, err := db.ExecContext(context.Background(), "UPDATE users SET name = ?", "jane")
+	require.NoError(t, err)
+	assert.Equal(t, []driver.Value{"JANE"}, s.args)
+
+	plain := newStmt(&tracer{}, &fakeStmt{}, "SELECT 1")
+	_, ok := plain.(driver.ColumnConverter) //nolint:staticcheck // testing the forwarding
+	assert.False(t, ok)
+}
+
+// fakeConnector is a connector for the fake driver
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return &fakeDriver{} }
+
+// TestWrapConnector will test wrapping a connector
+func TestWrapConnector(t *testing.T) {
+	connector := WrapConnector(fakeConnector{}, nil)
+	assert.IsType(t, &wrappedDriver{}, connector.Driver())
+
+	db := sql.OpenDB(connector)
+	defer func() { _ = db.Close() }()
+	c := capture(t)
+
+	// The default logger only logs errors and slow queries
+	_, err := db.ExecContext(context.Background(), "DELETE FROM sessions")
+	require.NoError(t, err)
+	assert.Empty(t, c.Lines())
+
+	_, err = db.ExecContext(context.Background(), "DELETE FROM fail")
+	require.ErrorIs(t, err, errQueryFailed)
+	assert.Len(t, c.Lines(), 1)
+}
+
+// TestOpen will test opening a registered driver
+func TestOpen(t *testing.T) {
+	sql.Register("sqllog-fake", &fakeDriver{})
+
+	db, err := Open("sqllog-fake", "dsn", logger.NewGormLogger(true, 3))
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	c := capture(t)
+
+	require.NoError(t, db.PingContext(context.Background()))
+	_, err = db.ExecContext(context.Background(), "DELETE FROM sessions")
+	require.NoError(t, err)
+	require.Len(t, c.Lines(), 1)
+
+	_, err = Open("sqllog-unknown", "dsn", logger.NewGormLogger(true, 3))
+	require.Error(t, err)
+}
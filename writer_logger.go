@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// EntryLogger is a Logger that receives the structured entries of Data() and NoFileData()
+// (instead of a line encoded with the global encoder)
+type EntryLogger interface {
+	Logger
+	LogEntry(e *Entry)
+}
+
+// WriterOption is an optional setting for NewWriterLogger
+type WriterOption func(l *WriterLogger)
+
+// WithPrefix sets the prefix of every line (see log.New)
+func WithPrefix(prefix string) WriterOption {
+	return func(l *WriterLogger) {
+		l.prefix = prefix
+	}
+}
+
+// WithFlags sets the header flags of every line (see log.LstdFlags, defaults to log.LstdFlags)
+func WithFlags(flags int) WriterOption {
+	return func(l *WriterLogger) {
+		l.flags = flags
+	}
+}
+
+// WithEncoder sets the encoder for Data() and NoFileData() (defaults to the global encoder)
+func WithEncoder(enc Encoder) WriterOption {
+	return func(l *WriterLogger) {
+		l.encoder = enc
+	}
+}
+
+// WriterLogger is a Logger writing lines to an io.Writer (IE: os.Stderr, a buffer or a pipe),
+// without touching the standard log package
+type WriterLogger struct {
+	encoder Encoder
+	flags   int
+	logger  *log.Logger
+	mu      sync.Mutex // Serializes the encoding (encoders are not required to be safe for concurrent use)
+	prefix  string
+}
+
+// NewWriterLogger creates a Logger writing to w
+func NewWriterLogger(w io.Writer, opts ...WriterOption) *WriterLogger {
+	l := &WriterLogger{flags: log.LstdFlags}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.logger = log.New(w, l.prefix, l.flags)
+	return l
+}
+
+// LogEntry implements the EntryLogger interface, encoding the entry with the logger's encoder
+func (l *WriterLogger) LogEntry(e *Entry) {
+	enc := l.encoder
+	if enc == nil {
+		enc = encoder
+	}
+
+	l.mu.Lock()
+	var buf bytes.Buffer
+	enc.Encode(&buf, e)
+	l.mu.Unlock()
+
+	_ = l.logger.Output(2, buf.String())
+}
+
+// Writer returns the destination of the logger
+func (l *WriterLogger) Writer() io.Writer {
+	return l.logger.Writer()
+}
+
+// Panic overloads built-in method
+func (l *WriterLogger) Panic(v ...interface{}) {
+	_ = l.logger.Output(2, fmt.Sprint(v...))
+	os.Exit(1)
+}
+
+// Panicln overloads built-in method
+func (l *WriterLogger) Panicln(v ...interface{}) {
+	_ = l.logger.Output(2, fmt.Sprintln(v...))
+	os.Exit(1)
+}
+
+// Panicf overloads built-in method
+func (l *WriterLogger) Panicf(format string, v ...interface{}) {
+	_ = l.logger.Output(2, fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
+
+// Print overloads built-in method
+func (l *WriterLogger) Print(v ...interface{}) {
+	_ = l.logger.Output(2, fmt.Sprint(v...))
+}
+
+// Println overloads built-in method
+func (l *WriterLogger) Println(v ...interface{}) {
+	_ = l.logger.Output(2, fmt.Sprintln(v...))
+}
+
+// Printf overloads built-in method
+func (l *WriterLogger) Printf(format string, v ...interface{}) {
+	_ = l.logger.Output(2, fmt.Sprintf(format, v...))
+}
+
+// Fatal overloads built-in method
+func (l *WriterLogger) Fatal(v ...interface{}) {
+	_ = l.logger.Output(2, fmt.Sprint(v...))
+	os.Exit(1)
+}
+
+// Fatalln overloads built-in method
+func (l *WriterLogger) Fatalln(v ...interface{}) {
+	_ = l.logger.Output(2, fmt.Sprintln(v...))
+	os.Exit(1)
+}
+
+// Fatalf overloads built-in method
+func (l *WriterLogger) Fatalf(format string, v ...interface{}) {
+	_ = l.logger.Output(2, fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewWriterLogger will test the NewWriterLogger() method
+func TestNewWriterLogger(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := NewWriterLogger(&buf)
+		assert.Equal(t, log.LstdFlags, l.flags)
+		assert.Empty(t, l.prefix)
+		assert.Nil(t, l.encoder)
+		assert.Equal(t, &buf, l.Writer())
+	})
+
+	t.Run("options", func(t *testing.T) {
+		var buf bytes.Buffer
+		enc := &JSONEncoder{}
+		l := NewWriterLogger(&buf, WithPrefix("app: "), WithFlags(0), WithEncoder(enc))
+		assert.Equal(t, 0, l.flags)
+		assert.Equal(t, "app: ", l.prefix)
+		assert.Equal(t, enc, l.encoder)
+
+		l.Print("test")
+		assert.Equal(t, "app: test\n", buf.String())
+	})
+}
+
+// TestWriterLogger_Print will test the Print(), Println() and Printf() methods
+func TestWriterLogger_Print(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, WithFlags(0))
+
+	l.Print("print", 1)
+	l.Println("println", 1)
+	l.Printf("printf %d", 1)
+	assert.Equal(t, "print1\nprintln 1\nprintf 1\n", buf.String())
+}
+
+// TestWriterLogger_Flags will test the header flags
+func TestWriterLogger_Flags(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf, WithFlags(log.Lshortfile|log.Lmsgprefix), WithPrefix("> "))
+
+	l.Print("test")
+	assert.Regexp(t, `^writer_logger_test\.go:\d+: > test\n$`, buf.String())
+}
+
+// TestWriterLogger_Panic will test the Panic() method
+func TestWriterLogger_Panic(t *testing.T) {
+	if os.Getenv("EXIT_FUNCTION") == "1" {
+		NewWriterLogger(os.Stdout, WithFlags(0)).Panicf("panicf %d", 1)
+		return
+	}
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestWriterLogger_Panic") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION=1")
+	out, err := cmd.Output()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		assert.Equal(t, "panicf 1\n", string(out))
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestWriterLogger_Fatal will test the Fatal() method
+func TestWriterLogger_Fatal(t *testing.T) {
+	if os.Getenv("EXIT_FUNCTION") == "1" {
+		NewWriterLogger(os.Stdout, WithFlags(0)).Fatalln("fatal", 1)
+		return
+	}
+	cmd := exec.CommandContext(context.Background(), os.Args[0], "-test.run=TestWriterLogger_Fatal") //nolint:gosec // G204
+	cmd.Env = append(os.Environ(), "EXIT_FUNCTION=1")
+	out, err := cmd.Output()
+	var e *exec.ExitError
+	if errors.As(err, &e) && !e.Success() {
+		assert.Equal(t, "fatal 1\n", string(out))
+		return
+	}
+	t.Fatalf("process ran with err %v, want exit status 1", err)
+}
+
+// TestWriterLogger_LogEntry will test Data() and NoFileData() through a WriterLogger
+func TestWriterLogger_LogEntry(t *testing.T) {
+	previous := GetImplementation()
+	defer SetImplementation(previous)
+
+	t.Run("global encoder", func(t *testing.T) {
+		var buf bytes.Buffer
+		SetImplementation(NewWriterLogger(&buf, WithFlags(0)))
+
+		NoFileData(INFO, "test", MakeParameter("key", "value"))
+		assert.Equal(t, `type="info" message="test" key="value"`+"\n", buf.String())
+	})
+
+	t.Run("own encoder", func(t *testing.T) {
+		var buf bytes.Buffer
+		SetImplementation(NewWriterLogger(&buf, WithFlags(0), WithEncoder(&JSONEncoder{})))
+
+		Data(2, WARN, "test", Int("n", 1))
+		assert.Contains(t, buf.String(), `"level":"warn","file":"`)
+		assert.Contains(t, buf.String(), `"message":"test","n":1}`)
+		assert.IsType(t, &LogfmtEncoder{}, GetEncoder())
+	})
+
+	t.Run("concurrent entries", func(t *testing.T) {
+		var buf bytes.Buffer
+		l := NewWriterLogger(&buf, WithFlags(0))
+		SetImplementation(l)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				NoFileData(DEBUG, "concurrent")
+			}()
+		}
+		wg.Wait()
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		require.Len(t, lines, 50)
+		for _, line := range lines {
+			assert.Equal(t, `type="debug" message="concurrent"`, line)
+		}
+	})
+}
+
+// BenchmarkWriterLogger_LogEntry benchmarks the LogEntry() method
+func BenchmarkWriterLogger_LogEntry(b *testing.B) {
+	var buf bytes.Buffer
+	l := NewWriterLogger(&buf)
+	e := &Entry{Level: INFO, Message: "benchmark", Fields: []KeyValue{String("key", "value")}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		l.LogEntry(e)
+	}
+}